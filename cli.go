@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/taliesins/terraform-provider-cleaneks/internal/provider"
+)
+
+// Exit codes of the clean, status, adopt and restore subcommands.
+const (
+	exitOk          = 0
+	exitFailed      = 1
+	exitUsage       = 2
+	exitUnsatisfied = 3
+)
+
+// stringsFlag is a flag that can be given more than once.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// envBool returns the boolean value of an environment variable, or the default when it isn't set or isn't a boolean.
+func envBool(name string, defaultValue bool) bool {
+	switch strings.ToLower(os.Getenv(name)) {
+	case "1", "true":
+		return true
+	case "0", "false":
+		return false
+	}
+	return defaultValue
+}
+
+// connectionFlags adds the flags of the provider connection attributes, which default to the same environment
+// variables as the provider.
+func connectionFlags(flags *flag.FlagSet, connection *provider.CliConnection) {
+	flags.StringVar(&connection.Host, "host", "", "hostname (in form of URI) of the Kubernetes API")
+	flags.StringVar(&connection.ClusterName, "cluster-name", "", "name of the EKS cluster, to resolve the host and CA certificate from the EKS API")
+	flags.StringVar(&connection.Region, "region", "", "AWS region of the EKS cluster")
+	flags.StringVar(&connection.EksEndpoint, "eks-endpoint", "", "override for the EKS API endpoint")
+	flags.StringVar(&connection.Username, "username", os.Getenv("KUBE_USER"), "username for HTTP basic authentication (KUBE_USER)")
+	flags.StringVar(&connection.Password, "password", os.Getenv("KUBE_PASSWORD"), "password for HTTP basic authentication (KUBE_PASSWORD)")
+	flags.BoolVar(&connection.Insecure, "insecure", envBool("KUBE_INSECURE", false), "skip verifying the server certificate (KUBE_INSECURE)")
+	flags.StringVar(&connection.TLSServerName, "tls-server-name", os.Getenv("KUBE_TLS_SERVER_NAME"), "server name for verifying the server certificate (KUBE_TLS_SERVER_NAME)")
+	flags.StringVar(&connection.ClientCertificate, "client-certificate", os.Getenv("KUBE_CLIENT_CERT_DATA"), "PEM-encoded client certificate (KUBE_CLIENT_CERT_DATA)")
+	flags.StringVar(&connection.ClientKey, "client-key", os.Getenv("KUBE_CLIENT_KEY_DATA"), "PEM-encoded client key (KUBE_CLIENT_KEY_DATA)")
+	flags.StringVar(&connection.ClusterCACertificate, "cluster-ca-certificate", os.Getenv("KUBE_CLUSTER_CA_CERT_DATA"), "PEM-encoded root certificates bundle (KUBE_CLUSTER_CA_CERT_DATA)")
+	flags.StringVar(&connection.ConfigPath, "config-path", os.Getenv("KUBE_CONFIG_PATH"), "path to the kube config file (KUBE_CONFIG_PATH)")
+	flags.StringVar(&connection.ConfigPath, "kubeconfig", os.Getenv("KUBE_CONFIG_PATH"), "alias of -config-path")
+	flags.Var((*stringsFlag)(&connection.ConfigPaths), "config-paths", "path to a kube config file, can be given more than once (KUBE_CONFIG_PATHS)")
+	flags.StringVar(&connection.ConfigContext, "config-context", os.Getenv("KUBE_CTX"), "context of the kube config file (KUBE_CTX)")
+	flags.StringVar(&connection.ConfigContext, "context", os.Getenv("KUBE_CTX"), "alias of -config-context")
+	flags.StringVar(&connection.ConfigContextAuthInfo, "config-context-auth-info", os.Getenv("KUBE_CTX_AUTH_INFO"), "user of the kube config file (KUBE_CTX_AUTH_INFO)")
+	flags.StringVar(&connection.ConfigContextCluster, "config-context-cluster", os.Getenv("KUBE_CTX_CLUSTER"), "cluster of the kube config file (KUBE_CTX_CLUSTER)")
+	flags.StringVar(&connection.Token, "token", os.Getenv("KUBE_TOKEN"), "token to authenticate a service account (KUBE_TOKEN)")
+	flags.StringVar(&connection.ProxyURL, "proxy-url", os.Getenv("KUBE_PROXY_URL"), "URL of the proxy to use for requests (KUBE_PROXY_URL)")
+	flags.StringVar(&connection.ImpersonateUser, "impersonate-user", os.Getenv("KUBE_IMPERSONATE_USER"), "user to impersonate (KUBE_IMPERSONATE_USER)")
+	flags.StringVar(&connection.ImpersonateUid, "impersonate-uid", os.Getenv("KUBE_IMPERSONATE_UID"), "UID to impersonate (KUBE_IMPERSONATE_UID)")
+	flags.Var((*stringsFlag)(&connection.ImpersonateGroups), "impersonate-groups", "group to impersonate, can be given more than once (KUBE_IMPERSONATE_GROUPS)")
+	flags.BoolVar(&connection.EksAuth, "eks-auth", false, "generate the EKS token in-process for -cluster-name, like the eks_auth block")
+	flags.StringVar(&connection.EksAuthRoleArn, "eks-auth-role-arn", "", "IAM role to assume when generating the EKS token")
+	flags.StringVar(&connection.EksAuthProfile, "eks-auth-profile", "", "AWS shared config profile to use when generating the EKS token")
+	flags.StringVar(&connection.ReadyTimeout, "ready-timeout", "", "how long to wait for the API server to be ready, 0s doesn't wait (default 5m)")
+}
+
+// runCli runs the clean, status, adopt or restore subcommand against the cluster of the connection flags, the same
+// way a cleaneks_job is created, returning the exit code.
+func runCli(command string, args []string, stdout io.Writer, stderr io.Writer) int {
+	var connection provider.CliConnection
+	var options provider.JobOptions
+	var acknowledged stringsFlag
+	var output string
+	var manageEksAddons, admissionGuard bool
+	var admissionGuardName string
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	connectionFlags(flags, &connection)
+	flags.StringVar(&output, "output", "text", "format of the report: text or json")
+
+	switch command {
+	case provider.CliCommandClean:
+		flags.StringVar(&options.AwsCni, "aws-cni", "remove", "action for AWS CNI: keep or remove")
+		flags.StringVar(&options.KubeProxy, "kube-proxy", "remove", "action for Kube Proxy: keep or remove")
+		flags.StringVar(&options.Coredns, "coredns", "keep", "action for CoreDNS: keep, remove, adopt or restore")
+	case provider.CliCommandStatus:
+		flags.StringVar(&options.AwsCni, "aws-cni", "keep", "action for AWS CNI to check: keep or remove")
+		flags.StringVar(&options.KubeProxy, "kube-proxy", "keep", "action for Kube Proxy to check: keep or remove")
+		flags.StringVar(&options.Coredns, "coredns", "keep", "action for CoreDNS to check: keep, remove, adopt or restore")
+	}
+	flags.StringVar(&options.ConflictPolicy, "conflict-policy", "", "what to do with objects owned by another Helm release: fail, takeover or skip (default fail)")
+	if command != provider.CliCommandStatus {
+		flags.BoolVar(&options.DryRun, "dry-run", envBool("CLEANEKS_DRY_RUN", false), "run every change as a server-side dry run (CLEANEKS_DRY_RUN)")
+		flags.BoolVar(&options.Force, "force", false, "remove kube-proxy even when no replacement is detected")
+		flags.BoolVar(&options.VerifyEks, "verify-eks", true, "refuse to run against a cluster that isn't EKS")
+		flags.StringVar(&options.ExpectedClusterName, "expected-cluster-name", "", "refuse to run unless the cluster has this EKS name")
+		flags.StringVar(&options.ExpectedClusterArn, "expected-cluster-arn", "", "refuse to run unless the cluster has this EKS ARN")
+		flags.StringVar(&options.LockLeaseName, "lock-lease-name", "", "name of the lease that stops concurrent runs (default cleaneks-job)")
+		flags.DurationVar(&options.LockTimeout, "lock-timeout", 5*time.Minute, "how long to wait for the lease")
+		flags.Var(&acknowledged, "acknowledged-aws-cni-dependency", "VPC CNI feature in use that may break when AWS CNI is removed: pod_eni, network_policy or custom_networking, can be given more than once")
+		flags.DurationVar(&options.WaitForTimeout, "wait-for-timeout", 10*time.Minute, "how long to wait for the replacements to be ready")
+		flags.BoolVar(&manageEksAddons, "manage-eks-addons", false, "release the components from their EKS managed add-ons, and give CoreDNS back on restore")
+		flags.BoolVar(&options.StrictAddonOwnership, "strict-addon-ownership", false, "fail instead of warning when objects owned by an EKS managed add-on would be changed")
+		flags.BoolVar(&admissionGuard, "admission-guard", false, "install an admission policy that denies creating the removed components again")
+		flags.StringVar(&admissionGuardName, "admission-guard-name", "", "name of the admission policy and its binding (default cleaneks-guard)")
+	}
+
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s %s [flags]\n\nFlags:\n", os.Args[0], command)
+		flags.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExit codes: 0 success, 1 failure, 2 usage error, 3 a component isn't in the requested state\n")
+	}
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOk
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+		return exitUsage
+	}
+	if output != "text" && output != "json" {
+		fmt.Fprintf(stderr, "output: unsupported value %q, use one of text, json\n", output)
+		return exitUsage
+	}
+
+	options.AcknowledgedAwsCniDependencies = acknowledged
+	if admissionGuard {
+		options.AdmissionGuard = &provider.AdmissionGuard{Name: admissionGuardName}
+	}
+	options, err = provider.CliJobOptions(command, options)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := provider.RunCli(ctx, provider.NewCliProvider(version, connection), command, options, manageEksAddons)
+	if output == "json" {
+		err = report.WriteJson(stdout)
+	} else {
+		err = report.WriteText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to write report: %v\n", err)
+		return exitFailed
+	}
+
+	return exitCode(report)
+}
+
+// exitCode returns the exit code of a report, a dry run is never unsatisfied as nothing was changed.
+func exitCode(report *provider.CliReport) int {
+	switch {
+	case report.HasErrors():
+		return exitFailed
+	case len(report.Unsatisfied) > 0 && !report.DryRun:
+		return exitUnsatisfied
+	}
+	return exitOk
+}
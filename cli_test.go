@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/taliesins/terraform-provider-cleaneks/internal/provider"
+)
+
+func TestExitCode(t *testing.T) {
+	failure := []provider.CliMessage{{Summary: "failed"}}
+	warning := []provider.CliMessage{{Summary: "warning"}}
+
+	tests := []struct {
+		name   string
+		report provider.CliReport
+		want   int
+	}{
+		{
+			name:   "ok",
+			report: provider.CliReport{Unsatisfied: []string{}},
+			want:   exitOk,
+		},
+		{
+			name:   "warnings",
+			report: provider.CliReport{Warnings: warning},
+			want:   exitOk,
+		},
+		{
+			name:   "errors",
+			report: provider.CliReport{Errors: failure},
+			want:   exitFailed,
+		},
+		{
+			name:   "errors and unsatisfied",
+			report: provider.CliReport{Errors: failure, Unsatisfied: []string{"aws_cni"}},
+			want:   exitFailed,
+		},
+		{
+			name:   "unsatisfied",
+			report: provider.CliReport{Unsatisfied: []string{"coredns"}},
+			want:   exitUnsatisfied,
+		},
+		{
+			name:   "unsatisfied dry run",
+			report: provider.CliReport{Unsatisfied: []string{"kube_proxy"}, DryRun: true},
+			want:   exitOk,
+		},
+		{
+			name:   "errors dry run",
+			report: provider.CliReport{Errors: failure, DryRun: true},
+			want:   exitFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := exitCode(&test.report); got != test.want {
+				t.Errorf("exitCode() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRunCliUsage(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		args       []string
+		want       int
+		wantStderr string
+	}{
+		{
+			name:       "help",
+			command:    provider.CliCommandClean,
+			args:       []string{"-h"},
+			want:       exitOk,
+			wantStderr: "Exit codes:",
+		},
+		{
+			name:       "unknown flag",
+			command:    provider.CliCommandClean,
+			args:       []string{"-remove-everything"},
+			want:       exitUsage,
+			wantStderr: "flag provided but not defined",
+		},
+		{
+			name:       "status has no dry run",
+			command:    provider.CliCommandStatus,
+			args:       []string{"-dry-run"},
+			want:       exitUsage,
+			wantStderr: "flag provided but not defined",
+		},
+		{
+			name:       "unexpected arguments",
+			command:    provider.CliCommandAdopt,
+			args:       []string{"coredns"},
+			want:       exitUsage,
+			wantStderr: "unexpected arguments: coredns",
+		},
+		{
+			name:       "unsupported output",
+			command:    provider.CliCommandStatus,
+			args:       []string{"-output", "yaml"},
+			want:       exitUsage,
+			wantStderr: `output: unsupported value "yaml"`,
+		},
+		{
+			name:       "unsupported action",
+			command:    provider.CliCommandClean,
+			args:       []string{"-aws-cni", "adopt"},
+			want:       exitUsage,
+			wantStderr: `aws-cni: unsupported value "adopt"`,
+		},
+		{
+			name:       "unsupported conflict policy",
+			command:    provider.CliCommandRestore,
+			args:       []string{"-conflict-policy", "overwrite"},
+			want:       exitUsage,
+			wantStderr: `conflict-policy: unsupported value "overwrite"`,
+		},
+		{
+			name:       "admission guard without removal",
+			command:    provider.CliCommandClean,
+			args:       []string{"-aws-cni", "keep", "-kube-proxy", "keep", "-admission-guard"},
+			want:       exitUsage,
+			wantStderr: "admission-guard: needs aws-cni or kube-proxy to be removed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			got := runCli(test.command, test.args, &stdout, &stderr)
+			if got != test.want {
+				t.Errorf("runCli() = %d, want %d\nstderr: %s", got, test.want, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), test.wantStderr) {
+				t.Errorf("runCli() stderr = %q, want it to contain %q", stderr.String(), test.wantStderr)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("runCli() stdout = %q, want nothing", stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/taliesins/terraform-provider-cleaneks/internal/provider"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// runController runs the controller subcommand in the cluster it is deployed to, returning the exit code.
+func runController(args []string) int {
+	var options provider.ControllerOptions
+
+	flags := flag.NewFlagSet("controller", flag.ExitOnError)
+	flags.StringVar(&options.AwsCni, "aws-cni", "", "action to enforce for AWS CNI: keep or remove")
+	flags.StringVar(&options.KubeProxy, "kube-proxy", "", "action to enforce for Kube Proxy: keep or remove")
+	flags.StringVar(&options.Coredns, "coredns", "", "action to enforce for CoreDNS: keep, remove or adopt")
+	flags.BoolVar(&options.DryRun, "dry-run", false, "run every change as a server-side dry run")
+	flags.BoolVar(&options.Force, "force", false, "remove kube-proxy even when no replacement is detected")
+	flags.Var((*stringsFlag)(&options.AcknowledgedAwsCniDependencies), "acknowledged-aws-cni-dependency", "VPC CNI feature in use that may break when AWS CNI is removed: pod_eni, network_policy or custom_networking, can be given more than once")
+	flags.BoolVar(&options.VerifyEks, "verify-eks", true, "refuse to remove anything from a cluster that isn't EKS")
+	flags.StringVar(&options.ExpectedClusterName, "expected-cluster-name", "", "refuse to remove anything unless the cluster has this EKS name")
+	flags.StringVar(&options.ExpectedClusterArn, "expected-cluster-arn", "", "refuse to remove anything unless the cluster has this EKS ARN")
+	flags.BoolVar(&options.ManageEksAddons, "manage-eks-addons", false, "release a component from its EKS managed add-on before changing the objects the add-on owns, which are refused otherwise")
+	flags.StringVar(&options.ClusterName, "cluster-name", "", "name of the EKS cluster whose add-ons are released (default -expected-cluster-name)")
+	flags.StringVar(&options.Region, "region", "", "AWS region of the EKS cluster")
+	flags.StringVar(&options.EksEndpoint, "eks-endpoint", "", "override for the EKS API endpoint")
+	flags.StringVar(&options.LeaseName, "lease-name", "cleaneks-controller", "name of the leader election lease")
+	flags.StringVar(&options.LeaseNamespace, "lease-namespace", "kube-system", "namespace of the leader election lease")
+	flags.StringVar(&options.MetricsAddress, "metrics-bind-address", ":8080", "address to serve /metrics on, empty disables it")
+	flags.StringVar(&options.HealthAddress, "health-probe-bind-address", ":8081", "address to serve /healthz and /readyz on, empty disables it")
+	flags.DurationVar(&options.ResyncPeriod, "resync-period", 10*time.Minute, "how often every watched object is reconciled, even without a change")
+	klog.InitFlags(flags)
+	_ = flags.Parse(args)
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load in-cluster configuration: %v\n", err)
+		return 1
+	}
+	config.UserAgent = fmt.Sprintf("cleaneks-controller/%s", version)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = provider.RunController(ctx, config, version, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "controller failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
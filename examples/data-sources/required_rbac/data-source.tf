@@ -0,0 +1,55 @@
+data "cleaneks_required_rbac" "cluster" {
+  aws_cni {
+    action = "remove"
+  }
+
+  kube_proxy {
+    action = "remove"
+  }
+
+  coredns {
+    action = "adopt"
+  }
+}
+
+resource "kubernetes_manifest" "cleaneks_cluster_role" {
+  manifest = yamldecode(data.cleaneks_required_rbac.cluster.cluster_role_yaml)
+}
+
+resource "kubernetes_cluster_role_binding_v1" "cleaneks" {
+  metadata {
+    name = "cleaneks"
+  }
+
+  role_ref {
+    api_group = "rbac.authorization.k8s.io"
+    kind      = "ClusterRole"
+    name      = "cleaneks"
+  }
+
+  subject {
+    kind      = "User"
+    name      = "terraform"
+    api_group = "rbac.authorization.k8s.io"
+  }
+}
+
+resource "kubernetes_role_v1" "cleaneks" {
+  for_each = { for role in data.cleaneks_required_rbac.cluster.roles : role.namespace => role }
+
+  metadata {
+    name      = "cleaneks"
+    namespace = each.key
+  }
+
+  dynamic "rule" {
+    for_each = each.value.rules
+
+    content {
+      api_groups     = rule.value.api_groups
+      resources      = rule.value.resources
+      resource_names = rule.value.resource_names
+      verbs          = rule.value.verbs
+    }
+  }
+}
@@ -0,0 +1,16 @@
+resource "helm_release" "cilium" {
+  name       = "cilium"
+  repository = "https://helm.cilium.io"
+  chart      = "cilium"
+  namespace  = "kube-system"
+}
+
+resource "cleaneks_aws_cni_removal" "cluster" {
+  wait_for {
+    kind      = "DaemonSet"
+    namespace = "kube-system"
+    name      = "cilium"
+  }
+
+  depends_on = [helm_release.cilium]
+}
@@ -0,0 +1,12 @@
+resource "cleaneks_coredns_adoption" "cluster" {
+  conflict_policy = "fail"
+}
+
+resource "helm_release" "coredns" {
+  name       = "coredns"
+  repository = "https://coredns.github.io/helm"
+  chart      = "coredns"
+  namespace  = "kube-system"
+
+  depends_on = [cleaneks_coredns_adoption.cluster]
+}
@@ -1,7 +1,15 @@
 resource "cleaneks_job" "cluster" {
-  remove_aws_cni         = true
-  remove_kube_proxy      = true
-  import_coredns_to_helm = true
+  aws_cni {
+    action = "remove"
+  }
+
+  kube_proxy {
+    action = "remove"
+  }
+
+  coredns {
+    action = "adopt"
+  }
 }
 
 provider "cleaneks" {
@@ -0,0 +1,15 @@
+resource "helm_release" "cilium" {
+  name       = "cilium"
+  repository = "https://helm.cilium.io"
+  chart      = "cilium"
+  namespace  = "kube-system"
+
+  set {
+    name  = "kubeProxyReplacement"
+    value = "true"
+  }
+}
+
+resource "cleaneks_kube_proxy_removal" "cluster" {
+  depends_on = [helm_release.cilium]
+}
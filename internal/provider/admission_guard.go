@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const defaultAdmissionGuardName string = "cleaneks-guard"
+const admissionGuardManagedByValue string = "cleaneks"
+
+// AdmissionGuard is a ValidatingAdmissionPolicy, and the binding that enforces it, that denies creating the removed
+// components again with the EKS component label, e.g. by an EKS platform upgrade or an add-on install. Objects created
+// without the label, such as a replacement installed by a Helm chart, are let through.
+type AdmissionGuard struct {
+	// Name of both the policy and the binding
+	Name    string
+	Objects []ClusterObject
+}
+
+// AdmissionGuardObjects returns the objects the guard protects for the removed components, followed by the extra ones.
+func AdmissionGuardObjects(awsCni string, kubeProxy string, extra []ClusterObject) (objects []ClusterObject) {
+	if awsCni == componentActionRemove {
+		objects = append(objects, AwsCniObjects[0])
+	}
+	if kubeProxy == componentActionRemove {
+		objects = append(objects, KubeProxyObjects[0])
+	}
+	return append(objects, extra...)
+}
+
+// AdmissionGuardChange describes a change to the policy or binding, in the same form as the changes made to objects.
+func AdmissionGuardChange(action string, kind string, name string) string {
+	return fmt.Sprintf("%s %s %s", action, kind, name)
+}
+
+// Policy returns the ValidatingAdmissionPolicy of the guard, with one validation for each object.
+func (g AdmissionGuard) Policy() *admissionregistrationv1.ValidatingAdmissionPolicy {
+	failurePolicy := admissionregistrationv1.Fail
+	scope := admissionregistrationv1.NamespacedScope
+
+	// One rule for each resource the objects are of, the validations pick out the names
+	rules := map[string]admissionregistrationv1.NamedRuleWithOperations{}
+	validations := make([]admissionregistrationv1.Validation, 0, len(g.Objects))
+	for _, object := range g.Objects {
+		group, resource := object.GroupResource()
+		key := group + "/" + resource
+		if _, ok := rules[key]; !ok {
+			rules[key] = admissionregistrationv1.NamedRuleWithOperations{
+				RuleWithOperations: admissionregistrationv1.RuleWithOperations{
+					Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+					Rule: admissionregistrationv1.Rule{
+						APIGroups:   []string{group},
+						APIVersions: []string{"*"},
+						Resources:   []string{resource},
+						Scope:       &scope,
+					},
+				},
+			}
+		}
+
+		validations = append(validations, admissionregistrationv1.Validation{
+			Expression: fmt.Sprintf(
+				"!(request.resource.group == '%s' && request.resource.resource == '%s' && request.namespace == '%s' && object.metadata.name == '%s' && has(object.metadata.labels) && '%s' in object.metadata.labels)",
+				group, resource, object.Namespace, object.Name, amazonManagedLabelName,
+			),
+			Message: fmt.Sprintf("%s was removed from this cluster and must not be created again with the %s label", object, amazonManagedLabelName),
+		})
+	}
+
+	keys := make([]string, 0, len(rules))
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resourceRules := make([]admissionregistrationv1.NamedRuleWithOperations, 0, len(keys))
+	for _, key := range keys {
+		resourceRules = append(resourceRules, rules[key])
+	}
+
+	return &admissionregistrationv1.ValidatingAdmissionPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   g.Name,
+			Labels: map[string]string{managedByLabelName: admissionGuardManagedByValue},
+		},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicySpec{
+			FailurePolicy:    &failurePolicy,
+			MatchConstraints: &admissionregistrationv1.MatchResources{ResourceRules: resourceRules},
+			Validations:      validations,
+		},
+	}
+}
+
+// Binding returns the ValidatingAdmissionPolicyBinding that enforces the policy in every namespace.
+func (g AdmissionGuard) Binding() *admissionregistrationv1.ValidatingAdmissionPolicyBinding {
+	return &admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   g.Name,
+			Labels: map[string]string{managedByLabelName: admissionGuardManagedByValue},
+		},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec{
+			PolicyName:        g.Name,
+			ValidationActions: []admissionregistrationv1.ValidationAction{admissionregistrationv1.Deny},
+		},
+	}
+}
+
+// ApplyAdmissionGuard creates the policy and the binding, or updates them when they differ from the guard. It returns
+// the changes that were made.
+func ApplyAdmissionGuard(ctx context.Context, clientset *kubernetes.Clientset, guard AdmissionGuard, dryRun bool) (changes []string, err error) {
+	policies := clientset.AdmissionregistrationV1().ValidatingAdmissionPolicies()
+	policy := guard.Policy()
+	current, err := policies.Get(ctx, policy.Name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = policies.Create(ctx, policy, metav1.CreateOptions{DryRun: dryRunOptions(dryRun)})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, AdmissionGuardChange("create", "ValidatingAdmissionPolicy", policy.Name))
+	case err != nil:
+		return changes, err
+	case !admissionPolicyUpToDate(current, policy):
+		// The API server defaults the fields the guard leaves empty, so only the ones it sets are compared
+		current.Labels = policy.Labels
+		current.Spec.FailurePolicy = policy.Spec.FailurePolicy
+		if current.Spec.MatchConstraints == nil {
+			current.Spec.MatchConstraints = &admissionregistrationv1.MatchResources{}
+		}
+		current.Spec.MatchConstraints.ResourceRules = policy.Spec.MatchConstraints.ResourceRules
+		current.Spec.Validations = policy.Spec.Validations
+		_, err = policies.Update(ctx, current, metav1.UpdateOptions{DryRun: dryRunOptions(dryRun)})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, AdmissionGuardChange("update", "ValidatingAdmissionPolicy", policy.Name))
+	}
+
+	bindings := clientset.AdmissionregistrationV1().ValidatingAdmissionPolicyBindings()
+	binding := guard.Binding()
+	currentBinding, err := bindings.Get(ctx, binding.Name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = bindings.Create(ctx, binding, metav1.CreateOptions{DryRun: dryRunOptions(dryRun)})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, AdmissionGuardChange("create", "ValidatingAdmissionPolicyBinding", binding.Name))
+	case err != nil:
+		return changes, err
+	case currentBinding.Spec.PolicyName != binding.Spec.PolicyName || !equality.Semantic.DeepEqual(currentBinding.Spec.ValidationActions, binding.Spec.ValidationActions):
+		currentBinding.Labels = binding.Labels
+		currentBinding.Spec.PolicyName = binding.Spec.PolicyName
+		currentBinding.Spec.ValidationActions = binding.Spec.ValidationActions
+		_, err = bindings.Update(ctx, currentBinding, metav1.UpdateOptions{DryRun: dryRunOptions(dryRun)})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, AdmissionGuardChange("update", "ValidatingAdmissionPolicyBinding", binding.Name))
+	}
+	return changes, nil
+}
+
+func admissionPolicyUpToDate(current *admissionregistrationv1.ValidatingAdmissionPolicy, policy *admissionregistrationv1.ValidatingAdmissionPolicy) bool {
+	return current.Labels[managedByLabelName] == admissionGuardManagedByValue &&
+		current.Spec.MatchConstraints != nil &&
+		equality.Semantic.DeepEqual(current.Spec.FailurePolicy, policy.Spec.FailurePolicy) &&
+		equality.Semantic.DeepEqual(current.Spec.MatchConstraints.ResourceRules, policy.Spec.MatchConstraints.ResourceRules) &&
+		equality.Semantic.DeepEqual(current.Spec.Validations, policy.Spec.Validations)
+}
+
+// AdmissionGuardExists returns true when both the policy and the binding exist.
+func AdmissionGuardExists(ctx context.Context, clientset *kubernetes.Clientset, name string) (exists bool, err error) {
+	_, err = clientset.AdmissionregistrationV1().ValidatingAdmissionPolicies().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	_, err = clientset.AdmissionregistrationV1().ValidatingAdmissionPolicyBindings().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteAdmissionGuard deletes the binding and then the policy. It returns the changes that were made, the ones that
+// didn't exist are left out.
+func DeleteAdmissionGuard(ctx context.Context, clientset *kubernetes.Clientset, name string, dryRun bool) (changes []string, err error) {
+	err = clientset.AdmissionregistrationV1().ValidatingAdmissionPolicyBindings().Delete(ctx, name, metav1.DeleteOptions{DryRun: dryRunOptions(dryRun)})
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return changes, err
+	case err == nil:
+		changes = append(changes, AdmissionGuardChange("delete", "ValidatingAdmissionPolicyBinding", name))
+	}
+
+	err = clientset.AdmissionregistrationV1().ValidatingAdmissionPolicies().Delete(ctx, name, metav1.DeleteOptions{DryRun: dryRunOptions(dryRun)})
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return changes, err
+	case err == nil:
+		changes = append(changes, AdmissionGuardChange("delete", "ValidatingAdmissionPolicy", name))
+	}
+	return changes, nil
+}
+
+// admissionGuardObjectsString lists the guarded objects for messages.
+func admissionGuardObjectsString(objects []ClusterObject) string {
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		names = append(names, object.String())
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const retryOnConnectionRefused string = "connection_refused"
+const retryOnUnauthorized string = "unauthorized"
+const retryOnTooManyRequests string = "too_many_requests"
+const retryOnServerError string = "server_error"
+
+// retryOnAll are the classes of error that are retried by default.
+var retryOnAll = []string{retryOnConnectionRefused, retryOnUnauthorized, retryOnTooManyRequests, retryOnServerError}
+
+const defaultRetryAttempts = 10
+const defaultRetryBackoff = 1 * time.Second
+const defaultRetryMaxBackoff = 30 * time.Second
+const defaultReadyTimeout = 5 * time.Minute
+
+const readyzPollInterval = 5 * time.Second
+
+// RetryPolicy decides which API server requests are retried and how long to wait between attempts. A freshly created
+// EKS cluster refuses connections, answers 401 while the access entries or aws-auth propagate and sheds load with 429
+// and 5xx responses for a while.
+type RetryPolicy struct {
+	// Attempts is the total number of attempts, including the first one
+	Attempts int
+	// Backoff is the delay before the first retry, it doubles after every attempt up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// RetryOn are the classes of error that are retried, see retryOnAll
+	RetryOn []string
+}
+
+// Retries returns whether the class of error is retried.
+func (p RetryPolicy) Retries(class string) bool {
+	for _, retryOn := range p.RetryOn {
+		if retryOn == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Retriable returns the class of error of a response to a request with the method, and whether it is retried.
+// Responses that aren't an error have no class. A refused connection never reached the API server, so it is retried
+// for every method. Error responses are only retried for idempotent methods, as a create or delete may have been
+// carried out even though its response was lost or is an error.
+func (p RetryPolicy) Retriable(method string, resp *http.Response, err error) (class string, retriable bool) {
+	switch {
+	case err != nil && errors.Is(err, syscall.ECONNREFUSED):
+		class = retryOnConnectionRefused
+	case err != nil:
+		return "", false
+	case !idempotentMethod(method):
+		return "", false
+	case resp.StatusCode == http.StatusUnauthorized:
+		class = retryOnUnauthorized
+	case resp.StatusCode == http.StatusTooManyRequests:
+		class = retryOnTooManyRequests
+	case resp.StatusCode >= http.StatusInternalServerError:
+		class = retryOnServerError
+	default:
+		return "", false
+	}
+	return class, p.Retries(class)
+}
+
+// idempotentMethod returns whether a request with the method can be sent again without changing its outcome.
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// WrapTransport retries the requests of every client built from a rest.Config using the policy, so that every call to
+// the API server is covered.
+func (p RetryPolicy) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	if p.Attempts <= 1 {
+		return rt
+	}
+	return &retryRoundTripper{policy: p, next: rt}
+}
+
+type retryRoundTripper struct {
+	policy RetryPolicy
+	next   http.RoundTripper
+}
+
+func (t *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	backoff := t.policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+
+		class, retriable := t.policy.Retriable(req.Method, resp, err)
+		if !retriable || attempt >= t.policy.Attempts || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		delay := backoff
+		if resp != nil {
+			// Honour the delay a throttling API server asks for, when it is longer than ours
+			if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && time.Duration(seconds)*time.Second > delay {
+				delay = time.Duration(seconds) * time.Second
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		tflog.Debug(ctx, "Retrying API server request", map[string]interface{}{
+			"method":  req.Method,
+			"url":     req.URL.String(),
+			"class":   class,
+			"attempt": attempt,
+			"delay":   delay.String(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		backoff *= 2
+		if backoff > t.policy.MaxBackoff {
+			backoff = t.policy.MaxBackoff
+		}
+
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// WaitForApiServerReady polls /readyz until the API server reports that it is ready, or the timeout expires.
+func WaitForApiServerReady(ctx context.Context, clientset *kubernetes.Clientset, timeout time.Duration) error {
+	var lastErr error
+	err := wait.PollUntilContextTimeout(ctx, readyzPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		_, lastErr = clientset.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+		return lastErr == nil, nil
+	})
+
+	if err != nil && wait.Interrupted(err) {
+		return fmt.Errorf("timed out after %s waiting for the API server to be ready: %v", timeout, lastErr)
+	}
+	return err
+}
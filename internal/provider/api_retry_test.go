@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestRetryPolicyRetriable(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	response := func(statusCode int) *http.Response {
+		return &http.Response{StatusCode: statusCode}
+	}
+	all := RetryPolicy{RetryOn: retryOnAll}
+
+	tests := []struct {
+		name          string
+		policy        RetryPolicy
+		method        string
+		resp          *http.Response
+		err           error
+		wantClass     string
+		wantRetriable bool
+	}{
+		{name: "get ok", policy: all, method: http.MethodGet, resp: response(http.StatusOK)},
+		{name: "get not found", policy: all, method: http.MethodGet, resp: response(http.StatusNotFound)},
+		{name: "get conflict", policy: all, method: http.MethodGet, resp: response(http.StatusConflict)},
+		{name: "get connection refused", policy: all, method: http.MethodGet, err: refused, wantClass: retryOnConnectionRefused, wantRetriable: true},
+		{name: "get wrapped connection refused", policy: all, method: http.MethodGet, err: fmt.Errorf("request: %w", refused), wantClass: retryOnConnectionRefused, wantRetriable: true},
+		{name: "get other error", policy: all, method: http.MethodGet, err: errors.New("connection reset")},
+		{name: "get unauthorized", policy: all, method: http.MethodGet, resp: response(http.StatusUnauthorized), wantClass: retryOnUnauthorized, wantRetriable: true},
+		{name: "get too many requests", policy: all, method: http.MethodGet, resp: response(http.StatusTooManyRequests), wantClass: retryOnTooManyRequests, wantRetriable: true},
+		{name: "get server error", policy: all, method: http.MethodGet, resp: response(http.StatusInternalServerError), wantClass: retryOnServerError, wantRetriable: true},
+		{name: "get service unavailable", policy: all, method: http.MethodGet, resp: response(http.StatusServiceUnavailable), wantClass: retryOnServerError, wantRetriable: true},
+		{name: "head server error", policy: all, method: http.MethodHead, resp: response(http.StatusBadGateway), wantClass: retryOnServerError, wantRetriable: true},
+		{name: "options unauthorized", policy: all, method: http.MethodOptions, resp: response(http.StatusUnauthorized), wantClass: retryOnUnauthorized, wantRetriable: true},
+		{name: "post connection refused", policy: all, method: http.MethodPost, err: refused, wantClass: retryOnConnectionRefused, wantRetriable: true},
+		{name: "delete connection refused", policy: all, method: http.MethodDelete, err: refused, wantClass: retryOnConnectionRefused, wantRetriable: true},
+		{name: "post unauthorized", policy: all, method: http.MethodPost, resp: response(http.StatusUnauthorized)},
+		{name: "put too many requests", policy: all, method: http.MethodPut, resp: response(http.StatusTooManyRequests)},
+		{name: "patch server error", policy: all, method: http.MethodPatch, resp: response(http.StatusInternalServerError)},
+		{name: "delete server error", policy: all, method: http.MethodDelete, resp: response(http.StatusServiceUnavailable)},
+		{name: "post other error", policy: all, method: http.MethodPost, err: errors.New("connection reset")},
+		{name: "class not retried", policy: RetryPolicy{RetryOn: []string{retryOnConnectionRefused}}, method: http.MethodGet, resp: response(http.StatusUnauthorized), wantClass: retryOnUnauthorized},
+		{name: "nothing retried", policy: RetryPolicy{}, method: http.MethodGet, err: refused, wantClass: retryOnConnectionRefused},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			class, retriable := test.policy.Retriable(test.method, test.resp, test.err)
+			if class != test.wantClass || retriable != test.wantRetriable {
+				t.Errorf("Retriable() = %q, %t, want %q, %t", class, retriable, test.wantClass, test.wantRetriable)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const auditActionRemoved string = "removed"
+const auditActionAdopted string = "adopted"
+const auditActionRestored string = "restored"
+
+// auditReasons are the event reasons for each action.
+var auditReasons = map[string]string{
+	auditActionRemoved:  "CleanEksRemoved",
+	auditActionAdopted:  "CleanEksAdopted",
+	auditActionRestored: "CleanEksRestored",
+}
+
+const auditLedgerNamespace string = "kube-system"
+const auditLedgerConfigMapName string = "cleaneks-ledger"
+const auditComponentName string = "cleaneks"
+
+// AuditedObjects are every object the job may remove or adopt.
+var AuditedObjects = append(append(append([]ClusterObject{}, AwsCniObjects...), KubeProxyObjects...), CorednsHelmObjects...)
+
+// AuditEntry is a single line of the ledger config map.
+type AuditEntry struct {
+	Timestamp               string `json:"timestamp"`
+	ProviderVersion         string `json:"providerVersion"`
+	Action                  string `json:"action"`
+	Kind                    string `json:"kind"`
+	Namespace               string `json:"namespace"`
+	Name                    string `json:"name"`
+	PreviousResourceVersion string `json:"previousResourceVersion"`
+}
+
+// Key returns the config map key of the entry, which sorts in the order the entries were recorded.
+func (e AuditEntry) Key() string {
+	return strings.ToLower(fmt.Sprintf("%s.%s.%s.%s.%s", strings.NewReplacer(":", "", "-", "").Replace(e.Timestamp), e.Action, e.Kind, e.Namespace, e.Name))
+}
+
+// AuditLog records removals and adoptions as Kubernetes Events on the affected objects and as entries of the ledger
+// config map. The metadata of the objects is snapshotted before they are changed, so that the previous resource
+// version is known after an object is gone.
+type AuditLog struct {
+	clientset       *kubernetes.Clientset
+	providerVersion string
+	dryRun          bool
+
+	snapshots map[ClusterObject]*metav1.ObjectMeta
+	entries   []AuditEntry
+	actions   map[ClusterObject]string
+}
+
+func NewAuditLog(clientset *kubernetes.Clientset, providerVersion string, dryRun bool) *AuditLog {
+	return &AuditLog{
+		clientset:       clientset,
+		providerVersion: providerVersion,
+		dryRun:          dryRun,
+		snapshots:       map[ClusterObject]*metav1.ObjectMeta{},
+		actions:         map[ClusterObject]string{},
+	}
+}
+
+// Snapshot remembers the metadata of the objects as they are before any change.
+func (a *AuditLog) Snapshot(ctx context.Context, objects []ClusterObject) error {
+	for _, object := range objects {
+		objectMeta, err := GetObjectMeta(ctx, a.clientset, object)
+		if err != nil {
+			return err
+		}
+		a.snapshots[object] = objectMeta
+	}
+	return nil
+}
+
+// Record emits an event on the object and queues a ledger entry. Nothing is recorded during a dry run.
+func (a *AuditLog) Record(ctx context.Context, action string, object ClusterObject) error {
+	if a.dryRun {
+		return nil
+	}
+	a.actions[object] = action
+
+	now := time.Now().UTC()
+	entry := AuditEntry{
+		Timestamp:       now.Format(time.RFC3339Nano),
+		ProviderVersion: a.providerVersion,
+		Action:          action,
+		Kind:            object.Kind,
+		Namespace:       object.Namespace,
+		Name:            object.Name,
+	}
+
+	involvedObject := corev1.ObjectReference{
+		APIVersion: object.APIVersion(),
+		Kind:       object.Kind,
+		Namespace:  object.Namespace,
+		Name:       object.Name,
+	}
+	if objectMeta := a.snapshots[object]; objectMeta != nil {
+		entry.PreviousResourceVersion = objectMeta.ResourceVersion
+		involvedObject.UID = objectMeta.UID
+		involvedObject.ResourceVersion = objectMeta.ResourceVersion
+	}
+	a.entries = append(a.entries, entry)
+
+	message := fmt.Sprintf("Removed by terraform-provider-cleaneks %s", a.providerVersion)
+	switch action {
+	case auditActionAdopted:
+		message = fmt.Sprintf("Adopted into Helm release %s/%s by terraform-provider-cleaneks %s", helmReleaseNamespaceAnnotationValue, helmReleaseNameAnnotationValue, a.providerVersion)
+	case auditActionRestored:
+		message = fmt.Sprintf("Restored from Helm release %s/%s by terraform-provider-cleaneks %s", helmReleaseNamespaceAnnotationValue, helmReleaseNameAnnotationValue, a.providerVersion)
+	}
+
+	eventTime := metav1.NewTime(now)
+	_, err := a.clientset.CoreV1().Events(object.Namespace).Create(ctx, &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s.", object.Name),
+			Namespace:    object.Namespace,
+		},
+		InvolvedObject: involvedObject,
+		Reason:         auditReasons[action],
+		Message:        message,
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: auditComponentName},
+		FirstTimestamp: eventTime,
+		LastTimestamp:  eventTime,
+		Count:          1,
+	}, metav1.CreateOptions{})
+	return err
+}
+
+// Actions returns the last action recorded for each object.
+func (a *AuditLog) Actions() map[ClusterObject]string {
+	return a.actions
+}
+
+// Flush writes the queued entries to the ledger config map, creating it when needed.
+func (a *AuditLog) Flush(ctx context.Context) error {
+	if len(a.entries) == 0 {
+		return nil
+	}
+
+	data := map[string]string{}
+	for _, entry := range a.entries {
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		data[entry.Key()] = string(value)
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := a.clientset.CoreV1().ConfigMaps(auditLedgerNamespace).Get(ctx, auditLedgerConfigMapName, metav1.GetOptions{})
+		switch {
+		case err != nil && !errors.IsNotFound(err):
+			return err
+		case errors.IsNotFound(err):
+			_, err = a.clientset.CoreV1().ConfigMaps(auditLedgerNamespace).Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      auditLedgerConfigMapName,
+					Namespace: auditLedgerNamespace,
+					Labels: map[string]string{
+						"app.kubernetes.io/name": auditComponentName,
+					},
+				},
+				Data: data,
+			}, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				// Created by a concurrent run, retry as an update
+				return errors.NewConflict(corev1.Resource("configmaps"), auditLedgerConfigMapName, err)
+			}
+			return err
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		for key, value := range data {
+			configMap.Data[key] = value
+		}
+		_, err = a.clientset.CoreV1().ConfigMaps(auditLedgerNamespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	a.entries = nil
+	return nil
+}
+
+// LedgerRecords returns whether the ledger config map has an entry of the action on the object.
+func LedgerRecords(ctx context.Context, clientset *kubernetes.Clientset, action string, object ClusterObject) (bool, error) {
+	configMap, err := clientset.CoreV1().ConfigMaps(auditLedgerNamespace).Get(ctx, auditLedgerConfigMapName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, readError("get", fmt.Sprintf("ConfigMap %s/%s", auditLedgerNamespace, auditLedgerConfigMapName), err)
+	}
+
+	for _, value := range configMap.Data {
+		var entry AuditEntry
+		if json.Unmarshal([]byte(value), &entry) != nil {
+			continue
+		}
+		if entry.Action == action && entry.Kind == object.Kind && entry.Namespace == object.Namespace && entry.Name == object.Name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const awsCniDependencyPodEni string = "pod_eni"
+const awsCniDependencyNetworkPolicy string = "network_policy"
+const awsCniDependencyCustomNetworking string = "custom_networking"
+
+const awsCniContainerName string = "aws-node"
+const awsCniConfigMapName string = "amazon-vpc-cni"
+const podEniAnnotationName string = "vpc.amazonaws.com/pod-eni"
+
+// awsCniDependencyDescriptions explains what breaks for each VPC CNI feature once aws-node is removed.
+var awsCniDependencyDescriptions = map[string]string{
+	awsCniDependencyPodEni:           "security groups for pods (pods using branch ENIs lose their network attachment)",
+	awsCniDependencyNetworkPolicy:    "VPC CNI network policy enforcement (network policies stop being enforced)",
+	awsCniDependencyCustomNetworking: "VPC CNI custom networking (new pods are no longer placed on ENIConfig subnets)",
+}
+
+// AwsCniDependency is a VPC CNI feature in use by the cluster, with the evidence that it is in use.
+type AwsCniDependency struct {
+	Name     string
+	Evidence []string
+}
+
+func (d AwsCniDependency) String() string {
+	return fmt.Sprintf("%s - %s, detected from: %s", d.Name, awsCniDependencyDescriptions[d.Name], strings.Join(d.Evidence, "; "))
+}
+
+// AwsCniDependencies inspects the aws-node daemonset, the amazon-vpc-cni config map, pod annotations and VPC CNI
+// custom resources for features that silently break when aws-node is removed.
+func AwsCniDependencies(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (dependencies []AwsCniDependency, err error) {
+	evidence := map[string][]string{}
+
+	daemonset, err := clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return nil, err
+	case errors.IsNotFound(err):
+		return nil, nil
+	}
+
+	for _, container := range daemonset.Spec.Template.Spec.Containers {
+		if container.Name != awsCniContainerName {
+			continue
+		}
+		for _, env := range container.Env {
+			switch {
+			case env.Name == "ENABLE_POD_ENI" && strings.EqualFold(env.Value, "true"):
+				evidence[awsCniDependencyPodEni] = append(evidence[awsCniDependencyPodEni], fmt.Sprintf("daemonset %s/%s has ENABLE_POD_ENI=true", namespace, name))
+			case env.Name == "AWS_VPC_K8S_CNI_CUSTOM_NETWORK_CFG" && strings.EqualFold(env.Value, "true"):
+				evidence[awsCniDependencyCustomNetworking] = append(evidence[awsCniDependencyCustomNetworking], fmt.Sprintf("daemonset %s/%s has AWS_VPC_K8S_CNI_CUSTOM_NETWORK_CFG=true", namespace, name))
+			}
+		}
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, awsCniConfigMapName, metav1.GetOptions{})
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return nil, err
+	case err == nil:
+		if strings.EqualFold(configMap.Data["enable-network-policy-controller"], "true") {
+			evidence[awsCniDependencyNetworkPolicy] = append(evidence[awsCniDependencyNetworkPolicy], fmt.Sprintf("config map %s/%s has enable-network-policy-controller=true", namespace, awsCniConfigMapName))
+		}
+	}
+
+	pods, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	podsWithEni := 0
+	for _, pod := range pods.Items {
+		if _, ok := pod.Annotations[podEniAnnotationName]; ok {
+			podsWithEni++
+		}
+	}
+	if podsWithEni > 0 {
+		evidence[awsCniDependencyPodEni] = append(evidence[awsCniDependencyPodEni], fmt.Sprintf("%d pods are annotated with %s", podsWithEni, podEniAnnotationName))
+	}
+
+	policyEndpoints, err := CustomResourceCount(ctx, clientset, "networking.k8s.aws", "v1alpha1", "policyendpoints")
+	if err != nil {
+		return nil, err
+	}
+	if policyEndpoints > 0 {
+		evidence[awsCniDependencyNetworkPolicy] = append(evidence[awsCniDependencyNetworkPolicy], fmt.Sprintf("%d PolicyEndpoint objects exist", policyEndpoints))
+	}
+
+	if len(evidence[awsCniDependencyNetworkPolicy]) > 0 {
+		networkPolicies, err := clientset.NetworkingV1().NetworkPolicies(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if len(networkPolicies.Items) > 0 {
+			evidence[awsCniDependencyNetworkPolicy] = append(evidence[awsCniDependencyNetworkPolicy], fmt.Sprintf("%d NetworkPolicy objects are enforced by the VPC CNI", len(networkPolicies.Items)))
+		}
+	}
+
+	eniConfigs, err := CustomResourceCount(ctx, clientset, "crd.k8s.amazonaws.com", "v1alpha1", "eniconfigs")
+	if err != nil {
+		return nil, err
+	}
+	if eniConfigs > 0 {
+		evidence[awsCniDependencyCustomNetworking] = append(evidence[awsCniDependencyCustomNetworking], fmt.Sprintf("%d ENIConfig objects exist", eniConfigs))
+	}
+
+	for _, dependency := range []string{awsCniDependencyPodEni, awsCniDependencyNetworkPolicy, awsCniDependencyCustomNetworking} {
+		if len(evidence[dependency]) > 0 {
+			dependencies = append(dependencies, AwsCniDependency{Name: dependency, Evidence: evidence[dependency]})
+		}
+	}
+	return dependencies, nil
+}
+
+// CustomResourceCount counts the objects of a custom resource across all namespaces. It returns zero when the custom
+// resource definition is not installed.
+func CustomResourceCount(ctx context.Context, clientset *kubernetes.Clientset, group string, version string, resource string) (count int, err error) {
+	data, err := clientset.Discovery().RESTClient().Get().AbsPath("apis", group, version, resource).DoRaw(ctx)
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return 0, err
+	case errors.IsNotFound(err):
+		return 0, nil
+	}
+
+	list := metav1.PartialObjectMetadataList{}
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return 0, err
+	}
+	return len(list.Items), nil
+}
+
+// UnacknowledgedAwsCniDependencies describes the dependencies that aren't acknowledged, which stop AWS CNI from being
+// removed.
+func UnacknowledgedAwsCniDependencies(dependencies []AwsCniDependency, acknowledged []string) (unacknowledged []string) {
+	acknowledgedNames := map[string]bool{}
+	for _, name := range acknowledged {
+		acknowledgedNames[name] = true
+	}
+
+	unacknowledged = []string{}
+	for _, dependency := range dependencies {
+		if !acknowledgedNames[dependency.Name] {
+			unacknowledged = append(unacknowledged, dependency.String())
+		}
+	}
+	return unacknowledged
+}
@@ -0,0 +1,398 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// CliConnection are the connection attributes of the provider, for the subcommands that run a job outside Terraform.
+type CliConnection struct {
+	Host                  string
+	ClusterName           string
+	Region                string
+	EksEndpoint           string
+	Username              string
+	Password              string
+	Insecure              bool
+	TLSServerName         string
+	ClientCertificate     string
+	ClientKey             string
+	ClusterCACertificate  string
+	ConfigPaths           []string
+	ConfigPath            string
+	ConfigContext         string
+	ConfigContextAuthInfo string
+	ConfigContextCluster  string
+	Token                 string
+	ProxyURL              string
+	ImpersonateUser       string
+	ImpersonateUid        string
+	ImpersonateGroups     []string
+
+	// EksAuth generates the token in-process like the eks_auth block, for ClusterName
+	EksAuth        bool
+	EksAuthRoleArn string
+	EksAuthProfile string
+
+	ReadyTimeout string
+}
+
+// NewCliProvider returns a provider configured with the connection, as if it had been configured by Terraform.
+func NewCliProvider(version string, connection CliConnection) *CleanEksProvider {
+	model := CleanEksProviderModel{
+		Host:                  cliString(connection.Host),
+		ClusterName:           cliString(connection.ClusterName),
+		Region:                cliString(connection.Region),
+		EksEndpoint:           cliString(connection.EksEndpoint),
+		Username:              cliString(connection.Username),
+		Password:              cliString(connection.Password),
+		Insecure:              types.BoolValue(connection.Insecure),
+		TLSServerName:         cliString(connection.TLSServerName),
+		ClientCertificate:     cliString(connection.ClientCertificate),
+		ClientKey:             cliString(connection.ClientKey),
+		ClusterCACertificate:  cliString(connection.ClusterCACertificate),
+		ConfigPaths:           connection.ConfigPaths,
+		ConfigPath:            cliString(connection.ConfigPath),
+		ConfigContext:         cliString(connection.ConfigContext),
+		ConfigContextAuthInfo: cliString(connection.ConfigContextAuthInfo),
+		ConfigContextCluster:  cliString(connection.ConfigContextCluster),
+		Token:                 cliString(connection.Token),
+		ProxyURL:              cliString(connection.ProxyURL),
+		ImpersonateUser:       cliString(connection.ImpersonateUser),
+		ImpersonateUid:        cliString(connection.ImpersonateUid),
+		ImpersonateGroups:     connection.ImpersonateGroups,
+		ReadyTimeout:          cliString(connection.ReadyTimeout),
+		RetryOn:               types.SetNull(types.StringType),
+	}
+	if connection.EksAuth {
+		model.EksAuth = make([]struct {
+			ClusterName types.String `tfsdk:"cluster_name"`
+			Region      types.String `tfsdk:"region"`
+			RoleArn     types.String `tfsdk:"role_arn"`
+			Profile     types.String `tfsdk:"profile"`
+		}, 1)
+		model.EksAuth[0].RoleArn = cliString(connection.EksAuthRoleArn)
+		model.EksAuth[0].Profile = cliString(connection.EksAuthProfile)
+	}
+	return &CleanEksProvider{Version: version, model: model}
+}
+
+// cliString leaves the attribute null when the flag wasn't set, the same as when it is left out of the configuration.
+func cliString(value string) types.String {
+	if value == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(value)
+}
+
+// Subcommands that run a job outside Terraform.
+const (
+	CliCommandClean   string = "clean"
+	CliCommandStatus  string = "status"
+	CliCommandAdopt   string = "adopt"
+	CliCommandRestore string = "restore"
+)
+
+// CliJobOptions completes the options of a subcommand. clean and status take the component actions from the flags,
+// adopt and restore only change CoreDNS. The settings left empty get the defaults of a cleaneks_job.
+func CliJobOptions(command string, options JobOptions) (JobOptions, error) {
+	switch command {
+	case CliCommandClean, CliCommandStatus:
+	case CliCommandAdopt:
+		options.AwsCni, options.KubeProxy, options.Coredns = componentActionKeep, componentActionKeep, componentActionAdopt
+	case CliCommandRestore:
+		options.AwsCni, options.KubeProxy, options.Coredns = componentActionKeep, componentActionKeep, componentActionRestore
+	default:
+		return options, fmt.Errorf("unknown command %q", command)
+	}
+
+	for _, action := range []struct {
+		flag    string
+		value   string
+		allowed []string
+	}{
+		{"aws-cni", options.AwsCni, []string{componentActionKeep, componentActionRemove}},
+		{"kube-proxy", options.KubeProxy, []string{componentActionKeep, componentActionRemove}},
+		{"coredns", options.Coredns, []string{componentActionKeep, componentActionRemove, componentActionAdopt, componentActionRestore}},
+		{"conflict-policy", options.ConflictPolicy, []string{conflictPolicyFail, conflictPolicyTakeover, conflictPolicySkip}},
+	} {
+		valid := action.value == ""
+		for _, allowed := range action.allowed {
+			valid = valid || action.value == allowed
+		}
+		if !valid {
+			return options, fmt.Errorf("%s: unsupported value %q, use one of %s", action.flag, action.value, strings.Join(action.allowed, ", "))
+		}
+	}
+
+	if options.ConflictPolicy == "" {
+		options.ConflictPolicy = conflictPolicyFail
+	}
+	if options.LockLeaseName == "" {
+		options.LockLeaseName = defaultLockLeaseName
+	}
+	if options.AdmissionGuard != nil {
+		if options.AdmissionGuard.Name == "" {
+			options.AdmissionGuard.Name = defaultAdmissionGuardName
+		}
+		options.AdmissionGuard.Objects = AdmissionGuardObjects(options.AwsCni, options.KubeProxy, nil)
+		if len(options.AdmissionGuard.Objects) == 0 {
+			return options, fmt.Errorf("admission-guard: needs aws-cni or kube-proxy to be removed")
+		}
+	}
+	return options, nil
+}
+
+// CliReport is what a subcommand found and changed.
+type CliReport struct {
+	Command    string `json:"command"`
+	Host       string `json:"host"`
+	ClusterUid string `json:"cluster_uid,omitempty"`
+	ClusterArn string `json:"cluster_arn,omitempty"`
+	DryRun     bool   `json:"dry_run"`
+
+	// Changes are the diffs and deletes that were made, or would have been made during a dry run
+	Changes       []string             `json:"changes"`
+	HelmConflicts []string             `json:"helm_conflicts"`
+	Components    []CliComponentReport `json:"components"`
+	// Unsatisfied are the components whose action doesn't hold in the cluster
+	Unsatisfied []string `json:"unsatisfied"`
+
+	Warnings []CliMessage `json:"warnings"`
+	Errors   []CliMessage `json:"errors"`
+}
+
+type CliComponentReport struct {
+	Kind           string          `json:"kind"`
+	Namespace      string          `json:"namespace"`
+	Name           string          `json:"name"`
+	Exists         bool            `json:"exists"`
+	IsEksManaged   bool            `json:"is_eks_managed"`
+	IsAddonManaged bool            `json:"is_addon_managed"`
+	Adoption       map[string]bool `json:"adoption"`
+	LastAction     string          `json:"last_action,omitempty"`
+}
+
+type CliMessage struct {
+	Summary string `json:"summary"`
+	Detail  string `json:"detail"`
+}
+
+func (r *CliReport) addDiagnostics(diags diag.Diagnostics) {
+	for _, d := range diags {
+		message := CliMessage{Summary: d.Summary(), Detail: d.Detail()}
+		if d.Severity() == diag.SeverityError {
+			r.Errors = append(r.Errors, message)
+		} else {
+			r.Warnings = append(r.Warnings, message)
+		}
+	}
+}
+
+// HasErrors returns true when the subcommand failed.
+func (r *CliReport) HasErrors() bool {
+	return len(r.Errors) > 0
+}
+
+// RunCli runs the steps of a cleaneks_job with the options against the cluster of the provider, and reports on every
+// component afterwards. The status command changes nothing and only reports.
+func RunCli(ctx context.Context, p *CleanEksProvider, command string, options JobOptions, manageEksAddons bool) (report *CliReport) {
+	report = &CliReport{
+		Command:       command,
+		DryRun:        options.DryRun,
+		Changes:       []string{},
+		HelmConflicts: []string{},
+		Components:    []CliComponentReport{},
+		Unsatisfied:   []string{},
+		Warnings:      []CliMessage{},
+		Errors:        []CliMessage{},
+	}
+	options.ReadyTimeout = p.ReadyTimeout()
+	options.ReadObjects = jobReadObjects
+
+	if manageEksAddons {
+		clusterName := p.AwsOptions().ClusterName
+		if clusterName == "" {
+			clusterName = options.ExpectedClusterName
+		}
+		if clusterName == "" {
+			report.addDiagnostics(diag.Diagnostics{diag.NewErrorDiagnostic(
+				"Missing EKS cluster name",
+				"manage-eks-addons needs the name of the EKS cluster, set cluster-name or expected-cluster-name.",
+			)})
+			return report
+		}
+		options.EksAddons = &EksAddons{ClusterName: clusterName, Client: p.EksClient}
+	}
+
+	clientSet, err := p.GetClientSet(ctx)
+	if err != nil {
+		report.addDiagnostics(diag.Diagnostics{diag.NewErrorDiagnostic(
+			"Error getting Kubernetes client",
+			fmt.Sprintf("Error getting Kubernetes client: %s", err),
+		)})
+		return report
+	}
+	report.Host = p.Host()
+
+	// The cluster may have only just been created, so it is waited for before the identity is read
+	diags := waitForApiServer(ctx, clientSet, options.ReadyTimeout)
+	report.addDiagnostics(diags)
+	if diags.HasError() {
+		return report
+	}
+
+	identity, identityDiags := checkClusterIdentity(ctx, clientSet, ClusterIdentity{}, false, p.EksClusterArn())
+	report.addDiagnostics(identityDiags)
+	if identityDiags.HasError() {
+		return report
+	}
+	report.ClusterUid = identity.Uid
+	report.ClusterArn = identity.Arn
+	options.ClusterArn = identity.Arn
+
+	var actions map[ClusterObject]string
+	if command != CliCommandStatus {
+		run, runDiags := RunJob(ctx, clientSet, p.Version, options)
+		report.addDiagnostics(runDiags)
+		report.Changes = append(report.Changes, run.Changes...)
+		report.HelmConflicts = append(report.HelmConflicts, run.HelmConflicts...)
+		actions = run.Audit.Actions()
+		if runDiags.HasError() {
+			return report
+		}
+	}
+
+	statuses, errs := CheckComponents(ctx, clientSet, AuditedObjects)
+	report.addDiagnostics(errs.Diagnostics())
+	for _, object := range AuditedObjects {
+		status, ok := statuses[object]
+		if !ok {
+			continue
+		}
+		report.Components = append(report.Components, CliComponentReport{
+			Kind:           object.Kind,
+			Namespace:      object.Namespace,
+			Name:           object.Name,
+			Exists:         status.Exists,
+			IsEksManaged:   status.IsEksManaged,
+			IsAddonManaged: status.IsAddonManaged,
+			Adoption:       status.Adoption,
+			LastAction:     actions[object],
+		})
+	}
+	if len(errs) == 0 {
+		report.Unsatisfied = UnsatisfiedActions(options, statuses, report.HelmConflicts)
+	}
+	return report
+}
+
+// UnsatisfiedActions returns the components whose action doesn't hold for the statuses, in the same way a
+// cleaneks_job detects drift.
+func UnsatisfiedActions(options JobOptions, statuses map[ClusterObject]ComponentStatus, helmConflicts []string) (unsatisfied []string) {
+	unsatisfied = []string{}
+	anyExists := func(objects []ClusterObject, eksManagedOnly bool) bool {
+		for _, object := range objects {
+			status := statuses[object]
+			if status.Exists && (!eksManagedOnly || status.IsEksManaged) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if options.AwsCni == componentActionRemove && anyExists(AwsCniObjects, false) {
+		unsatisfied = append(unsatisfied, "aws_cni")
+	}
+	if options.KubeProxy == componentActionRemove && anyExists(KubeProxyObjects, false) {
+		unsatisfied = append(unsatisfied, "kube_proxy")
+	}
+	switch options.Coredns {
+	case componentActionRemove:
+		if anyExists(CorednsHelmObjects, true) {
+			unsatisfied = append(unsatisfied, "coredns")
+		}
+	case componentActionAdopt:
+		if !CorednsAdopted(statuses, options.ConflictPolicy, helmConflicts) {
+			unsatisfied = append(unsatisfied, "coredns")
+		}
+	case componentActionRestore:
+		if !CorednsRestored(statuses) {
+			unsatisfied = append(unsatisfied, "coredns")
+		}
+	}
+	return unsatisfied
+}
+
+// WriteJson writes the report as indented JSON.
+func (r *CliReport) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteText writes the report for people to read.
+func (r *CliReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+	dryRun := ""
+	if r.DryRun {
+		dryRun = " (dry run)"
+	}
+	fmt.Fprintf(&b, "cleaneks %s%s against %s\n", r.Command, dryRun, r.Host)
+	if r.ClusterUid != "" {
+		fmt.Fprintf(&b, "Cluster: %s\n", strings.TrimSpace(r.ClusterUid+" "+r.ClusterArn))
+	}
+
+	if r.Command != CliCommandStatus {
+		if len(r.Changes) == 0 && !r.HasErrors() {
+			fmt.Fprintf(&b, "\nNo changes.\n")
+		} else if len(r.Changes) > 0 {
+			fmt.Fprintf(&b, "\nChanges:\n")
+			for _, change := range r.Changes {
+				fmt.Fprintf(&b, "  %s\n", strings.ReplaceAll(strings.TrimRight(change, "\n"), "\n", "\n  "))
+			}
+		}
+	}
+	if len(r.HelmConflicts) > 0 {
+		fmt.Fprintf(&b, "\nHelm conflicts:\n  - %s\n", strings.Join(r.HelmConflicts, "\n  - "))
+	}
+
+	if len(r.Components) > 0 {
+		fmt.Fprintf(&b, "\nComponents:\n")
+		table := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(table, "  OBJECT\tEXISTS\tEKS LABEL\tADD-ON\tADOPTED\tLAST ACTION\n")
+		for _, component := range r.Components {
+			adopted := "-"
+			if component.Exists {
+				adopted = fmt.Sprintf("%t", ComponentStatus{Adoption: component.Adoption}.Adopted())
+			}
+			fmt.Fprintf(table, "  %s %s/%s\t%t\t%t\t%t\t%s\t%s\n", component.Kind, component.Namespace, component.Name, component.Exists, component.IsEksManaged, component.IsAddonManaged, adopted, component.LastAction)
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(r.Unsatisfied) > 0 {
+		sorted := append([]string{}, r.Unsatisfied...)
+		sort.Strings(sorted)
+		fmt.Fprintf(&b, "\nNot in the requested state: %s\n", strings.Join(sorted, ", "))
+	}
+
+	for _, warning := range r.Warnings {
+		fmt.Fprintf(&b, "\nWarning: %s\n  %s\n", warning.Summary, strings.ReplaceAll(warning.Detail, "\n", "\n  "))
+	}
+	for _, err := range r.Errors {
+		fmt.Fprintf(&b, "\nError: %s\n  %s\n", err.Summary, strings.ReplaceAll(err.Detail, "\n", "\n  "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCliJobOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		options JobOptions
+		want    JobOptions
+		wantErr string
+	}{
+		{
+			name:    "clean",
+			command: CliCommandClean,
+			options: JobOptions{AwsCni: "remove", KubeProxy: "remove", Coredns: "keep"},
+			want:    JobOptions{AwsCni: "remove", KubeProxy: "remove", Coredns: "keep", ConflictPolicy: "fail", LockLeaseName: "cleaneks-job"},
+		},
+		{
+			name:    "status",
+			command: CliCommandStatus,
+			options: JobOptions{AwsCni: "keep", KubeProxy: "keep", Coredns: "adopt", ConflictPolicy: "skip", LockLeaseName: "pipeline"},
+			want:    JobOptions{AwsCni: "keep", KubeProxy: "keep", Coredns: "adopt", ConflictPolicy: "skip", LockLeaseName: "pipeline"},
+		},
+		{
+			name:    "adopt",
+			command: CliCommandAdopt,
+			options: JobOptions{ConflictPolicy: "takeover"},
+			want:    JobOptions{AwsCni: "keep", KubeProxy: "keep", Coredns: "adopt", ConflictPolicy: "takeover", LockLeaseName: "cleaneks-job"},
+		},
+		{
+			name:    "restore",
+			command: CliCommandRestore,
+			want:    JobOptions{AwsCni: "keep", KubeProxy: "keep", Coredns: "restore", ConflictPolicy: "fail", LockLeaseName: "cleaneks-job"},
+		},
+		{
+			name:    "admission guard defaults",
+			command: CliCommandClean,
+			options: JobOptions{AwsCni: "remove", KubeProxy: "remove", Coredns: "keep", AdmissionGuard: &AdmissionGuard{}},
+			want: JobOptions{AwsCni: "remove", KubeProxy: "remove", Coredns: "keep", ConflictPolicy: "fail", LockLeaseName: "cleaneks-job", AdmissionGuard: &AdmissionGuard{
+				Name:    "cleaneks-guard",
+				Objects: []ClusterObject{{Kind: "DaemonSet", Namespace: "kube-system", Name: "aws-node"}, {Kind: "DaemonSet", Namespace: "kube-system", Name: "kube-proxy"}},
+			}},
+		},
+		{
+			name:    "admission guard name",
+			command: CliCommandClean,
+			options: JobOptions{AwsCni: "remove", KubeProxy: "keep", Coredns: "keep", AdmissionGuard: &AdmissionGuard{Name: "guard"}},
+			want: JobOptions{AwsCni: "remove", KubeProxy: "keep", Coredns: "keep", ConflictPolicy: "fail", LockLeaseName: "cleaneks-job", AdmissionGuard: &AdmissionGuard{
+				Name:    "guard",
+				Objects: []ClusterObject{{Kind: "DaemonSet", Namespace: "kube-system", Name: "aws-node"}},
+			}},
+		},
+		{
+			name:    "admission guard without removal",
+			command: CliCommandAdopt,
+			options: JobOptions{AdmissionGuard: &AdmissionGuard{}},
+			wantErr: "admission-guard: needs aws-cni or kube-proxy to be removed",
+		},
+		{
+			name:    "unknown command",
+			command: "delete",
+			wantErr: `unknown command "delete"`,
+		},
+		{
+			name:    "adopt aws cni",
+			command: CliCommandClean,
+			options: JobOptions{AwsCni: "adopt"},
+			wantErr: `aws-cni: unsupported value "adopt", use one of keep, remove`,
+		},
+		{
+			name:    "unknown kube proxy action",
+			command: CliCommandClean,
+			options: JobOptions{KubeProxy: "delete"},
+			wantErr: `kube-proxy: unsupported value "delete", use one of keep, remove`,
+		},
+		{
+			name:    "unknown coredns action",
+			command: CliCommandStatus,
+			options: JobOptions{Coredns: "delete"},
+			wantErr: `coredns: unsupported value "delete", use one of keep, remove, adopt, restore`,
+		},
+		{
+			name:    "unknown conflict policy",
+			command: CliCommandAdopt,
+			options: JobOptions{ConflictPolicy: "overwrite"},
+			wantErr: `conflict-policy: unsupported value "overwrite", use one of fail, takeover, skip`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := CliJobOptions(test.command, test.options)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("CliJobOptions() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CliJobOptions() unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("CliJobOptions()\n got: %+v\nwant: %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnsatisfiedActions(t *testing.T) {
+	adopted := map[string]bool{
+		helmReleaseNameAnnotationName:      true,
+		helmReleaseNamespaceAnnotationName: true,
+		managedByLabelName:                 true,
+		amazonManagedLabelName:             true,
+	}
+	eks := map[string]bool{
+		helmReleaseNameAnnotationName:      false,
+		helmReleaseNamespaceAnnotationName: false,
+		managedByLabelName:                 false,
+		amazonManagedLabelName:             false,
+	}
+	// statuses returns the statuses of every audited object, with the given ones changed
+	statuses := func(changed ...ComponentStatus) map[ClusterObject]ComponentStatus {
+		statuses := map[ClusterObject]ComponentStatus{}
+		for _, object := range AuditedObjects {
+			statuses[object] = ComponentStatus{Object: object, Adoption: map[string]bool{}}
+		}
+		for _, status := range changed {
+			statuses[status.Object] = status
+		}
+		return statuses
+	}
+	corednsStatuses := func(status ComponentStatus) (changed []ComponentStatus) {
+		for _, object := range CorednsHelmObjects {
+			status.Object = object
+			changed = append(changed, status)
+		}
+		return changed
+	}
+	awsNode := AwsCniObjects[0]
+	kubeProxyConfigMap := KubeProxyObjects[1]
+	corednsDeployment := CorednsHelmObjects[0]
+
+	tests := []struct {
+		name          string
+		options       JobOptions
+		statuses      map[ClusterObject]ComponentStatus
+		helmConflicts []string
+		want          []string
+	}{
+		{
+			name:     "all removed",
+			options:  JobOptions{AwsCni: "remove", KubeProxy: "remove", Coredns: "remove"},
+			statuses: statuses(),
+			want:     []string{},
+		},
+		{
+			name:     "keep",
+			options:  JobOptions{AwsCni: "keep", KubeProxy: "keep", Coredns: "keep"},
+			statuses: statuses(append(corednsStatuses(ComponentStatus{Exists: true, IsEksManaged: true, Adoption: eks}), ComponentStatus{Object: awsNode, Exists: true})...),
+			want:     []string{},
+		},
+		{
+			name:     "aws cni back",
+			options:  JobOptions{AwsCni: "remove", KubeProxy: "remove"},
+			statuses: statuses(ComponentStatus{Object: awsNode, Exists: true, IsEksManaged: true}),
+			want:     []string{"aws_cni"},
+		},
+		{
+			name:     "kube proxy config map left",
+			options:  JobOptions{AwsCni: "remove", KubeProxy: "remove"},
+			statuses: statuses(ComponentStatus{Object: kubeProxyConfigMap, Exists: true}),
+			want:     []string{"kube_proxy"},
+		},
+		{
+			// Only the objects EKS installed count, a replacement under the same name doesn't
+			name:     "coredns replaced",
+			options:  JobOptions{Coredns: "remove"},
+			statuses: statuses(corednsStatuses(ComponentStatus{Exists: true, Adoption: adopted})...),
+			want:     []string{},
+		},
+		{
+			name:     "coredns back",
+			options:  JobOptions{Coredns: "remove"},
+			statuses: statuses(ComponentStatus{Object: corednsDeployment, Exists: true, IsEksManaged: true, Adoption: eks}),
+			want:     []string{"coredns"},
+		},
+		{
+			name:     "coredns adopted",
+			options:  JobOptions{Coredns: "adopt"},
+			statuses: statuses(corednsStatuses(ComponentStatus{Exists: true, Adoption: adopted})...),
+			want:     []string{},
+		},
+		{
+			name:     "coredns not adopted",
+			options:  JobOptions{Coredns: "adopt"},
+			statuses: statuses(corednsStatuses(ComponentStatus{Exists: true, IsEksManaged: true, Adoption: eks})...),
+			want:     []string{"coredns"},
+		},
+		{
+			name:          "coredns conflict skipped",
+			options:       JobOptions{Coredns: "adopt", ConflictPolicy: "skip"},
+			statuses:      statuses(ComponentStatus{Object: corednsDeployment, Exists: true, IsEksManaged: true, Adoption: eks}),
+			helmConflicts: []string{corednsDeployment.String() + ": owned by Helm release other/coredns"},
+			want:          []string{},
+		},
+		{
+			name:          "coredns conflict failed",
+			options:       JobOptions{Coredns: "adopt", ConflictPolicy: "fail"},
+			statuses:      statuses(ComponentStatus{Object: corednsDeployment, Exists: true, IsEksManaged: true, Adoption: eks}),
+			helmConflicts: []string{corednsDeployment.String() + ": owned by Helm release other/coredns"},
+			want:          []string{"coredns"},
+		},
+		{
+			name:     "coredns restored",
+			options:  JobOptions{Coredns: "restore"},
+			statuses: statuses(corednsStatuses(ComponentStatus{Exists: true, IsEksManaged: true, Adoption: eks})...),
+			want:     []string{},
+		},
+		{
+			name:     "coredns still adopted",
+			options:  JobOptions{Coredns: "restore"},
+			statuses: statuses(corednsStatuses(ComponentStatus{Exists: true, Adoption: adopted})...),
+			want:     []string{"coredns"},
+		},
+		{
+			name:     "everything back",
+			options:  JobOptions{AwsCni: "remove", KubeProxy: "remove", Coredns: "remove"},
+			statuses: statuses(append(corednsStatuses(ComponentStatus{Exists: true, IsEksManaged: true, Adoption: eks}), ComponentStatus{Object: awsNode, Exists: true}, ComponentStatus{Object: kubeProxyConfigMap, Exists: true})...),
+			want:     []string{"aws_cni", "kube_proxy", "coredns"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := UnsatisfiedActions(test.options, test.statuses, test.helmConflicts)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("UnsatisfiedActions() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
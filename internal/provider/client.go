@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"fmt"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -23,6 +24,20 @@ const managedByLabelValue string = "Helm"
 
 const amazonManagedLabelName string = "eks.amazonaws.com/component"
 
+// EKS applies the objects of its managed add-ons with server-side apply under this field manager.
+const eksAddonFieldManager string = "eks"
+
+// IsEksAddonManaged returns true when an EKS managed add-on owns fields of the object. Changes to such an object don't
+// last, the add-on puts it back the next time it reconciles.
+func IsEksAddonManaged(objectMeta metav1.ObjectMeta) bool {
+	for _, entry := range objectMeta.ManagedFields {
+		if entry.Manager == eksAddonFieldManager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
+
 func DaemonsetExist(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
 	_, err = clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
 	switch {
@@ -35,25 +50,26 @@ func DaemonsetExist(ctx context.Context, clientset *kubernetes.Clientset, namesp
 	}
 }
 
-func DaemonsetExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
+func DaemonsetExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, isAddonManaged bool, err error) {
 	deployment, err := clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
-		return false, err
+		return false, false, err
 	case errors.IsNotFound(err):
-		return false, nil
+		return false, false, nil
 	default:
+		isAddonManaged = IsEksAddonManaged(deployment.ObjectMeta)
 		if deployment.Labels == nil {
-			return false, nil
+			return false, isAddonManaged, nil
 		}
 
 		_, ok := deployment.Labels[amazonManagedLabelName]
-		return ok, nil
+		return ok, isAddonManaged, nil
 	}
 }
 
-func DeleteDaemonset(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
-	err = clientset.AppsV1().DaemonSets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+func DeleteDaemonset(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (exists bool, err error) {
+	err = clientset.AppsV1().DaemonSets(namespace).Delete(ctx, name, metav1.DeleteOptions{DryRun: dryRunOptions(dryRun)})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
 		return false, err
@@ -76,25 +92,26 @@ func DeploymentExist(ctx context.Context, clientset *kubernetes.Clientset, names
 	}
 }
 
-func DeploymentExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
+func DeploymentExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, isAddonManaged bool, err error) {
 	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
-		return false, err
+		return false, false, err
 	case errors.IsNotFound(err):
-		return false, nil
+		return false, false, nil
 	default:
+		isAddonManaged = IsEksAddonManaged(deployment.ObjectMeta)
 		if deployment.Labels == nil {
-			return false, nil
+			return false, isAddonManaged, nil
 		}
 
 		_, ok := deployment.Labels[amazonManagedLabelName]
-		return ok, nil
+		return ok, isAddonManaged, nil
 	}
 }
 
-func DeleteDeployment(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
-	err = clientset.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+func DeleteDeployment(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (exists bool, err error) {
+	err = clientset.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{DryRun: dryRunOptions(dryRun)})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
 		return false, err
@@ -117,26 +134,26 @@ func ServiceExist(ctx context.Context, clientset *kubernetes.Clientset, namespac
 	}
 }
 
-func ServiceExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, clusterIPs []string, err error) {
+func ServiceExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, isAddonManaged bool, clusterIPs []string, err error) {
 	deployment, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
-		return false, nil, err
+		return false, false, nil, err
 	case errors.IsNotFound(err):
-		return false, nil, nil
+		return false, false, nil, nil
 	default:
-
+		isAddonManaged = IsEksAddonManaged(deployment.ObjectMeta)
 		if deployment.Labels == nil {
-			return false, nil, nil
+			return false, isAddonManaged, nil, nil
 		}
 
 		_, ok := deployment.Labels[amazonManagedLabelName]
-		return ok, deployment.Spec.ClusterIPs, nil
+		return ok, isAddonManaged, deployment.Spec.ClusterIPs, nil
 	}
 }
 
-func DeleteService(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
-	err = clientset.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+func DeleteService(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (exists bool, err error) {
+	err = clientset.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{DryRun: dryRunOptions(dryRun)})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
 		return false, err
@@ -159,25 +176,26 @@ func ServiceAccountExist(ctx context.Context, clientset *kubernetes.Clientset, n
 	}
 }
 
-func ServiceAccountExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
+func ServiceAccountExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, isAddonManaged bool, err error) {
 	deployment, err := clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
-		return false, err
+		return false, false, err
 	case errors.IsNotFound(err):
-		return false, nil
+		return false, false, nil
 	default:
+		isAddonManaged = IsEksAddonManaged(deployment.ObjectMeta)
 		if deployment.Labels == nil {
-			return false, nil
+			return false, isAddonManaged, nil
 		}
 
 		_, ok := deployment.Labels[amazonManagedLabelName]
-		return ok, nil
+		return ok, isAddonManaged, nil
 	}
 }
 
-func DeleteServiceAccount(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
-	err = clientset.CoreV1().ServiceAccounts(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+func DeleteServiceAccount(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (exists bool, err error) {
+	err = clientset.CoreV1().ServiceAccounts(namespace).Delete(ctx, name, metav1.DeleteOptions{DryRun: dryRunOptions(dryRun)})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
 		return false, err
@@ -200,25 +218,26 @@ func ConfigMapExist(ctx context.Context, clientset *kubernetes.Clientset, namesp
 	}
 }
 
-func ConfigMapExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
+func ConfigMapExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, isAddonManaged bool, err error) {
 	deployment, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
-		return false, err
+		return false, false, err
 	case errors.IsNotFound(err):
-		return false, nil
+		return false, false, nil
 	default:
+		isAddonManaged = IsEksAddonManaged(deployment.ObjectMeta)
 		if deployment.Labels == nil {
-			return false, nil
+			return false, isAddonManaged, nil
 		}
 
 		_, ok := deployment.Labels[amazonManagedLabelName]
-		return ok, nil
+		return ok, isAddonManaged, nil
 	}
 }
 
-func DeleteConfigMap(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
-	err = clientset.CoreV1().ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+func DeleteConfigMap(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (exists bool, err error) {
+	err = clientset.CoreV1().ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{DryRun: dryRunOptions(dryRun)})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
 		return false, err
@@ -241,25 +260,26 @@ func PodDisruptionBudgetExist(ctx context.Context, clientset *kubernetes.Clients
 	}
 }
 
-func PodDisruptionBudgetExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
+func PodDisruptionBudgetExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, isAddonManaged bool, err error) {
 	deployment, err := clientset.PolicyV1().PodDisruptionBudgets(namespace).Get(ctx, name, metav1.GetOptions{})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
-		return false, err
+		return false, false, err
 	case errors.IsNotFound(err):
-		return false, nil
+		return false, false, nil
 	default:
+		isAddonManaged = IsEksAddonManaged(deployment.ObjectMeta)
 		if deployment.Labels == nil {
-			return false, nil
+			return false, isAddonManaged, nil
 		}
 
 		_, ok := deployment.Labels[amazonManagedLabelName]
-		return ok, nil
+		return ok, isAddonManaged, nil
 	}
 }
 
-func DeletePodDisruptionBudget(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (exists bool, err error) {
-	err = clientset.PolicyV1().PodDisruptionBudgets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+func DeletePodDisruptionBudget(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (exists bool, err error) {
+	err = clientset.PolicyV1().PodDisruptionBudgets(namespace).Delete(ctx, name, metav1.DeleteOptions{DryRun: dryRunOptions(dryRun)})
 	switch {
 	case err != nil && !errors.IsNotFound(err):
 		return false, err
@@ -270,237 +290,7 @@ func DeletePodDisruptionBudget(ctx context.Context, clientset *kubernetes.Client
 	}
 }
 
-func DeploymentImportedIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (helmReleaseNameAnnotationSet bool, helmReleaseNamespaceAnnotationSet bool, managedByLabelSet bool, amazonManagedLabelRemoved bool, err error) {
-	helmReleaseNameAnnotationSet = false
-	helmReleaseNamespaceAnnotationSet = false
-	managedByLabelSet = false
-	amazonManagedLabelRemoved = false
-
-	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return true, true, true, true, nil
-		} else {
-			return false, false, false, false, err
-		}
-	}
-
-	if deployment.Labels == nil {
-		deployment.Labels = map[string]string{}
-	}
-
-	if deployment.Annotations == nil {
-		deployment.Annotations = map[string]string{}
-	}
-
-	value, ok := deployment.Annotations[helmReleaseNameAnnotationName]
-	if ok && value == helmReleaseNameAnnotationValue {
-		helmReleaseNameAnnotationSet = true
-	}
-
-	value, ok = deployment.Annotations[helmReleaseNamespaceAnnotationName]
-	if ok && value == helmReleaseNamespaceAnnotationValue {
-		helmReleaseNamespaceAnnotationSet = true
-	}
-
-	value, ok = deployment.Labels[managedByLabelName]
-	if ok && value == managedByLabelValue {
-		managedByLabelSet = true
-	}
-
-	_, ok = deployment.Labels[amazonManagedLabelName]
-	if !ok {
-		amazonManagedLabelRemoved = true
-	}
-
-	return helmReleaseNameAnnotationSet, helmReleaseNamespaceAnnotationSet, managedByLabelSet, amazonManagedLabelRemoved, nil
-}
-
-func ServiceImportedIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (helmReleaseNameAnnotationSet bool, helmReleaseNamespaceAnnotationSet bool, managedByLabelSet bool, amazonManagedLabelRemoved bool, err error) {
-	helmReleaseNameAnnotationSet = false
-	helmReleaseNamespaceAnnotationSet = false
-	managedByLabelSet = false
-	amazonManagedLabelRemoved = false
-
-	service, err := clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return true, true, true, true, nil
-		} else {
-			return false, false, false, false, err
-		}
-	}
-
-	if service.Labels == nil {
-		service.Labels = map[string]string{}
-	}
-
-	if service.Annotations == nil {
-		service.Annotations = map[string]string{}
-	}
-
-	value, ok := service.Annotations[helmReleaseNameAnnotationName]
-	if ok && value == helmReleaseNameAnnotationValue {
-		helmReleaseNameAnnotationSet = true
-	}
-
-	value, ok = service.Annotations[helmReleaseNamespaceAnnotationName]
-	if ok && value == helmReleaseNamespaceAnnotationValue {
-		helmReleaseNamespaceAnnotationSet = true
-	}
-
-	value, ok = service.Labels[managedByLabelName]
-	if ok && value == managedByLabelValue {
-		managedByLabelSet = true
-	}
-
-	_, ok = service.Labels[amazonManagedLabelName]
-	if !ok {
-		amazonManagedLabelRemoved = true
-	}
-
-	return helmReleaseNameAnnotationSet, helmReleaseNamespaceAnnotationSet, managedByLabelSet, amazonManagedLabelRemoved, nil
-}
-
-func ServiceAccountImportedIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (helmReleaseNameAnnotationSet bool, helmReleaseNamespaceAnnotationSet bool, managedByLabelSet bool, amazonManagedLabelRemoved bool, err error) {
-	helmReleaseNameAnnotationSet = false
-	helmReleaseNamespaceAnnotationSet = false
-	managedByLabelSet = false
-	amazonManagedLabelRemoved = false
-
-	serviceAccount, err := clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return true, true, true, true, nil
-		} else {
-			return false, false, false, false, err
-		}
-	}
-
-	if serviceAccount.Labels == nil {
-		serviceAccount.Labels = map[string]string{}
-	}
-
-	if serviceAccount.Annotations == nil {
-		serviceAccount.Annotations = map[string]string{}
-	}
-
-	value, ok := serviceAccount.Annotations[helmReleaseNameAnnotationName]
-	if ok && value == helmReleaseNameAnnotationValue {
-		helmReleaseNameAnnotationSet = true
-	}
-
-	value, ok = serviceAccount.Annotations[helmReleaseNamespaceAnnotationName]
-	if ok && value == helmReleaseNamespaceAnnotationValue {
-		helmReleaseNamespaceAnnotationSet = true
-	}
-
-	value, ok = serviceAccount.Labels[managedByLabelName]
-	if ok && value == managedByLabelValue {
-		managedByLabelSet = true
-	}
-
-	_, ok = serviceAccount.Labels[amazonManagedLabelName]
-	if !ok {
-		amazonManagedLabelRemoved = true
-	}
-
-	return helmReleaseNameAnnotationSet, helmReleaseNamespaceAnnotationSet, managedByLabelSet, amazonManagedLabelRemoved, nil
-}
-
-func PodDisruptionBudgetImportedIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (helmReleaseNameAnnotationSet bool, helmReleaseNamespaceAnnotationSet bool, managedByLabelSet bool, amazonManagedLabelRemoved bool, err error) {
-	helmReleaseNameAnnotationSet = false
-	helmReleaseNamespaceAnnotationSet = false
-	managedByLabelSet = false
-	amazonManagedLabelRemoved = false
-
-	podDisruptionBudget, err := clientset.PolicyV1().PodDisruptionBudgets(namespace).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return true, true, true, true, nil
-		} else {
-			return false, false, false, false, err
-		}
-	}
-
-	if podDisruptionBudget.Labels == nil {
-		podDisruptionBudget.Labels = map[string]string{}
-	}
-
-	if podDisruptionBudget.Annotations == nil {
-		podDisruptionBudget.Annotations = map[string]string{}
-	}
-
-	value, ok := podDisruptionBudget.Annotations[helmReleaseNameAnnotationName]
-	if ok && value == helmReleaseNameAnnotationValue {
-		helmReleaseNameAnnotationSet = true
-	}
-
-	value, ok = podDisruptionBudget.Annotations[helmReleaseNamespaceAnnotationName]
-	if ok && value == helmReleaseNamespaceAnnotationValue {
-		helmReleaseNamespaceAnnotationSet = true
-	}
-
-	value, ok = podDisruptionBudget.Labels[managedByLabelName]
-	if ok && value == managedByLabelValue {
-		managedByLabelSet = true
-	}
-
-	_, ok = podDisruptionBudget.Labels[amazonManagedLabelName]
-	if !ok {
-		amazonManagedLabelRemoved = true
-	}
-
-	return helmReleaseNameAnnotationSet, helmReleaseNamespaceAnnotationSet, managedByLabelSet, amazonManagedLabelRemoved, nil
-}
-
-func ConfigMapImportedIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (helmReleaseNameAnnotationSet bool, helmReleaseNamespaceAnnotationSet bool, managedByLabelSet bool, amazonManagedLabelRemoved bool, err error) {
-	helmReleaseNameAnnotationSet = false
-	helmReleaseNamespaceAnnotationSet = false
-	managedByLabelSet = false
-	amazonManagedLabelRemoved = false
-
-	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return true, true, true, true, nil
-		} else {
-			return false, false, false, false, err
-		}
-	}
-
-	if configMap.Labels == nil {
-		configMap.Labels = map[string]string{}
-	}
-
-	if configMap.Annotations == nil {
-		configMap.Annotations = map[string]string{}
-	}
-
-	value, ok := configMap.Annotations[helmReleaseNameAnnotationName]
-	if ok && value == helmReleaseNameAnnotationValue {
-		helmReleaseNameAnnotationSet = true
-	}
-
-	value, ok = configMap.Annotations[helmReleaseNamespaceAnnotationName]
-	if ok && value == helmReleaseNamespaceAnnotationValue {
-		helmReleaseNamespaceAnnotationSet = true
-	}
-
-	value, ok = configMap.Labels[managedByLabelName]
-	if ok && value == managedByLabelValue {
-		managedByLabelSet = true
-	}
-
-	_, ok = configMap.Labels[amazonManagedLabelName]
-	if !ok {
-		amazonManagedLabelRemoved = true
-	}
-
-	return helmReleaseNameAnnotationSet, helmReleaseNamespaceAnnotationSet, managedByLabelSet, amazonManagedLabelRemoved, nil
-}
-
-func ImportDeploymentIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (err error) {
+func ImportDeploymentIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (diff string, err error) {
 	patchFunc := func(deployment *appsv1.Deployment) (bool, *appsv1.Deployment) {
 		updated := false
 		value := ""
@@ -589,13 +379,18 @@ func ImportDeploymentIntoHelm(ctx context.Context, clientset *kubernetes.Clients
 			return nil
 		}
 
-		_, err = clientset.AppsV1().Deployments(namespace).Update(ctx, updatedDeployment, metav1.UpdateOptions{})
+		result, err := clientset.AppsV1().Deployments(namespace).Update(ctx, updatedDeployment, metav1.UpdateOptions{DryRun: dryRunOptions(dryRun)})
+		if err != nil {
+			return err
+		}
+
+		diff, err = ObjectDiff("Deployment", deployment, result)
 		return err
 	})
-	return err
+	return diff, err
 }
 
-func ImportServiceIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (err error) {
+func ImportServiceIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (diff string, err error) {
 	patchFunc := func(service *corev1.Service) (bool, *corev1.Service) {
 		updated := false
 		value := ""
@@ -646,13 +441,18 @@ func ImportServiceIntoHelm(ctx context.Context, clientset *kubernetes.Clientset,
 			return nil
 		}
 
-		_, err = clientset.CoreV1().Services(namespace).Update(ctx, updatedService, metav1.UpdateOptions{})
+		result, err := clientset.CoreV1().Services(namespace).Update(ctx, updatedService, metav1.UpdateOptions{DryRun: dryRunOptions(dryRun)})
+		if err != nil {
+			return err
+		}
+
+		diff, err = ObjectDiff("Service", service, result)
 		return err
 	})
-	return err
+	return diff, err
 }
 
-func ImportServiceAccountIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (err error) {
+func ImportServiceAccountIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (diff string, err error) {
 	patchFunc := func(serviceAccount *corev1.ServiceAccount) (bool, *corev1.ServiceAccount) {
 		updated := false
 		value := ""
@@ -703,13 +503,18 @@ func ImportServiceAccountIntoHelm(ctx context.Context, clientset *kubernetes.Cli
 			return nil
 		}
 
-		_, err = clientset.CoreV1().ServiceAccounts(namespace).Update(ctx, updatedServiceAccount, metav1.UpdateOptions{})
+		result, err := clientset.CoreV1().ServiceAccounts(namespace).Update(ctx, updatedServiceAccount, metav1.UpdateOptions{DryRun: dryRunOptions(dryRun)})
+		if err != nil {
+			return err
+		}
+
+		diff, err = ObjectDiff("ServiceAccount", serviceAccount, result)
 		return err
 	})
-	return err
+	return diff, err
 }
 
-func ImportPodDisruptionBudgetIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (err error) {
+func ImportPodDisruptionBudgetIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (diff string, err error) {
 	patchFunc := func(serviceAccount *policyv1.PodDisruptionBudget) (bool, *policyv1.PodDisruptionBudget) {
 		updated := false
 		value := ""
@@ -760,13 +565,18 @@ func ImportPodDisruptionBudgetIntoHelm(ctx context.Context, clientset *kubernete
 			return nil
 		}
 
-		_, err = clientset.PolicyV1().PodDisruptionBudgets(namespace).Update(ctx, updatedPodDisruptionBudget, metav1.UpdateOptions{})
+		result, err := clientset.PolicyV1().PodDisruptionBudgets(namespace).Update(ctx, updatedPodDisruptionBudget, metav1.UpdateOptions{DryRun: dryRunOptions(dryRun)})
+		if err != nil {
+			return err
+		}
+
+		diff, err = ObjectDiff("PodDisruptionBudget", podDisruptionBudget, result)
 		return err
 	})
-	return err
+	return diff, err
 }
 
-func ImportConfigMapAccountIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string) (err error) {
+func ImportConfigMapAccountIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, dryRun bool) (diff string, err error) {
 	patchFunc := func(configMap *corev1.ConfigMap) (bool, *corev1.ConfigMap) {
 		updated := false
 		value := ""
@@ -817,8 +627,168 @@ func ImportConfigMapAccountIntoHelm(ctx context.Context, clientset *kubernetes.C
 			return nil
 		}
 
-		_, err = clientset.CoreV1().ConfigMaps(namespace).Update(ctx, updatedConfigMap, metav1.UpdateOptions{})
+		result, err := clientset.CoreV1().ConfigMaps(namespace).Update(ctx, updatedConfigMap, metav1.UpdateOptions{DryRun: dryRunOptions(dryRun)})
+		if err != nil {
+			return err
+		}
+
+		diff, err = ObjectDiff("ConfigMap", configMap, result)
 		return err
 	})
-	return err
+	return diff, err
+}
+
+// restoreObjectMeta removes the Helm release metadata and puts the EKS component label back. Objects owned by another
+// Helm release are left alone.
+func restoreObjectMeta(objectMeta *metav1.ObjectMeta, component string) (updated bool) {
+	releaseName, ok := objectMeta.Annotations[helmReleaseNameAnnotationName]
+	if ok && releaseName != helmReleaseNameAnnotationValue {
+		return false
+	}
+
+	if ok {
+		updated = true
+		delete(objectMeta.Annotations, helmReleaseNameAnnotationName)
+	}
+
+	_, ok = objectMeta.Annotations[helmReleaseNamespaceAnnotationName]
+	if ok {
+		updated = true
+		delete(objectMeta.Annotations, helmReleaseNamespaceAnnotationName)
+	}
+
+	value, ok := objectMeta.Labels[managedByLabelName]
+	if ok && value == managedByLabelValue {
+		updated = true
+		delete(objectMeta.Labels, managedByLabelName)
+	}
+
+	if objectMeta.Labels == nil {
+		objectMeta.Labels = make(map[string]string)
+	}
+
+	value, ok = objectMeta.Labels[amazonManagedLabelName]
+	if !ok || value != component {
+		updated = true
+		objectMeta.Labels[amazonManagedLabelName] = component
+	}
+
+	return updated
+}
+
+// RestoreFromHelm undoes an adoption into Helm, so that the object looks like the one EKS installed.
+func RestoreFromHelm(ctx context.Context, clientset *kubernetes.Clientset, object ClusterObject, component string, dryRun bool) (diff string, err error) {
+	updateOptions := metav1.UpdateOptions{DryRun: dryRunOptions(dryRun)}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		switch object.Kind {
+		case "Deployment":
+			deployment, err := clientset.AppsV1().Deployments(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			updatedDeployment := deployment.DeepCopy()
+			if !restoreObjectMeta(&updatedDeployment.ObjectMeta, component) {
+				return nil
+			}
+			if updatedDeployment.Spec.Template.ObjectMeta.Labels == nil {
+				updatedDeployment.Spec.Template.ObjectMeta.Labels = make(map[string]string)
+			}
+			updatedDeployment.Spec.Template.ObjectMeta.Labels[amazonManagedLabelName] = component
+
+			result, err := clientset.AppsV1().Deployments(object.Namespace).Update(ctx, updatedDeployment, updateOptions)
+			if err != nil {
+				return err
+			}
+			diff, err = ObjectDiff(object.Kind, deployment, result)
+			return err
+
+		case "Service":
+			service, err := clientset.CoreV1().Services(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			updatedService := service.DeepCopy()
+			if !restoreObjectMeta(&updatedService.ObjectMeta, component) {
+				return nil
+			}
+
+			result, err := clientset.CoreV1().Services(object.Namespace).Update(ctx, updatedService, updateOptions)
+			if err != nil {
+				return err
+			}
+			diff, err = ObjectDiff(object.Kind, service, result)
+			return err
+
+		case "ServiceAccount":
+			serviceAccount, err := clientset.CoreV1().ServiceAccounts(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			updatedServiceAccount := serviceAccount.DeepCopy()
+			if !restoreObjectMeta(&updatedServiceAccount.ObjectMeta, component) {
+				return nil
+			}
+
+			result, err := clientset.CoreV1().ServiceAccounts(object.Namespace).Update(ctx, updatedServiceAccount, updateOptions)
+			if err != nil {
+				return err
+			}
+			diff, err = ObjectDiff(object.Kind, serviceAccount, result)
+			return err
+
+		case "ConfigMap":
+			configMap, err := clientset.CoreV1().ConfigMaps(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			updatedConfigMap := configMap.DeepCopy()
+			if !restoreObjectMeta(&updatedConfigMap.ObjectMeta, component) {
+				return nil
+			}
+
+			result, err := clientset.CoreV1().ConfigMaps(object.Namespace).Update(ctx, updatedConfigMap, updateOptions)
+			if err != nil {
+				return err
+			}
+			diff, err = ObjectDiff(object.Kind, configMap, result)
+			return err
+
+		case "PodDisruptionBudget":
+			podDisruptionBudget, err := clientset.PolicyV1().PodDisruptionBudgets(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			updatedPodDisruptionBudget := podDisruptionBudget.DeepCopy()
+			if !restoreObjectMeta(&updatedPodDisruptionBudget.ObjectMeta, component) {
+				return nil
+			}
+
+			result, err := clientset.PolicyV1().PodDisruptionBudgets(object.Namespace).Update(ctx, updatedPodDisruptionBudget, updateOptions)
+			if err != nil {
+				return err
+			}
+			diff, err = ObjectDiff(object.Kind, podDisruptionBudget, result)
+			return err
+
+		default:
+			return fmt.Errorf("unsupported kind %q", object.Kind)
+		}
+	})
+	return diff, err
 }
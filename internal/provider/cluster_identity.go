@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const clusterIdentityNamespace string = "kube-system"
+const irsaRoleArnAnnotationName string = "eks.amazonaws.com/role-arn"
+const awsAuthConfigMapName string = "aws-auth"
+
+var iamAccountRegexp = regexp.MustCompile(`arn:aws[a-zA-Z-]*:iam::([0-9]{12}):`)
+
+// ClusterIdentity identifies a cluster independently of how it is reached. The kube-system namespace UID changes
+// whenever the cluster is rebuilt, the ARN is empty when it could not be determined.
+type ClusterIdentity struct {
+	Uid string
+	Arn string
+}
+
+// GetClusterIdentity reads the identity of the cluster the clientset points at.
+func GetClusterIdentity(ctx context.Context, clientset *kubernetes.Clientset) (identity ClusterIdentity, err error) {
+	namespace, err := clientset.CoreV1().Namespaces().Get(ctx, clusterIdentityNamespace, metav1.GetOptions{})
+	if err != nil {
+		return identity, readError("get", fmt.Sprintf("Namespace %s", clusterIdentityNamespace), err)
+	}
+	identity.Uid = string(namespace.UID)
+
+	identity.Arn, err = DiscoverEksClusterArn(ctx, clientset)
+	if err != nil {
+		return identity, err
+	}
+
+	return identity, nil
+}
+
+// Pin returns the identity to pin in place of pinned. A live ARN that can't be determined, e.g. once aws-node is
+// removed, keeps the pinned ARN of the same cluster rather than clearing it.
+func (i ClusterIdentity) Pin(pinned ClusterIdentity) ClusterIdentity {
+	if i.Arn == "" && (pinned.Uid == "" || i.Uid == pinned.Uid) {
+		i.Arn = pinned.Arn
+	}
+	return i
+}
+
+// Mismatches compares a live identity with the pinned one. The ARN is only compared when both are known.
+func (i ClusterIdentity) Mismatches(pinned ClusterIdentity) (mismatches []string) {
+	if pinned.Uid != "" && i.Uid != pinned.Uid {
+		mismatches = append(mismatches, fmt.Sprintf("%s namespace UID is %q but %q was pinned", clusterIdentityNamespace, i.Uid, pinned.Uid))
+	}
+	if pinned.Arn != "" && i.Arn != "" && i.Arn != pinned.Arn {
+		mismatches = append(mismatches, fmt.Sprintf("cluster ARN is %q but %q was pinned", i.Arn, pinned.Arn))
+	}
+	return mismatches
+}
+
+// DiscoverEksClusterArn builds the ARN of the cluster from its name, region and account. It runs on every read, so
+// unlike DiscoverEksCluster it only makes lookups of single objects. It returns an empty ARN when any part can't be
+// determined, e.g. once aws-node is removed.
+func DiscoverEksClusterArn(ctx context.Context, clientset *kubernetes.Clientset) (arn string, err error) {
+	var info EksClusterInfo
+	info.ClusterName, _, err = discoverAwsNodeClusterName(ctx, clientset)
+	if err != nil || info.ClusterName == "" {
+		return "", err
+	}
+	info.Region = discoverEksRegion(ctx, clientset)
+	info.Account, err = discoverEksAccount(ctx, clientset)
+	if err != nil {
+		return "", err
+	}
+	return info.Arn(), nil
+}
+
+// awsPartition returns the partition a region belongs to.
+func awsPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	default:
+		return "aws"
+	}
+}
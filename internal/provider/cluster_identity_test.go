@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClusterIdentityPin(t *testing.T) {
+	const arn = "arn:aws:eks:eu-west-1:123456789012:cluster/prod"
+
+	tests := []struct {
+		name   string
+		live   ClusterIdentity
+		pinned ClusterIdentity
+		want   ClusterIdentity
+	}{
+		{name: "first pin", live: ClusterIdentity{Uid: "a", Arn: arn}, want: ClusterIdentity{Uid: "a", Arn: arn}},
+		{name: "unchanged", live: ClusterIdentity{Uid: "a", Arn: arn}, pinned: ClusterIdentity{Uid: "a", Arn: arn}, want: ClusterIdentity{Uid: "a", Arn: arn}},
+		{name: "aws-node removed", live: ClusterIdentity{Uid: "a"}, pinned: ClusterIdentity{Uid: "a", Arn: arn}, want: ClusterIdentity{Uid: "a", Arn: arn}},
+		{name: "arn found later", live: ClusterIdentity{Uid: "a", Arn: arn}, pinned: ClusterIdentity{Uid: "a"}, want: ClusterIdentity{Uid: "a", Arn: arn}},
+		{name: "replaced cluster", live: ClusterIdentity{Uid: "b"}, pinned: ClusterIdentity{Uid: "a", Arn: arn}, want: ClusterIdentity{Uid: "b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.live.Pin(test.pinned); got != test.want {
+				t.Errorf("Pin() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClusterIdentityMismatches(t *testing.T) {
+	const arn = "arn:aws:eks:eu-west-1:123456789012:cluster/prod"
+	const otherArn = "arn:aws:eks:eu-west-1:210987654321:cluster/prod"
+
+	tests := []struct {
+		name   string
+		live   ClusterIdentity
+		pinned ClusterIdentity
+		want   []string
+	}{
+		{name: "nothing pinned", live: ClusterIdentity{Uid: "a", Arn: arn}},
+		{name: "same", live: ClusterIdentity{Uid: "a", Arn: arn}, pinned: ClusterIdentity{Uid: "a", Arn: arn}},
+		{name: "live arn unknown", live: ClusterIdentity{Uid: "a"}, pinned: ClusterIdentity{Uid: "a", Arn: arn}},
+		{
+			name:   "other uid",
+			live:   ClusterIdentity{Uid: "b", Arn: arn},
+			pinned: ClusterIdentity{Uid: "a", Arn: arn},
+			want:   []string{`kube-system namespace UID is "b" but "a" was pinned`},
+		},
+		{
+			name:   "other arn",
+			live:   ClusterIdentity{Uid: "a", Arn: otherArn},
+			pinned: ClusterIdentity{Uid: "a", Arn: arn},
+			want:   []string{`cluster ARN is "arn:aws:eks:eu-west-1:210987654321:cluster/prod" but "arn:aws:eks:eu-west-1:123456789012:cluster/prod" was pinned`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.live.Mismatches(test.pinned); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Mismatches()\n got: %q\nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNodeRoleAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapRoles string
+		want     string
+	}{
+		{name: "empty"},
+		{name: "invalid", mapRoles: "rolearn: ["},
+		{
+			name: "node role",
+			mapRoles: `
+- rolearn: arn:aws:iam::123456789012:role/nodes
+  username: system:node:{{EC2PrivateDNSName}}
+  groups:
+    - system:bootstrappers
+    - system:nodes
+`,
+			want: "123456789012",
+		},
+		{
+			// An administrator role of another account mapped first doesn't say where the cluster is
+			name: "cross account role first",
+			mapRoles: `
+- rolearn: arn:aws:iam::210987654321:role/admin
+  username: admin
+  groups:
+    - system:masters
+- rolearn: arn:aws:iam::123456789012:role/nodes
+  username: system:node:{{EC2PrivateDNSName}}
+  groups:
+    - system:bootstrappers
+    - system:nodes
+`,
+			want: "123456789012",
+		},
+		{
+			name: "no node role",
+			mapRoles: `
+- rolearn: arn:aws:iam::210987654321:role/admin
+  username: admin
+  groups:
+    - system:masters
+`,
+		},
+		{
+			name: "node roles in different accounts",
+			mapRoles: `
+- rolearn: arn:aws:iam::123456789012:role/nodes
+  groups:
+    - system:nodes
+- rolearn: arn:aws:iam::210987654321:role/nodes
+  groups:
+    - system:nodes
+`,
+		},
+		{
+			name: "node roles in the same account",
+			mapRoles: `
+- rolearn: arn:aws:iam::123456789012:role/nodes-a
+  groups:
+    - system:nodes
+- rolearn: arn:aws-cn:iam::123456789012:role/nodes-b
+  groups:
+    - system:nodes
+`,
+			want: "123456789012",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := nodeRoleAccount(test.mapRoles); got != test.want {
+				t.Errorf("nodeRoleAccount() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClusterObject identifies an object the job removes or adopts.
+type ClusterObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (o ClusterObject) String() string {
+	return fmt.Sprintf("%s %s/%s", o.Kind, o.Namespace, o.Name)
+}
+
+// GetObjectMeta returns the metadata of one of the supported kinds, or nil when the object doesn't exist.
+func GetObjectMeta(ctx context.Context, clientset *kubernetes.Clientset, object ClusterObject) (objectMeta *metav1.ObjectMeta, err error) {
+	switch object.Kind {
+	case "DaemonSet":
+		daemonset, err := clientset.AppsV1().DaemonSets(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+		if err == nil {
+			objectMeta = &daemonset.ObjectMeta
+		}
+		return notFoundIsNil(objectMeta, err)
+	case "Deployment":
+		deployment, err := clientset.AppsV1().Deployments(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+		if err == nil {
+			objectMeta = &deployment.ObjectMeta
+		}
+		return notFoundIsNil(objectMeta, err)
+	case "Service":
+		service, err := clientset.CoreV1().Services(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+		if err == nil {
+			objectMeta = &service.ObjectMeta
+		}
+		return notFoundIsNil(objectMeta, err)
+	case "ServiceAccount":
+		serviceAccount, err := clientset.CoreV1().ServiceAccounts(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+		if err == nil {
+			objectMeta = &serviceAccount.ObjectMeta
+		}
+		return notFoundIsNil(objectMeta, err)
+	case "ConfigMap":
+		configMap, err := clientset.CoreV1().ConfigMaps(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+		if err == nil {
+			objectMeta = &configMap.ObjectMeta
+		}
+		return notFoundIsNil(objectMeta, err)
+	case "PodDisruptionBudget":
+		podDisruptionBudget, err := clientset.PolicyV1().PodDisruptionBudgets(object.Namespace).Get(ctx, object.Name, metav1.GetOptions{})
+		if err == nil {
+			objectMeta = &podDisruptionBudget.ObjectMeta
+		}
+		return notFoundIsNil(objectMeta, err)
+	default:
+		return nil, fmt.Errorf("unsupported kind %q", object.Kind)
+	}
+}
+
+func notFoundIsNil(objectMeta *metav1.ObjectMeta, err error) (*metav1.ObjectMeta, error) {
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return nil, err
+	case errors.IsNotFound(err):
+		return nil, nil
+	default:
+		return objectMeta, nil
+	}
+}
+
+// APIVersion returns the group version of the kind.
+func (o ClusterObject) APIVersion() string {
+	switch o.Kind {
+	case "DaemonSet", "Deployment":
+		return "apps/v1"
+	case "PodDisruptionBudget":
+		return "policy/v1"
+	default:
+		return "v1"
+	}
+}
+
+// ObjectExistsAndIsAwsOne returns true when the object exists and still carries the EKS component label, and whether
+// an EKS managed add-on owns it.
+func ObjectExistsAndIsAwsOne(ctx context.Context, clientset *kubernetes.Clientset, object ClusterObject) (exists bool, isAddonManaged bool, err error) {
+	switch object.Kind {
+	case "DaemonSet":
+		return DaemonsetExistsAndIsAwsOne(ctx, clientset, object.Namespace, object.Name)
+	case "Deployment":
+		return DeploymentExistsAndIsAwsOne(ctx, clientset, object.Namespace, object.Name)
+	case "Service":
+		exists, isAddonManaged, _, err = ServiceExistsAndIsAwsOne(ctx, clientset, object.Namespace, object.Name)
+		return exists, isAddonManaged, err
+	case "ServiceAccount":
+		return ServiceAccountExistsAndIsAwsOne(ctx, clientset, object.Namespace, object.Name)
+	case "ConfigMap":
+		return ConfigMapExistsAndIsAwsOne(ctx, clientset, object.Namespace, object.Name)
+	case "PodDisruptionBudget":
+		return PodDisruptionBudgetExistsAndIsAwsOne(ctx, clientset, object.Namespace, object.Name)
+	default:
+		return false, false, fmt.Errorf("unsupported kind %q", object.Kind)
+	}
+}
+
+// DeleteObject deletes an object of one of the supported kinds. It returns false when the object didn't exist.
+func DeleteObject(ctx context.Context, clientset *kubernetes.Clientset, object ClusterObject, dryRun bool) (deleted bool, err error) {
+	switch object.Kind {
+	case "DaemonSet":
+		return DeleteDaemonset(ctx, clientset, object.Namespace, object.Name, dryRun)
+	case "Deployment":
+		return DeleteDeployment(ctx, clientset, object.Namespace, object.Name, dryRun)
+	case "Service":
+		return DeleteService(ctx, clientset, object.Namespace, object.Name, dryRun)
+	case "ServiceAccount":
+		return DeleteServiceAccount(ctx, clientset, object.Namespace, object.Name, dryRun)
+	case "ConfigMap":
+		return DeleteConfigMap(ctx, clientset, object.Namespace, object.Name, dryRun)
+	case "PodDisruptionBudget":
+		return DeletePodDisruptionBudget(ctx, clientset, object.Namespace, object.Name, dryRun)
+	default:
+		return false, fmt.Errorf("unsupported kind %q", object.Kind)
+	}
+}
+
+// ImportObjectIntoHelm adds the Helm metadata to an object of one of the supported kinds, returning the diff of the
+// change. The diff is empty when there was nothing to change.
+func ImportObjectIntoHelm(ctx context.Context, clientset *kubernetes.Clientset, object ClusterObject, dryRun bool) (diff string, err error) {
+	switch object.Kind {
+	case "Deployment":
+		return ImportDeploymentIntoHelm(ctx, clientset, object.Namespace, object.Name, dryRun)
+	case "Service":
+		return ImportServiceIntoHelm(ctx, clientset, object.Namespace, object.Name, dryRun)
+	case "ServiceAccount":
+		return ImportServiceAccountIntoHelm(ctx, clientset, object.Namespace, object.Name, dryRun)
+	case "ConfigMap":
+		return ImportConfigMapAccountIntoHelm(ctx, clientset, object.Namespace, object.Name, dryRun)
+	case "PodDisruptionBudget":
+		return ImportPodDisruptionBudgetIntoHelm(ctx, clientset, object.Namespace, object.Name, dryRun)
+	default:
+		return "", fmt.Errorf("unsupported kind %q", object.Kind)
+	}
+}
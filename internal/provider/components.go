@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// ComponentStatus is the state of one object the job removes or adopts.
+type ComponentStatus struct {
+	Object       ClusterObject
+	Exists       bool
+	IsEksManaged bool
+	// IsAddonManaged tells whether an EKS managed add-on owns fields of the object
+	IsAddonManaged bool
+	// Adoption tells for each Helm metadata key whether it has the value Helm expects (for the EKS component label,
+	// whether it has been removed). It is empty when the object doesn't exist.
+	Adoption map[string]bool
+}
+
+// Adopted returns true when the object is ready to be managed by Helm, which includes not existing at all.
+func (s ComponentStatus) Adopted() bool {
+	for _, adopted := range s.Adoption {
+		if !adopted {
+			return false
+		}
+	}
+	return true
+}
+
+// AdoptedKey returns whether a metadata key has the value Helm expects. An object that doesn't exist counts as
+// adopted, as the Helm chart can create it.
+func (s ComponentStatus) AdoptedKey(key string) bool {
+	if !s.Exists {
+		return true
+	}
+	return s.Adoption[key]
+}
+
+// CheckComponent reads the object with the client for its kind and reports its status.
+func CheckComponent(ctx context.Context, clientset *kubernetes.Clientset, object ClusterObject) (status ComponentStatus, err error) {
+	status = ComponentStatus{
+		Object:   object,
+		Adoption: map[string]bool{},
+	}
+
+	objectMeta, err := GetObjectMeta(ctx, clientset, object)
+	if err != nil {
+		return status, err
+	}
+	if objectMeta == nil {
+		return status, nil
+	}
+
+	status.Exists = true
+	_, status.IsEksManaged = objectMeta.Labels[amazonManagedLabelName]
+	status.IsAddonManaged = IsEksAddonManaged(*objectMeta)
+
+	status.Adoption[helmReleaseNameAnnotationName] = objectMeta.Annotations[helmReleaseNameAnnotationName] == helmReleaseNameAnnotationValue
+	status.Adoption[helmReleaseNamespaceAnnotationName] = objectMeta.Annotations[helmReleaseNamespaceAnnotationName] == helmReleaseNamespaceAnnotationValue
+	status.Adoption[managedByLabelName] = objectMeta.Labels[managedByLabelName] == managedByLabelValue
+	status.Adoption[amazonManagedLabelName] = !status.IsEksManaged
+
+	return status, nil
+}
+
+// CheckComponents checks every object, keyed by object. It keeps going when an object can't be read, so that every
+// failure is returned. Objects that couldn't be read are missing from statuses.
+func CheckComponents(ctx context.Context, clientset *kubernetes.Clientset, objects []ClusterObject) (statuses map[ClusterObject]ComponentStatus, errs ReadErrors) {
+	statuses = map[ClusterObject]ComponentStatus{}
+	for _, object := range objects {
+		status, err := CheckComponent(ctx, clientset, object)
+		if errs.Add("get", object.String(), err) {
+			continue
+		}
+		statuses[object] = status
+	}
+	return statuses, errs
+}
+
+const componentActionKeep string = "keep"
+const componentActionRemove string = "remove"
+const componentActionAdopt string = "adopt"
+const componentActionRestore string = "restore"
+
+// Restored returns true when the object looks like the one EKS installed, which includes not existing at all. Objects
+// owned by another Helm release count as restored, as restoring leaves them alone.
+func (s ComponentStatus) Restored() bool {
+	if !s.Exists {
+		return true
+	}
+	return s.IsEksManaged && !s.Adoption[helmReleaseNameAnnotationName] && !s.Adoption[helmReleaseNamespaceAnnotationName]
+}
+
+// EksComponentLabelValue returns the value EKS gives the eks.amazonaws.com/component label of the object.
+func EksComponentLabelValue(object ClusterObject) string {
+	if object.Kind == "Service" && object.Name == "kube-dns" {
+		return "kube-dns"
+	}
+	return object.Name
+}
+
+// CorednsAdopted returns true when every CoreDNS object is ready to be managed by Helm. Under the skip conflict policy
+// the objects that had a conflicting Helm owner are meant to stay with their owner, so they don't count.
+func CorednsAdopted(statuses map[ClusterObject]ComponentStatus, conflictPolicy string, helmConflicts []string) bool {
+	skipped := map[ClusterObject]bool{}
+	if conflictPolicy == conflictPolicySkip {
+		for _, conflict := range helmConflicts {
+			for _, object := range CorednsHelmObjects {
+				if strings.HasPrefix(conflict, object.String()+": ") {
+					skipped[object] = true
+				}
+			}
+		}
+	}
+
+	for _, object := range CorednsHelmObjects {
+		if !skipped[object] && !statuses[object].Adopted() {
+			return false
+		}
+	}
+	return true
+}
+
+// CorednsRestored returns true when every CoreDNS object looks like the one EKS installed.
+func CorednsRestored(statuses map[ClusterObject]ComponentStatus) bool {
+	for _, object := range CorednsHelmObjects {
+		if !statuses[object].Restored() {
+			return false
+		}
+	}
+	return true
+}
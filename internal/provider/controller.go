@@ -0,0 +1,590 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const controllerNamespace string = "kube-system"
+const defaultControllerLeaseName string = "cleaneks-controller"
+
+// Component names, which are also the keys of the controller work queue.
+const controllerComponentAwsCni string = "aws_cni"
+const controllerComponentKubeProxy string = "kube_proxy"
+const controllerComponentCoredns string = "coredns"
+
+const controllerLeaseDuration = 15 * time.Second
+const controllerRenewDeadline = 10 * time.Second
+const controllerRetryPeriod = 2 * time.Second
+const controllerShutdownTimeout = 5 * time.Second
+
+// ControllerOptions are the settings of the controller subcommand.
+type ControllerOptions struct {
+	// AwsCni, KubeProxy and Coredns are the component actions, empty means keep
+	AwsCni    string
+	KubeProxy string
+	Coredns   string
+
+	DryRun bool
+
+	// Force, AcknowledgedAwsCniDependencies, VerifyEks and the expected cluster are the guards of a cleaneks_job, which
+	// are checked before every removal
+	Force                          bool
+	AcknowledgedAwsCniDependencies []string
+	VerifyEks                      bool
+	ExpectedClusterName            string
+	ExpectedClusterArn             string
+
+	// ManageEksAddons releases a component from its EKS managed add-on before the objects the add-on owns are changed,
+	// they are refused otherwise as the add-on puts them back. ClusterName, Region and EksEndpoint locate the add-ons.
+	ManageEksAddons bool
+	ClusterName     string
+	Region          string
+	EksEndpoint     string
+
+	LeaseName      string
+	LeaseNamespace string
+
+	// MetricsAddress serves /metrics and HealthAddress serves /healthz and /readyz, empty disables them
+	MetricsAddress string
+	HealthAddress  string
+
+	// ResyncPeriod is how often every watched object is looked at again, even without a change
+	ResyncPeriod time.Duration
+}
+
+// Validate checks the actions are ones the controller can enforce. Restore is left out, as it is a one off hand over
+// rather than a state to keep.
+func (o ControllerOptions) Validate() error {
+	for _, action := range []struct {
+		flag    string
+		value   string
+		allowed []string
+	}{
+		{"aws-cni", o.AwsCni, []string{"", componentActionKeep, componentActionRemove}},
+		{"kube-proxy", o.KubeProxy, []string{"", componentActionKeep, componentActionRemove}},
+		{"coredns", o.Coredns, []string{"", componentActionKeep, componentActionRemove, componentActionAdopt}},
+	} {
+		valid := false
+		for _, allowed := range action.allowed {
+			valid = valid || action.value == allowed
+		}
+		if !valid {
+			return fmt.Errorf("%s: unsupported action %q", action.flag, action.value)
+		}
+	}
+	if o.AwsCni != componentActionRemove && o.KubeProxy != componentActionRemove && o.Coredns != componentActionRemove && o.Coredns != componentActionAdopt {
+		return errors.New("nothing to enforce, set aws-cni, kube-proxy or coredns")
+	}
+	if o.ManageEksAddons && o.eksClusterName() == "" {
+		return errors.New("manage-eks-addons needs the name of the EKS cluster, set cluster-name or expected-cluster-name")
+	}
+	return nil
+}
+
+// eksClusterName returns the name of the cluster the EKS managed add-ons are looked up in.
+func (o ControllerOptions) eksClusterName() string {
+	if o.ClusterName != "" {
+		return o.ClusterName
+	}
+	return o.ExpectedClusterName
+}
+
+// components returns the objects of each component the controller enforces, keyed by component.
+func (o ControllerOptions) components() map[string][]ClusterObject {
+	components := map[string][]ClusterObject{}
+	if o.AwsCni == componentActionRemove {
+		components[controllerComponentAwsCni] = AwsCniObjects
+	}
+	if o.KubeProxy == componentActionRemove {
+		components[controllerComponentKubeProxy] = KubeProxyObjects
+	}
+	if o.Coredns == componentActionRemove || o.Coredns == componentActionAdopt {
+		components[controllerComponentCoredns] = CorednsHelmObjects
+	}
+	return components
+}
+
+// controllerEksAddons are the EKS managed add-ons of the components.
+var controllerEksAddons = map[string]string{
+	controllerComponentAwsCni:    eksAddonAwsCni,
+	controllerComponentKubeProxy: eksAddonKubeProxy,
+	controllerComponentCoredns:   eksAddonCoredns,
+}
+
+func (o ControllerOptions) action(component string) string {
+	switch component {
+	case controllerComponentAwsCni:
+		return o.AwsCni
+	case controllerComponentKubeProxy:
+		return o.KubeProxy
+	default:
+		return o.Coredns
+	}
+}
+
+type controllerMetrics struct {
+	registry   *prometheus.Registry
+	reconciles *prometheus.CounterVec
+	changes    *prometheus.CounterVec
+	addonOwned *prometheus.GaugeVec
+	refused    *prometheus.CounterVec
+	leader     prometheus.Gauge
+}
+
+func newControllerMetrics() *controllerMetrics {
+	m := &controllerMetrics{
+		registry: prometheus.NewRegistry(),
+		reconciles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cleaneks_controller_reconciles_total",
+			Help: "Reconciles of a component, by result.",
+		}, []string{"component", "result"}),
+		changes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cleaneks_controller_changes_total",
+			Help: "Objects that reappeared and were removed or adopted again.",
+		}, []string{"component", "kind", "action"}),
+		addonOwned: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cleaneks_controller_addon_owned_objects",
+			Help: "Objects of a component owned by an EKS managed add-on, which undoes the changes of the controller.",
+		}, []string{"component"}),
+		refused: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cleaneks_controller_refused_removals_total",
+			Help: "Removals and adoptions refused because a guard failed, by guard.",
+		}, []string{"component", "guard"}),
+		leader: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "cleaneks_controller_leader",
+			Help: "1 while this replica holds the leader lease.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.reconciles,
+		m.changes,
+		m.addonOwned,
+		m.refused,
+		m.leader,
+	)
+	return m
+}
+
+// Controller removes or re-adopts the EKS components that reappear, e.g. after an EKS platform upgrade, as soon as they
+// do. Only the replica that holds the leader lease acts.
+type Controller struct {
+	clientset *kubernetes.Clientset
+	version   string
+	options   ControllerOptions
+	metrics   *controllerMetrics
+	// eksAddons is nil unless the components are released from their EKS managed add-ons
+	eksAddons *EksAddons
+
+	// watched maps each object to its component
+	watched map[ClusterObject]string
+	// clusterArn is the ARN of the cluster once it was verified, which stands in for what can't be discovered after
+	// aws-node is removed
+	clusterArn string
+	queue      workqueue.RateLimitingInterface
+	synced     atomic.Bool
+	leading    atomic.Bool
+}
+
+func NewController(clientset *kubernetes.Clientset, version string, options ControllerOptions) *Controller {
+	c := &Controller{
+		clientset: clientset,
+		version:   version,
+		options:   options,
+		metrics:   newControllerMetrics(),
+		watched:   map[ClusterObject]string{},
+		queue:     workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), workqueue.RateLimitingQueueConfig{Name: "cleaneks"}),
+	}
+	for component, objects := range options.components() {
+		for _, object := range objects {
+			c.watched[object] = component
+		}
+	}
+	if options.ManageEksAddons {
+		c.eksAddons = NewEksAddons(options.eksClusterName(), options.Region, options.EksEndpoint)
+	}
+	return c
+}
+
+// RunController runs the controller in the cluster of config until ctx is done or the leader lease is lost.
+func RunController(ctx context.Context, config *rest.Config, version string, options ControllerOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	if options.LeaseName == "" {
+		options.LeaseName = defaultControllerLeaseName
+	}
+	if options.LeaseNamespace == "" {
+		options.LeaseNamespace = controllerNamespace
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+	return NewController(clientset, version, options).Run(ctx)
+}
+
+// Run serves the metrics and health endpoints and takes part in leader election, reconciling while it leads.
+func (c *Controller) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer c.queue.ShutDown()
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{Namespace: c.options.LeaseNamespace, Name: c.options.LeaseName},
+		Client:    c.clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: LeaseLockIdentity(c.version),
+		},
+	}
+	leaderHealth := leaderelection.NewLeaderHealthzAdaptor(controllerRenewDeadline)
+
+	servers := []*http.Server{}
+	if c.options.MetricsAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(c.metrics.registry, promhttp.HandlerOpts{}))
+		servers = append(servers, &http.Server{Addr: c.options.MetricsAddress, Handler: mux, ReadHeaderTimeout: 10 * time.Second})
+	}
+	if c.options.HealthAddress != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			// Fails when this replica leads but has stopped renewing the lease
+			if err := leaderHealth.Check(r); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		})
+		mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+			if c.leading.Load() && !c.synced.Load() {
+				http.Error(w, "informer caches are not synced yet", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		})
+		servers = append(servers, &http.Server{Addr: c.options.HealthAddress, Handler: mux, ReadHeaderTimeout: 10 * time.Second})
+	}
+
+	// The first error stops the controller, the channel is large enough that nothing ever blocks on it
+	runErrors := make(chan error, len(servers)+2)
+	for _, server := range servers {
+		go func(server *http.Server) {
+			klog.InfoS("Serving", "address", server.Addr)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				runErrors <- fmt.Errorf("serving %s: %w", server.Addr, err)
+				cancel()
+			}
+		}(server)
+	}
+	defer func() {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.WithoutCancel(ctx), controllerShutdownTimeout)
+		defer shutdownCancel()
+		for _, server := range servers {
+			_ = server.Shutdown(shutdownCtx)
+		}
+	}()
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   controllerLeaseDuration,
+		RenewDeadline:   controllerRenewDeadline,
+		RetryPeriod:     controllerRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            c.options.LeaseName,
+		WatchDog:        leaderHealth,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				c.leading.Store(true)
+				c.metrics.leader.Set(1)
+				if err := c.lead(ctx); err != nil {
+					runErrors <- err
+				}
+				cancel()
+			},
+			OnStoppedLeading: func() {
+				c.leading.Store(false)
+				c.metrics.leader.Set(0)
+				if ctx.Err() == nil {
+					runErrors <- errors.New("lost the leader lease")
+				}
+				cancel()
+			},
+			OnNewLeader: func(identity string) {
+				klog.InfoS("Leader elected", "identity", identity)
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("setting up leader election: %w", err)
+	}
+	elector.Run(ctx)
+
+	select {
+	case err := <-runErrors:
+		return err
+	default:
+		return nil
+	}
+}
+
+// lead watches the objects of the components and reconciles them until ctx is done.
+func (c *Controller) lead(ctx context.Context) error {
+	klog.InfoS("Started leading", "awsCni", c.options.AwsCni, "kubeProxy", c.options.KubeProxy, "coredns", c.options.Coredns, "dryRun", c.options.DryRun)
+
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, c.options.ResyncPeriod, informers.WithNamespace(controllerNamespace))
+	for kind, informer := range map[string]cache.SharedIndexInformer{
+		"DaemonSet":  factory.Apps().V1().DaemonSets().Informer(),
+		"Deployment": factory.Apps().V1().Deployments().Informer(),
+		"Service":    factory.Core().V1().Services().Informer(),
+	} {
+		kind := kind
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { c.enqueue(kind, obj) },
+			UpdateFunc: func(_, obj interface{}) { c.enqueue(kind, obj) },
+		})
+		if err != nil {
+			return fmt.Errorf("watching %s objects: %w", kind, err)
+		}
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("syncing the %s informer cache", informerType)
+		}
+	}
+	c.synced.Store(true)
+
+	// Objects that aren't watched, like the kube-proxy config map, are looked at along with their component, so every
+	// component is reconciled once at the start
+	for component := range c.options.components() {
+		c.queue.Add(component)
+	}
+
+	go wait.UntilWithContext(ctx, c.work, time.Second)
+	<-ctx.Done()
+	return nil
+}
+
+// enqueue queues the component of a watched object.
+func (c *Controller) enqueue(kind string, obj interface{}) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	component, ok := c.watched[ClusterObject{Kind: kind, Namespace: accessor.GetNamespace(), Name: accessor.GetName()}]
+	if ok {
+		c.queue.Add(component)
+	}
+}
+
+// work reconciles queued components until the queue shuts down.
+func (c *Controller) work(ctx context.Context) {
+	for {
+		item, shutdown := c.queue.Get()
+		if shutdown {
+			return
+		}
+		component := item.(string)
+
+		err := c.reconcile(ctx, component)
+		if err != nil {
+			c.metrics.reconciles.WithLabelValues(component, "error").Inc()
+			klog.ErrorS(err, "Reconcile failed, retrying", "component", component)
+			c.queue.AddRateLimited(component)
+		} else {
+			c.metrics.reconciles.WithLabelValues(component, "success").Inc()
+			c.queue.Forget(component)
+		}
+		c.queue.Done(component)
+	}
+}
+
+// reconcile removes or re-adopts the objects of a component that carry the EKS component label again. Objects without
+// the label, such as a replacement that was installed under the same name, are left alone. Nothing is removed unless
+// the guards of the component pass, and objects owned by an EKS managed add-on are only changed once the add-on is
+// released. Every change is recorded in the same audit trail as the changes of a cleaneks_job.
+func (c *Controller) reconcile(ctx context.Context, component string) error {
+	action := c.options.action(component)
+	addonOwned := 0
+	changed := []ClusterObject{}
+	owned := []string{}
+	for _, object := range c.options.components()[component] {
+		isAwsOne, isAddonManaged, err := ObjectExistsAndIsAwsOne(ctx, c.clientset, object)
+		if err != nil {
+			return fmt.Errorf("checking %s is AWS one: %w", object, err)
+		}
+		if isAddonManaged {
+			addonOwned++
+		}
+		if !isAwsOne {
+			continue
+		}
+		changed = append(changed, object)
+		if isAddonManaged {
+			owned = append(owned, object.String())
+		}
+	}
+	c.metrics.addonOwned.WithLabelValues(component).Set(float64(addonOwned))
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if action == componentActionRemove {
+		guard, err := c.guardRemoval(ctx, component)
+		if err != nil {
+			if guard != "" {
+				c.metrics.refused.WithLabelValues(component, guard).Inc()
+			}
+			return err
+		}
+	}
+
+	// The add-on puts back whatever is changed the next time it reconciles, changing its objects anyway would never end
+	if len(owned) > 0 {
+		if c.eksAddons == nil {
+			c.metrics.refused.WithLabelValues(component, controllerGuardAddonOwnership).Inc()
+			return fmt.Errorf("refusing to change objects owned by the EKS managed add-on %s, which puts them back the next time it reconciles, set -manage-eks-addons to release it first: %s", controllerEksAddons[component], strings.Join(owned, "; "))
+		}
+		err := c.releaseEksAddon(ctx, component, action)
+		if err != nil {
+			return err
+		}
+	}
+
+	audit := NewAuditLog(c.clientset, c.version, c.options.DryRun)
+	if err := audit.Snapshot(ctx, changed); err != nil {
+		klog.ErrorS(err, "Failed to snapshot the objects for the audit trail", "component", component)
+	}
+	defer func() {
+		if err := audit.Flush(ctx); err != nil {
+			klog.ErrorS(err, "Failed to write the audit ledger", "component", component)
+		}
+	}()
+
+	for _, object := range changed {
+		switch action {
+		case componentActionRemove:
+			deleted, err := DeleteObject(ctx, c.clientset, object, c.options.DryRun)
+			if err != nil {
+				return fmt.Errorf("removing %s: %w", object, err)
+			}
+			if deleted {
+				c.metrics.changes.WithLabelValues(component, object.Kind, auditActionRemoved).Inc()
+				klog.InfoS("Removed reappeared object", "object", object.String(), "dryRun", c.options.DryRun)
+				c.record(ctx, audit, auditActionRemoved, object)
+			}
+		case componentActionAdopt:
+			diff, err := ImportObjectIntoHelm(ctx, c.clientset, object, c.options.DryRun)
+			if err != nil {
+				return fmt.Errorf("importing %s to Helm: %w", object, err)
+			}
+			if diff != "" {
+				c.metrics.changes.WithLabelValues(component, object.Kind, auditActionAdopted).Inc()
+				klog.InfoS("Adopted reappeared object", "object", object.String(), "dryRun", c.options.DryRun, "diff", diff)
+				c.record(ctx, audit, auditActionAdopted, object)
+			}
+		}
+	}
+	return nil
+}
+
+// record adds a change to the audit trail. A change that can't be recorded has still been made, so it is only logged.
+func (c *Controller) record(ctx context.Context, audit *AuditLog, action string, object ClusterObject) {
+	if err := audit.Record(ctx, action, object); err != nil {
+		klog.ErrorS(err, "Failed to record the change in the audit trail", "object", object.String(), "action", action)
+	}
+}
+
+// releaseEksAddon deletes the EKS managed add-on of the component, keeping its objects when they are adopted. The EKS
+// API has no dry run, so during a dry run the add-on is left alone.
+func (c *Controller) releaseEksAddon(ctx context.Context, component string, action string) error {
+	name := controllerEksAddons[component]
+	if c.options.DryRun {
+		klog.InfoS("Would release the EKS managed add-on", "addon", name, "component", component)
+		return nil
+	}
+
+	released, err := c.eksAddons.Release(ctx, name, action == componentActionAdopt)
+	if err != nil {
+		return fmt.Errorf("releasing EKS add-on %s: %w", name, err)
+	}
+	if released {
+		klog.InfoS("Released the EKS managed add-on", "addon", name, "component", component)
+	}
+	return nil
+}
+
+// Guards the controller checks before it changes a component, the guard label of the refused removals metric.
+const controllerGuardVerifyEks string = "verify_eks"
+const controllerGuardAwsCniDependencies string = "aws_cni_dependencies"
+const controllerGuardKubeProxyReplacement string = "kube_proxy_replacement"
+const controllerGuardAddonOwnership string = "addon_ownership"
+
+// guardRemoval runs the checks a cleaneks_job makes before it removes the component: that the cluster is the expected
+// EKS cluster, that no VPC CNI feature depends on AWS CNI and that a kube-proxy replacement is active. It returns the
+// guard that refused the removal, empty when a check couldn't be made at all.
+func (c *Controller) guardRemoval(ctx context.Context, component string) (guard string, err error) {
+	if c.options.VerifyEks {
+		info, problems, err := VerifyEksCluster(ctx, c.clientset, c.clusterArn, c.options.ExpectedClusterName, c.options.ExpectedClusterArn)
+		if err != nil {
+			return "", fmt.Errorf("verifying EKS cluster: %w", err)
+		}
+		if len(problems) > 0 {
+			return controllerGuardVerifyEks, fmt.Errorf("refusing to remove %s, the cluster could not be verified as the expected EKS cluster: %s", component, strings.Join(problems, "; "))
+		}
+		if arn := info.Arn(); arn != "" {
+			c.clusterArn = arn
+		}
+	}
+
+	switch component {
+	case controllerComponentAwsCni:
+		dependencies, err := AwsCniDependencies(ctx, c.clientset, "kube-system", "aws-node")
+		if err != nil {
+			return "", fmt.Errorf("checking for AWS CNI dependencies: %w", err)
+		}
+		unacknowledged := UnacknowledgedAwsCniDependencies(dependencies, c.options.AcknowledgedAwsCniDependencies)
+		if len(unacknowledged) > 0 {
+			return controllerGuardAwsCniDependencies, fmt.Errorf("refusing to remove AWS CNI, the cluster uses VPC CNI features that break without it, acknowledge them with -acknowledged-aws-cni-dependency: %s", strings.Join(unacknowledged, "; "))
+		}
+	case controllerComponentKubeProxy:
+		replacement, reasons, err := ActiveKubeProxyReplacement(ctx, c.clientset, []KubeProxyReplacementCheck{CiliumKubeProxyReplacementCheck})
+		if err != nil {
+			return "", fmt.Errorf("checking for kube-proxy replacement %w", err)
+		}
+		if replacement == "" {
+			if !c.options.Force {
+				return controllerGuardKubeProxyReplacement, fmt.Errorf("refusing to remove Kube Proxy, which breaks every ClusterIP service without an active kube-proxy replacement, set -force to remove it anyway: %s", strings.Join(reasons, "; "))
+			}
+			klog.InfoS("Removing Kube Proxy without a detected replacement, as force is set", "reasons", reasons)
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,39 @@
+package provider
+
+import "testing"
+
+func TestControllerOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ControllerOptions
+		wantErr string
+	}{
+		{name: "remove aws cni", options: ControllerOptions{AwsCni: "remove"}},
+		{name: "remove kube proxy", options: ControllerOptions{KubeProxy: "remove"}},
+		{name: "remove coredns", options: ControllerOptions{Coredns: "remove"}},
+		{name: "adopt coredns", options: ControllerOptions{AwsCni: "keep", KubeProxy: "keep", Coredns: "adopt"}},
+		{name: "remove all", options: ControllerOptions{AwsCni: "remove", KubeProxy: "remove", Coredns: "remove"}},
+		{name: "nothing", wantErr: "nothing to enforce, set aws-cni, kube-proxy or coredns"},
+		{name: "keep all", options: ControllerOptions{AwsCni: "keep", KubeProxy: "keep", Coredns: "keep"}, wantErr: "nothing to enforce, set aws-cni, kube-proxy or coredns"},
+		{name: "adopt aws cni", options: ControllerOptions{AwsCni: "adopt"}, wantErr: `aws-cni: unsupported action "adopt"`},
+		{name: "unknown kube proxy action", options: ControllerOptions{AwsCni: "remove", KubeProxy: "delete"}, wantErr: `kube-proxy: unsupported action "delete"`},
+		{name: "restore coredns", options: ControllerOptions{Coredns: "restore"}, wantErr: `coredns: unsupported action "restore"`},
+		{name: "manage eks addons", options: ControllerOptions{AwsCni: "remove", ManageEksAddons: true, ClusterName: "prod"}},
+		{name: "manage eks addons of expected cluster", options: ControllerOptions{AwsCni: "remove", ManageEksAddons: true, ExpectedClusterName: "prod"}},
+		{name: "manage eks addons without cluster", options: ControllerOptions{AwsCni: "remove", ManageEksAddons: true}, wantErr: "manage-eks-addons needs the name of the EKS cluster, set cluster-name or expected-cluster-name"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate()
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("Validate() unexpected error: %s", err)
+			case test.wantErr != "" && err == nil:
+				t.Errorf("Validate() = nil, want %q", test.wantErr)
+			case test.wantErr != "" && err.Error() != test.wantErr:
+				t.Errorf("Validate() = %q, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
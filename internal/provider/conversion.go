@@ -37,6 +37,26 @@ func StringListToStrings(v attr.Value) []string {
 	return out
 }
 
+// ValueToSetType ensures we have a types.Set literal
+func ValueToSetType(v attr.Value) types.Set {
+	if vb, ok := v.(types.Set); ok {
+		return vb
+	} else if vb, ok := v.(*types.Set); ok {
+		return *vb
+	} else {
+		panic(fmt.Sprintf("cannot pass type %T to conv.ValueToSetType", v))
+	}
+}
+
+func StringSetToStrings(v attr.Value) []string {
+	vt := ValueToSetType(v)
+	out := make([]string, len(vt.Elements()))
+	for i, ve := range vt.Elements() {
+		out[i] = AttributeValueToString(ve)
+	}
+	return out
+}
+
 func ValueToMapType(v attr.Value) types.Map {
 	if vb, ok := v.(types.Map); ok {
 		return vb
@@ -55,3 +75,12 @@ func StringMapToStrings(v attr.Value) map[string]string {
 	}
 	return out
 }
+
+// StringsToList converts a slice of strings into a types.List, an empty list is returned for a nil slice
+func StringsToList(values []string) types.List {
+	elements := make([]attr.Value, 0, len(values))
+	for _, value := range values {
+		elements = append(elements, types.StringValue(value))
+	}
+	return types.ListValueMust(types.StringType, elements)
+}
@@ -0,0 +1,444 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &RequiredRbacDataSource{}
+var _ datasource.DataSourceWithValidateConfig = &RequiredRbacDataSource{}
+
+const defaultRequiredRbacName string = "cleaneks"
+const defaultLockLeaseName string = "cleaneks-job"
+
+func NewRequiredRbacDataSource() datasource.DataSource {
+	return &RequiredRbacDataSource{}
+}
+
+type RequiredRbacDataSource struct {
+	provider *CleanEksProvider
+}
+
+type RequiredRbacDataSourceModel struct {
+	ID   types.String `tfsdk:"id"`
+	Name types.String `tfsdk:"name"`
+
+	AwsCni    *JobComponentActionModel `tfsdk:"aws_cni"`
+	KubeProxy *JobComponentActionModel `tfsdk:"kube_proxy"`
+	Coredns   *JobComponentActionModel `tfsdk:"coredns"`
+
+	DryRun        types.Bool   `tfsdk:"dry_run"`
+	VerifyEks     types.Bool   `tfsdk:"verify_eks"`
+	LockLeaseName types.String `tfsdk:"lock_lease_name"`
+
+	KubeProxyReplacementChecks []JobKubeProxyReplacementCheckModel `tfsdk:"kube_proxy_replacement_check"`
+
+	WaitFor []JobWaitForModel `tfsdk:"wait_for"`
+
+	AdmissionGuard []JobAdmissionGuardModel `tfsdk:"admission_guard"`
+
+	RequiredPermissions types.List              `tfsdk:"required_permissions"`
+	ClusterRoleRules    []RequiredRbacRuleModel `tfsdk:"cluster_role_rules"`
+	Roles               []RequiredRbacRoleModel `tfsdk:"roles"`
+	ClusterRoleYaml     types.String            `tfsdk:"cluster_role_yaml"`
+	RoleYaml            types.String            `tfsdk:"role_yaml"`
+}
+
+// RequiredRbacRuleModel is one rule of the ClusterRole or a Role.
+type RequiredRbacRuleModel struct {
+	ApiGroups       []string `tfsdk:"api_groups"`
+	Resources       []string `tfsdk:"resources"`
+	ResourceNames   []string `tfsdk:"resource_names"`
+	NonResourceUrls []string `tfsdk:"non_resource_urls"`
+	Verbs           []string `tfsdk:"verbs"`
+}
+
+// RequiredRbacRoleModel is the Role of a namespace.
+type RequiredRbacRoleModel struct {
+	Namespace types.String            `tfsdk:"namespace"`
+	Rules     []RequiredRbacRuleModel `tfsdk:"rules"`
+}
+
+func requiredRbacRules(rules []rbacv1.PolicyRule) []RequiredRbacRuleModel {
+	models := make([]RequiredRbacRuleModel, 0, len(rules))
+	for _, rule := range rules {
+		models = append(models, RequiredRbacRuleModel{
+			ApiGroups:       rule.APIGroups,
+			Resources:       rule.Resources,
+			ResourceNames:   rule.ResourceNames,
+			NonResourceUrls: rule.NonResourceURLs,
+			Verbs:           rule.Verbs,
+		})
+	}
+	return models
+}
+
+func (d *RequiredRbacDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_required_rbac"
+}
+
+func requiredRbacRuleAttributes() map[string]schema.Attribute {
+	list := func(description string) schema.Attribute {
+		return schema.ListAttribute{
+			Description: description,
+			Computed:    true,
+			ElementType: types.StringType,
+		}
+	}
+	return map[string]schema.Attribute{
+		"api_groups":        list("API groups of the resources, empty for the core group."),
+		"resources":         list("Resources the rule applies to."),
+		"resource_names":    list("Names of the objects the rule is restricted to, null for any object."),
+		"non_resource_urls": list("Non resource URLs the rule applies to."),
+		"verbs":             list("Verbs the rule allows."),
+	}
+}
+
+func componentActionBlock(component string, actions ...string) schema.Block {
+	return schema.SingleNestedBlock{
+		MarkdownDescription: fmt.Sprintf("What the job does with **%s**, as in `cleaneks_job`. Left alone when the block is absent.", component),
+		Description:         fmt.Sprintf("What the job does with %s, as in cleaneks_job. Left alone when the block is absent.", component),
+		Attributes: map[string]schema.Attribute{
+			"action": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("One of `%s`.", strings.Join(actions, "`, `")),
+				Description:         fmt.Sprintf("One of %s.", strings.Join(actions, ", ")),
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(actions...),
+				},
+			},
+		},
+	}
+}
+
+func (d *RequiredRbacDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "The least privileged ClusterRole and Roles that a `cleaneks_job` with the same settings needs. " +
+			"They are generated from the steps the job runs, so they cover exactly the calls it makes. Nothing is read from the cluster.",
+		Description: "The least privileged ClusterRole and Roles that a cleaneks_job with the same settings needs. " +
+			"They are generated from the steps the job runs, so they cover exactly the calls it makes. Nothing is read from the cluster.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Name of the ClusterRole and Roles.",
+				Computed:    true,
+			},
+
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name of the ClusterRole and Roles. Defaults to `cleaneks`.",
+				Description:         "Name of the ClusterRole and Roles. Defaults to cleaneks.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+
+			"dry_run": schema.BoolAttribute{
+				MarkdownDescription: "`dry_run` of the job, which needs no permissions to take the lock or write the audit trail. Defaults to the provider `dry_run` setting.",
+				Description:         "dry_run of the job, which needs no permissions to take the lock or write the audit trail. Defaults to the provider dry_run setting.",
+				Optional:            true,
+			},
+
+			"verify_eks": schema.BoolAttribute{
+				MarkdownDescription: "`verify_eks` of the job. Defaults to `true`.",
+				Description:         "verify_eks of the job. Defaults to true.",
+				Optional:            true,
+			},
+
+			"lock_lease_name": schema.StringAttribute{
+				MarkdownDescription: "`lock_lease_name` of the job. Defaults to `cleaneks-job`.",
+				Description:         "lock_lease_name of the job. Defaults to cleaneks-job.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+
+			"required_permissions": schema.ListAttribute{
+				MarkdownDescription: "Every (verb, group, resource, namespace, name) the job needs, e.g. `delete daemonsets.apps kube-system/aws-node`, as in the `required_permissions` of the job.",
+				Description:         "Every (verb, group, resource, namespace, name) the job needs, e.g. delete daemonsets.apps kube-system/aws-node, as in the required_permissions of the job.",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+
+			"cluster_role_rules": schema.ListNestedAttribute{
+				Description: "Rules of the ClusterRole: cluster scoped resources, calls across all namespaces and non resource URLs.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: requiredRbacRuleAttributes(),
+				},
+			},
+
+			"roles": schema.ListNestedAttribute{
+				Description: "The Role of every namespace the job makes namespaced calls in.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"namespace": schema.StringAttribute{
+							Description: "Namespace of the Role.",
+							Computed:    true,
+						},
+						"rules": schema.ListNestedAttribute{
+							Description: "Rules of the Role.",
+							Computed:    true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: requiredRbacRuleAttributes(),
+							},
+						},
+					},
+				},
+			},
+
+			"cluster_role_yaml": schema.StringAttribute{
+				Description: "The ClusterRole manifest.",
+				Computed:    true,
+			},
+
+			"role_yaml": schema.StringAttribute{
+				Description: "The Role manifests, one YAML document per namespace.",
+				Computed:    true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"aws_cni": componentActionBlock("AWS-CNI", componentActionKeep, componentActionRemove),
+
+			"kube_proxy": componentActionBlock("Kube-Proxy", componentActionKeep, componentActionRemove),
+
+			"coredns": componentActionBlock("CoreDNS", componentActionKeep, componentActionRemove, componentActionAdopt, componentActionRestore),
+
+			"kube_proxy_replacement_check": schema.ListNestedBlock{
+				MarkdownDescription: "`kube_proxy_replacement_check` blocks of the job.",
+				Description:         "kube_proxy_replacement_check blocks of the job.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Description: "Name of the replacement.",
+							Optional:    true,
+						},
+						"config_map_namespace": schema.StringAttribute{
+							MarkdownDescription: "Namespace of the config map that enables the replacement. Defaults to `kube-system`.",
+							Description:         "Namespace of the config map that enables the replacement. Defaults to kube-system.",
+							Optional:            true,
+						},
+						"config_map_name": schema.StringAttribute{
+							Description: "Name of the config map that enables the replacement.",
+							Optional:    true,
+						},
+						"config_map_key": schema.StringAttribute{
+							Description: "Key in the config map that enables the replacement.",
+							Optional:    true,
+						},
+						"expected_values": schema.ListAttribute{
+							Description: "Values of the config map key that mean the replacement is enabled.",
+							Optional:    true,
+							ElementType: types.StringType,
+						},
+						"daemonset_namespace": schema.StringAttribute{
+							MarkdownDescription: "Namespace of the daemonset that runs the replacement. Defaults to `kube-system`.",
+							Description:         "Namespace of the daemonset that runs the replacement. Defaults to kube-system.",
+							Optional:            true,
+						},
+						"daemonset_name": schema.StringAttribute{
+							Description: "Name of the daemonset that runs the replacement.",
+							Required:    true,
+						},
+					},
+				},
+			},
+
+			"wait_for": schema.ListNestedBlock{
+				MarkdownDescription: "`wait_for` blocks of the job.",
+				Description:         "wait_for blocks of the job.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"kind": schema.StringAttribute{
+							MarkdownDescription: "Kind of object to wait for. One of `DaemonSet`, `Deployment` or `Node`.",
+							Description:         "Kind of object to wait for. One of DaemonSet, Deployment or Node.",
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf(waitForKindDaemonSet, waitForKindDeployment, waitForKindNode),
+							},
+						},
+						"namespace": schema.StringAttribute{
+							MarkdownDescription: "Namespace of the DaemonSet or Deployment. Defaults to `kube-system`.",
+							Description:         "Namespace of the DaemonSet or Deployment. Defaults to kube-system.",
+							Optional:            true,
+						},
+						"name": schema.StringAttribute{
+							Description: "Name of the DaemonSet or Deployment, or of a single node.",
+							Optional:    true,
+						},
+						"min_ready": schema.Int64Attribute{
+							Description: "Minimum number of ready pods (or nodes).",
+							Optional:    true,
+							Validators: []validator.Int64{
+								int64validator.AtLeast(0),
+							},
+						},
+					},
+				},
+			},
+
+			"admission_guard": schema.ListNestedBlock{
+				MarkdownDescription: "`admission_guard` block of the job.",
+				Description:         "admission_guard block of the job.",
+				Validators: []validator.List{
+					listvalidator.SizeAtMost(1),
+				},
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Name of the policy and the binding. Defaults to `cleaneks-guard`.",
+							Description:         "Name of the policy and the binding. Defaults to cleaneks-guard.",
+							Optional:            true,
+						},
+					},
+					Blocks: map[string]schema.Block{
+						"object": schema.ListNestedBlock{
+							Description: "Another object that must not be created again with the EKS component label.",
+							NestedObject: schema.NestedBlockObject{
+								Attributes: map[string]schema.Attribute{
+									"kind": schema.StringAttribute{
+										MarkdownDescription: "Kind of the object. One of `DaemonSet`, `Deployment`, `Service`, `ServiceAccount`, `ConfigMap` or `PodDisruptionBudget`.",
+										Description:         "Kind of the object. One of DaemonSet, Deployment, Service, ServiceAccount, ConfigMap or PodDisruptionBudget.",
+										Required:            true,
+										Validators: []validator.String{
+											stringvalidator.OneOf("DaemonSet", "Deployment", "Service", "ServiceAccount", "ConfigMap", "PodDisruptionBudget"),
+										},
+									},
+									"namespace": schema.StringAttribute{
+										MarkdownDescription: "Namespace of the object. Defaults to `kube-system`.",
+										Description:         "Namespace of the object. Defaults to kube-system.",
+										Optional:            true,
+									},
+									"name": schema.StringAttribute{
+										Description: "Name of the object.",
+										Required:    true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *RequiredRbacDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var model RequiredRbacDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateWaitFor(model.WaitFor)...)
+	resp.Diagnostics.Append(validateKubeProxyReplacementChecks(model.KubeProxyReplacementChecks)...)
+	resp.Diagnostics.Append(validateAdmissionGuard(model.AdmissionGuard, model.AwsCni, model.KubeProxy)...)
+}
+
+func (d *RequiredRbacDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	cleanEksProvider, diags := configureProvider(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+	if cleanEksProvider != nil {
+		d.provider = cleanEksProvider
+	}
+}
+
+// Options returns the settings of the job the data source describes, with the defaults of the job for the ones that
+// aren't set.
+func (m RequiredRbacDataSourceModel) Options(p *CleanEksProvider) JobOptions {
+	defaultString := func(value types.String, defaultValue string) types.String {
+		if value.IsNull() || value.IsUnknown() {
+			return types.StringValue(defaultValue)
+		}
+		return value
+	}
+
+	waitFor := make([]JobWaitForModel, 0, len(m.WaitFor))
+	for _, condition := range m.WaitFor {
+		condition.Namespace = defaultString(condition.Namespace, "kube-system")
+		waitFor = append(waitFor, condition)
+	}
+	checks := make([]JobKubeProxyReplacementCheckModel, 0, len(m.KubeProxyReplacementChecks))
+	for _, check := range m.KubeProxyReplacementChecks {
+		check.ConfigMapNamespace = defaultString(check.ConfigMapNamespace, "kube-system")
+		check.DaemonsetNamespace = defaultString(check.DaemonsetNamespace, "kube-system")
+		checks = append(checks, check)
+	}
+
+	options := JobOptions{
+		AwsCni:                     m.AwsCni.ValueAction(),
+		KubeProxy:                  m.KubeProxy.ValueAction(),
+		Coredns:                    m.Coredns.ValueAction(),
+		VerifyEks:                  m.VerifyEks.IsNull() || m.VerifyEks.ValueBool(),
+		LockLeaseName:              defaultString(m.LockLeaseName, defaultLockLeaseName).ValueString(),
+		KubeProxyReplacementChecks: kubeProxyReplacementChecks(checks),
+		WaitFor:                    waitConditions(waitFor),
+		ReadObjects:                jobReadObjects,
+		ReadyTimeout:               defaultReadyTimeout,
+	}
+	options.AdmissionGuard = admissionGuardValue(m.AdmissionGuard, options.AwsCni, options.KubeProxy)
+	if p != nil {
+		options.DryRun = dryRunValue(p, m.DryRun)
+		options.ReadyTimeout = p.ReadyTimeout()
+	} else {
+		options.DryRun = m.DryRun.ValueBool()
+	}
+	return options
+}
+
+func (d *RequiredRbacDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var model RequiredRbacDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	permissions := RequiredPermissions(model.Options(d.provider))
+	rules := PermissionsToRbacRules(permissions)
+
+	name := defaultRequiredRbacName
+	if !(model.Name.IsNull() || model.Name.IsUnknown()) {
+		name = model.Name.ValueString()
+	}
+
+	clusterRoleYaml, err := rules.ClusterRoleYaml(name)
+	if err != nil {
+		resp.Diagnostics.AddError("Error generating ClusterRole", fmt.Sprintf("Error generating ClusterRole: %s", err))
+		return
+	}
+	roleYaml, err := rules.RoleYaml(name)
+	if err != nil {
+		resp.Diagnostics.AddError("Error generating Role", fmt.Sprintf("Error generating Role: %s", err))
+		return
+	}
+
+	requiredPermissions, diags := types.ListValueFrom(ctx, types.StringType, PermissionsToStrings(permissions))
+	resp.Diagnostics.Append(diags...)
+
+	model.ID = types.StringValue(name)
+	model.RequiredPermissions = requiredPermissions
+	model.ClusterRoleRules = requiredRbacRules(rules.ClusterRole)
+	model.Roles = make([]RequiredRbacRoleModel, 0, len(rules.Roles))
+	for _, namespace := range rules.Namespaces() {
+		model.Roles = append(model.Roles, RequiredRbacRoleModel{
+			Namespace: types.StringValue(namespace),
+			Rules:     requiredRbacRules(rules.Roles[namespace]),
+		})
+	}
+	model.ClusterRoleYaml = types.StringValue(clusterRoleYaml)
+	model.RoleYaml = types.StringValue(roleYaml)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
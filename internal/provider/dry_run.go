@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// dryRunOptions returns the value for the DryRun field of Kubernetes write options. When dryRun is set the API server
+// runs admission, RBAC and conflict checks for the request but does not persist anything.
+func dryRunOptions(dryRun bool) []string {
+	if dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// DeleteChange describes the removal of an object so that it can be recorded next to object diffs.
+func DeleteChange(kind string, namespace string, name string) string {
+	return fmt.Sprintf("delete %s %s/%s", kind, namespace, name)
+}
+
+// ObjectDiff returns a unified diff between the YAML representation of two versions of the same object. Fields that
+// change on every write (resource version, generation and managed fields) are ignored. An empty string is returned
+// when there is no difference.
+func ObjectDiff(kind string, before runtime.Object, after runtime.Object) (string, error) {
+	beforeYaml, err := objectYaml(before)
+	if err != nil {
+		return "", err
+	}
+
+	afterYaml, err := objectYaml(after)
+	if err != nil {
+		return "", err
+	}
+
+	objectName := kind
+	if accessor, ok := before.(metav1.Object); ok {
+		objectName = fmt.Sprintf("%s %s/%s", kind, accessor.GetNamespace(), accessor.GetName())
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(beforeYaml),
+		B:        difflib.SplitLines(afterYaml),
+		FromFile: "live " + objectName,
+		ToFile:   "updated " + objectName,
+		Context:  3,
+	})
+}
+
+func objectYaml(obj runtime.Object) (string, error) {
+	obj = obj.DeepCopyObject()
+	if accessor, ok := obj.(metav1.Object); ok {
+		accessor.SetResourceVersion("")
+		accessor.SetGeneration(0)
+		accessor.SetManagedFields(nil)
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+// DurationValidator returns a validator which ensures that any configured
+// string value can be parsed with time.ParseDuration, e.g. "90s" or "10m".
+func DurationValidator() validator.String {
+	return durationValidator{}
+}
+
+// durationValidator validates that a string attribute is a Go duration.
+type durationValidator struct{}
+
+// Description returns a human-readable description of the validator.
+func (v durationValidator) Description(_ context.Context) string {
+	return "value must be a duration such as 30s, 5m or 1h"
+}
+
+// MarkdownDescription returns a markdown description of the validator.
+func (v durationValidator) MarkdownDescription(_ context.Context) string {
+	return "value must be a duration such as `30s`, `5m` or `1h`"
+}
+
+// ValidateString implements the validation logic.
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	duration, err := time.ParseDuration(req.ConfigValue.ValueString())
+	if err != nil || duration < 0 {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Duration",
+			fmt.Sprintf("Attribute %s %s, got: %s", req.Path, v.Description(ctx), req.ConfigValue.ValueString()),
+		)
+	}
+}
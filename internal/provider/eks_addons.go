@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Names of the EKS managed add-ons of the components.
+const eksAddonAwsCni string = "vpc-cni"
+const eksAddonKubeProxy string = "kube-proxy"
+const eksAddonCoredns string = "coredns"
+
+const eksAddonPollInterval = 10 * time.Second
+
+// EksAddons releases components from, and hands them back to, the EKS managed add-ons of a cluster. Deleting the
+// objects of a component that is installed as a managed add-on doesn't last, EKS puts them back the next time the
+// add-on reconciles.
+type EksAddons struct {
+	ClusterName string
+	// Client returns the EKS API client, it is only created once an add-on step runs
+	Client func(ctx context.Context) (*eks.Client, error)
+}
+
+// NewEksAddons returns the add-ons of the cluster, loading the AWS configuration of region on first use and calling
+// endpoint instead of the regional endpoint when it is set.
+func NewEksAddons(clusterName string, region string, endpoint string) *EksAddons {
+	var client *eks.Client
+	return &EksAddons{
+		ClusterName: clusterName,
+		Client: func(ctx context.Context) (*eks.Client, error) {
+			if client == nil {
+				cfg, err := LoadAwsConfig(ctx, EksAuthOptions{ClusterName: clusterName, Region: region})
+				if err != nil {
+					return nil, err
+				}
+				client = NewEksClient(cfg, endpoint)
+			}
+			return client, nil
+		},
+	}
+}
+
+// EksAddonChange describes a change to an add-on, in the same form as the changes made to objects.
+func EksAddonChange(action string, name string) string {
+	return fmt.Sprintf("%s EKS add-on %s", action, name)
+}
+
+// describe returns the add-on, nil when it isn't installed.
+func (a *EksAddons) describe(ctx context.Context, client *eks.Client, name string) (*ekstypes.Addon, error) {
+	output, err := client.DescribeAddon(ctx, &eks.DescribeAddonInput{
+		ClusterName: aws.String(a.ClusterName),
+		AddonName:   aws.String(name),
+	})
+	var notFound *ekstypes.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("describing EKS add-on %s of cluster %s: %w", name, a.ClusterName, err)
+	}
+	return output.Addon, nil
+}
+
+// Installed returns whether the add-on is installed.
+func (a *EksAddons) Installed(ctx context.Context, name string) (bool, error) {
+	client, err := a.Client(ctx)
+	if err != nil {
+		return false, err
+	}
+	addon, err := a.describe(ctx, client, name)
+	return addon != nil, err
+}
+
+// Release deletes the add-on and waits until it is gone. With preserve the objects of the add-on are kept, which turns
+// the component into a self-managed one, otherwise EKS removes them as well. It returns false when the add-on isn't
+// installed.
+func (a *EksAddons) Release(ctx context.Context, name string, preserve bool) (released bool, err error) {
+	client, err := a.Client(ctx)
+	if err != nil {
+		return false, err
+	}
+	addon, err := a.describe(ctx, client, name)
+	if err != nil || addon == nil {
+		return false, err
+	}
+
+	if addon.Status != ekstypes.AddonStatusDeleting {
+		tflog.Debug(ctx, "Deleting EKS add-on", map[string]interface{}{
+			"clusterName": a.ClusterName,
+			"addon":       name,
+			"preserve":    preserve,
+		})
+		_, err = client.DeleteAddon(ctx, &eks.DeleteAddonInput{
+			ClusterName: aws.String(a.ClusterName),
+			AddonName:   aws.String(name),
+			Preserve:    preserve,
+		})
+		if err != nil {
+			return false, fmt.Errorf("deleting EKS add-on %s of cluster %s: %w", name, a.ClusterName, err)
+		}
+	}
+
+	err = a.wait(ctx, client, name, func(addon *ekstypes.Addon) (bool, error) {
+		if addon == nil {
+			return true, nil
+		}
+		if addon.Status == ekstypes.AddonStatusDeleteFailed {
+			return false, fmt.Errorf("deleting EKS add-on %s of cluster %s failed: %s", name, a.ClusterName, addonIssues(addon))
+		}
+		return false, nil
+	})
+	return err == nil, err
+}
+
+// HandBack installs the add-on, overwriting the self-managed objects of the component, and waits until it is active.
+// It returns false when the add-on is already installed.
+func (a *EksAddons) HandBack(ctx context.Context, name string) (created bool, err error) {
+	client, err := a.Client(ctx)
+	if err != nil {
+		return false, err
+	}
+	addon, err := a.describe(ctx, client, name)
+	if err != nil || addon != nil {
+		return false, err
+	}
+
+	tflog.Debug(ctx, "Creating EKS add-on", map[string]interface{}{
+		"clusterName": a.ClusterName,
+		"addon":       name,
+	})
+	_, err = client.CreateAddon(ctx, &eks.CreateAddonInput{
+		ClusterName:      aws.String(a.ClusterName),
+		AddonName:        aws.String(name),
+		ResolveConflicts: ekstypes.ResolveConflictsOverwrite,
+	})
+	if err != nil {
+		return false, fmt.Errorf("creating EKS add-on %s of cluster %s: %w", name, a.ClusterName, err)
+	}
+
+	err = a.wait(ctx, client, name, func(addon *ekstypes.Addon) (bool, error) {
+		switch {
+		case addon == nil:
+			return false, nil
+		case addon.Status == ekstypes.AddonStatusActive:
+			return true, nil
+		case addon.Status == ekstypes.AddonStatusCreateFailed:
+			return false, fmt.Errorf("creating EKS add-on %s of cluster %s failed: %s", name, a.ClusterName, addonIssues(addon))
+		default:
+			return false, nil
+		}
+	})
+	return err == nil, err
+}
+
+// wait polls the add-on until done returns true or an error, or ctx is done.
+func (a *EksAddons) wait(ctx context.Context, client *eks.Client, name string, done func(addon *ekstypes.Addon) (bool, error)) error {
+	var status ekstypes.AddonStatus
+	err := wait.PollUntilContextCancel(ctx, eksAddonPollInterval, true, func(ctx context.Context) (bool, error) {
+		addon, err := a.describe(ctx, client, name)
+		if err != nil {
+			return false, err
+		}
+		if addon != nil {
+			status = addon.Status
+		}
+		return done(addon)
+	})
+	if err != nil && wait.Interrupted(err) {
+		return fmt.Errorf("gave up waiting for EKS add-on %s of cluster %s, its status is %q: %w", name, a.ClusterName, status, err)
+	}
+	return err
+}
+
+// addonIssues describes the health issues EKS reports for an add-on.
+func addonIssues(addon *ekstypes.Addon) string {
+	if addon.Health == nil || len(addon.Health.Issues) == 0 {
+		return string(addon.Status)
+	}
+	issues := ""
+	for i, issue := range addon.Health.Issues {
+		if i > 0 {
+			issues += "; "
+		}
+		issues += fmt.Sprintf("%s: %s", issue.Code, aws.ToString(issue.Message))
+	}
+	return issues
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const eksTokenPrefix string = "k8s-aws-v1."
+const eksClusterIdHeader string = "x-k8s-aws-id"
+
+// EKS accepts a token for 15 minutes after it was signed, it is replaced a minute before that.
+const eksTokenLifetime = 15 * time.Minute
+const eksTokenRefreshBefore = 1 * time.Minute
+
+// EksAuthOptions are the settings of the eks_auth block.
+type EksAuthOptions struct {
+	ClusterName string
+	// Region, Profile and RoleArn are optional, the standard AWS credential sources and region are used when they are
+	// empty
+	Region  string
+	Profile string
+	RoleArn string
+}
+
+// LoadAwsConfig loads the AWS configuration from the standard sources (environment, shared config and credentials
+// files, web identity, container and instance metadata), in the profile and region of the options, assuming the role
+// when one is set.
+func LoadAwsConfig(ctx context.Context, options EksAuthOptions) (aws.Config, error) {
+	var loadOptions []func(*config.LoadOptions) error
+	if options.Region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(options.Region))
+	}
+	if options.Profile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(options.Profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return cfg, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		return cfg, fmt.Errorf("no AWS region is configured, set region in eks_auth or AWS_REGION")
+	}
+
+	if options.RoleArn != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), options.RoleArn))
+	}
+	return cfg, nil
+}
+
+// EksTokenSource generates the bearer tokens EKS accepts, a presigned STS GetCallerIdentity request for the cluster,
+// and replaces them before they expire.
+type EksTokenSource struct {
+	clusterName string
+	presign     *sts.PresignClient
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func NewEksTokenSource(cfg aws.Config, clusterName string) *EksTokenSource {
+	return &EksTokenSource{
+		clusterName: clusterName,
+		presign:     sts.NewPresignClient(sts.NewFromConfig(cfg)),
+	}
+}
+
+// Token returns a token that is valid for at least another eksTokenRefreshBefore.
+func (s *EksTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.expires.Add(-eksTokenRefreshBefore)) {
+		return s.token, nil
+	}
+
+	signedAt := time.Now()
+	request, err := s.presign.PresignGetCallerIdentity(ctx, &sts.GetCallerIdentityInput{}, func(options *sts.PresignOptions) {
+		options.ClientOptions = append(options.ClientOptions, func(o *sts.Options) {
+			o.APIOptions = append(o.APIOptions,
+				smithyhttp.SetHeaderValue(eksClusterIdHeader, s.clusterName),
+				smithyhttp.SetHeaderValue("X-Amz-Expires", "60"),
+			)
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("generating EKS token for cluster %s: %w", s.clusterName, err)
+	}
+
+	s.token = eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(request.URL))
+	s.expires = signedAt.Add(eksTokenLifetime)
+	tflog.Debug(ctx, "Generated EKS token", map[string]interface{}{
+		"clusterName": s.clusterName,
+		"expires":     s.expires.Format(time.RFC3339),
+	})
+	return s.token, nil
+}
+
+// WrapTransport authenticates every request of a client built from a rest.Config with a current token.
+func (s *EksTokenSource) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &eksTokenRoundTripper{source: s, next: rt}
+}
+
+type eksTokenRoundTripper struct {
+	source *EksTokenSource
+	next   http.RoundTripper
+}
+
+func (t *eksTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+)
+
+// EksCluster is what is needed to connect to an EKS cluster.
+type EksCluster struct {
+	Arn      string
+	Endpoint string
+	// CertificateAuthority is the PEM-encoded CA bundle of the API server
+	CertificateAuthority string
+}
+
+// NewEksClient returns an EKS API client, against endpoint instead of the regional endpoint when it is set.
+func NewEksClient(cfg aws.Config, endpoint string) *eks.Client {
+	return eks.NewFromConfig(cfg, func(o *eks.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+}
+
+// DescribeEksCluster returns the endpoint and CA of the cluster. It fails while the cluster has no endpoint yet, e.g.
+// while it is being created.
+func DescribeEksCluster(ctx context.Context, client *eks.Client, name string) (cluster EksCluster, err error) {
+	output, err := client.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
+	if err != nil {
+		return cluster, fmt.Errorf("describing EKS cluster %s: %w", name, err)
+	}
+
+	if output.Cluster == nil || aws.ToString(output.Cluster.Endpoint) == "" {
+		status := ""
+		if output.Cluster != nil {
+			status = string(output.Cluster.Status)
+		}
+		return cluster, fmt.Errorf("EKS cluster %s has no endpoint yet, its status is %q", name, status)
+	}
+	cluster.Arn = aws.ToString(output.Cluster.Arn)
+	cluster.Endpoint = aws.ToString(output.Cluster.Endpoint)
+
+	if output.Cluster.CertificateAuthority != nil && aws.ToString(output.Cluster.CertificateAuthority.Data) != "" {
+		ca, err := base64.StdEncoding.DecodeString(aws.ToString(output.Cluster.CertificateAuthority.Data))
+		if err != nil {
+			return cluster, fmt.Errorf("decoding the certificate authority of EKS cluster %s: %w", name, err)
+		}
+		cluster.CertificateAuthority = string(ca)
+	}
+	return cluster, nil
+}
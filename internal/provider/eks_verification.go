@@ -0,0 +1,299 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const eksGitVersionMarker string = "-eks-"
+const eksClusterRolePrefix string = "eks:"
+const awsProviderIdPrefix string = "aws:///"
+const eksctlClusterNameLabelName string = "alpha.eksctl.io/cluster-name"
+const awsAuthNodesGroup string = "system:nodes"
+
+var eksClusterArnRegexp = regexp.MustCompile(`^arn:aws[a-zA-Z-]*:eks:([a-z0-9-]+):([0-9]{12}):cluster/([0-9A-Za-z][A-Za-z0-9\-_]*)$`)
+var eksRegionRegexp = regexp.MustCompile(`\.([a-z]{2}(?:-[a-z]+)+-[0-9]+)\.eks\.amazonaws\.com`)
+
+// EksClusterInfo is what could be learnt about the identity of the cluster from the Kubernetes API.
+type EksClusterInfo struct {
+	GitVersion string
+	// EksClusterRoles is the number of eks: prefixed cluster roles, which EKS creates in every cluster
+	EksClusterRoles int
+	// NonAwsNodes are nodes whose provider ID does not start with aws:///
+	NonAwsNodes []string
+	// ClusterName is empty when it could not be determined
+	ClusterName string
+	// Region is empty when it could not be determined
+	Region string
+	// Account is the AWS account of the cluster, empty when it could not be determined
+	Account string
+	// AwsNodeRemoved is set when aws-node, where the cluster name and account are usually found, was removed by
+	// cleaneks, so that they can't be determined any more
+	AwsNodeRemoved bool
+}
+
+// ParseEksClusterArn splits an EKS cluster ARN into its region, account and cluster name.
+func ParseEksClusterArn(arn string) (region string, account string, name string, err error) {
+	matches := eksClusterArnRegexp.FindStringSubmatch(arn)
+	if matches == nil {
+		return "", "", "", fmt.Errorf("%q is not an EKS cluster ARN", arn)
+	}
+	return matches[1], matches[2], matches[3], nil
+}
+
+// UseClusterArn fills in the cluster name, region and account that could not be discovered from arn, the ARN of the
+// cluster from the EKS API or pinned in state. It does nothing when arn isn't an EKS cluster ARN.
+func (info *EksClusterInfo) UseClusterArn(arn string) {
+	region, account, name, err := ParseEksClusterArn(arn)
+	if err != nil {
+		return
+	}
+	if info.ClusterName == "" {
+		info.ClusterName = name
+	}
+	if info.Region == "" {
+		info.Region = region
+	}
+	if info.Account == "" {
+		info.Account = account
+	}
+}
+
+// Arn returns the ARN of the cluster, empty when its name, region or account could not be determined.
+func (info EksClusterInfo) Arn() string {
+	if info.ClusterName == "" || info.Region == "" || info.Account == "" {
+		return ""
+	}
+	return fmt.Sprintf("arn:%s:eks:%s:%s:cluster/%s", awsPartition(info.Region), info.Region, info.Account, info.ClusterName)
+}
+
+// DiscoverEksCluster collects the evidence that the cluster is an EKS cluster: the server version, EKS cluster roles,
+// node provider IDs, and where available the cluster name (aws-node CLUSTER_NAME or eksctl node labels), region
+// (service account issuer or API server host name) and account (IAM roles of aws-node or aws-auth).
+func DiscoverEksCluster(ctx context.Context, clientset *kubernetes.Clientset) (info EksClusterInfo, err error) {
+	serverVersion, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return info, readError("get", "server version", err)
+	}
+	info.GitVersion = serverVersion.GitVersion
+
+	clusterRoles, err := clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return info, readError("list", "ClusterRoles", err)
+	}
+	for _, clusterRole := range clusterRoles.Items {
+		if strings.HasPrefix(clusterRole.Name, eksClusterRolePrefix) {
+			info.EksClusterRoles++
+		}
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return info, readError("list", "Nodes", err)
+	}
+	for _, node := range nodes.Items {
+		if !strings.HasPrefix(node.Spec.ProviderID, awsProviderIdPrefix) {
+			info.NonAwsNodes = append(info.NonAwsNodes, node.Name)
+		}
+		if info.ClusterName == "" {
+			info.ClusterName = node.Labels[eksctlClusterNameLabelName]
+		}
+	}
+
+	clusterName, awsNodeExists, err := discoverAwsNodeClusterName(ctx, clientset)
+	if err != nil {
+		return info, err
+	}
+	if clusterName != "" {
+		info.ClusterName = clusterName
+	}
+	if !awsNodeExists {
+		info.AwsNodeRemoved, err = LedgerRecords(ctx, clientset, auditActionRemoved, AwsCniObjects[0])
+		if err != nil {
+			return info, err
+		}
+	}
+
+	info.Region = discoverEksRegion(ctx, clientset)
+
+	info.Account, err = discoverEksAccount(ctx, clientset)
+	if err != nil {
+		return info, err
+	}
+
+	return info, nil
+}
+
+// VerifyEksCluster discovers the cluster and returns every reason for which it can't be confirmed to be the expected
+// EKS cluster. knownArn is the ARN of the cluster from the EKS API or pinned in state, which stands in for what can't
+// be discovered once aws-node is gone.
+func VerifyEksCluster(ctx context.Context, clientset *kubernetes.Clientset, knownArn string, expectedClusterName string, expectedClusterArn string) (info EksClusterInfo, problems []string, err error) {
+	info, err = DiscoverEksCluster(ctx, clientset)
+	if err != nil {
+		return info, nil, err
+	}
+	info.UseClusterArn(knownArn)
+	return info, info.Problems(expectedClusterName, expectedClusterArn), nil
+}
+
+// discoverAwsNodeClusterName returns CLUSTER_NAME of the aws-node daemonset, and whether the daemonset exists.
+func discoverAwsNodeClusterName(ctx context.Context, clientset *kubernetes.Clientset) (clusterName string, exists bool, err error) {
+	daemonset, err := clientset.AppsV1().DaemonSets("kube-system").Get(ctx, "aws-node", metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return "", false, nil
+	case err != nil:
+		return "", false, readError("get", "DaemonSet kube-system/aws-node", err)
+	}
+
+	for _, container := range daemonset.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name == "CLUSTER_NAME" && env.Value != "" {
+				clusterName = env.Value
+			}
+		}
+	}
+	return clusterName, true, nil
+}
+
+// discoverEksRegion returns the region of the cluster from its service account issuer or API server host name, empty
+// when neither is an EKS one.
+func discoverEksRegion(ctx context.Context, clientset *kubernetes.Clientset) string {
+	// The issuer of an EKS cluster is https://oidc.eks.<region>.amazonaws.com/id/<id>, the API server host name is
+	// <id>.<zone>.<region>.eks.amazonaws.com. Neither is guaranteed to be readable, so failures are ignored.
+	openidConfiguration := struct {
+		Issuer string `json:"issuer"`
+	}{}
+	data, err := clientset.Discovery().RESTClient().Get().AbsPath("/.well-known/openid-configuration").DoRaw(ctx)
+	if err == nil && json.Unmarshal(data, &openidConfiguration) == nil {
+		if strings.HasPrefix(openidConfiguration.Issuer, "https://oidc.eks.") {
+			return strings.SplitN(strings.TrimPrefix(openidConfiguration.Issuer, "https://oidc.eks."), ".", 2)[0]
+		}
+	}
+
+	matches := eksRegionRegexp.FindStringSubmatch(clientset.Discovery().RESTClient().Get().URL().Host)
+	if matches != nil {
+		return matches[1]
+	}
+	return ""
+}
+
+// discoverEksAccount returns the account of the IAM role used by aws-node or of the node roles mapped in aws-auth, empty
+// when neither names one.
+func discoverEksAccount(ctx context.Context, clientset *kubernetes.Clientset) (account string, err error) {
+	serviceAccount, err := clientset.CoreV1().ServiceAccounts(clusterIdentityNamespace).Get(ctx, "aws-node", metav1.GetOptions{})
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return "", readError("get", fmt.Sprintf("ServiceAccount %s/aws-node", clusterIdentityNamespace), err)
+	case err == nil:
+		matches := iamAccountRegexp.FindStringSubmatch(serviceAccount.Annotations[irsaRoleArnAnnotationName])
+		if matches != nil {
+			return matches[1], nil
+		}
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps(clusterIdentityNamespace).Get(ctx, awsAuthConfigMapName, metav1.GetOptions{})
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return "", readError("get", fmt.Sprintf("ConfigMap %s/%s", clusterIdentityNamespace, awsAuthConfigMapName), err)
+	case err == nil:
+		return nodeRoleAccount(configMap.Data["mapRoles"]), nil
+	}
+
+	return "", nil
+}
+
+// nodeRoleAccount returns the account of the node roles in the mapRoles of aws-auth, which live in the account of the
+// cluster unlike the other roles that may be mapped from anywhere. It is empty when no node role is mapped or they are
+// in different accounts.
+func nodeRoleAccount(mapRoles string) (account string) {
+	var roles []struct {
+		RoleArn string   `json:"rolearn"`
+		Groups  []string `json:"groups"`
+	}
+	if yaml.Unmarshal([]byte(mapRoles), &roles) != nil {
+		return ""
+	}
+
+	for _, role := range roles {
+		isNodeRole := false
+		for _, group := range role.Groups {
+			isNodeRole = isNodeRole || group == awsAuthNodesGroup
+		}
+		matches := iamAccountRegexp.FindStringSubmatch(role.RoleArn)
+		if !isNodeRole || matches == nil {
+			continue
+		}
+		if account != "" && account != matches[1] {
+			return ""
+		}
+		account = matches[1]
+	}
+	return account
+}
+
+// Problems returns every reason for which the cluster can't be confirmed to be the expected EKS cluster. An empty
+// result means the cluster is verified. A name or account that can't be determined because cleaneks removed aws-node
+// isn't a problem, as the cluster was verified before aws-node was removed.
+func (info EksClusterInfo) Problems(expectedClusterName string, expectedClusterArn string) (problems []string) {
+	if !strings.Contains(info.GitVersion, eksGitVersionMarker) {
+		problems = append(problems, fmt.Sprintf("server version %q is not an EKS version (no %q marker)", info.GitVersion, eksGitVersionMarker))
+	}
+
+	if info.EksClusterRoles == 0 {
+		problems = append(problems, fmt.Sprintf("no %q prefixed cluster roles exist, EKS creates these in every cluster", eksClusterRolePrefix))
+	}
+
+	if len(info.NonAwsNodes) > 0 {
+		problems = append(problems, fmt.Sprintf("nodes %s do not have an %q provider ID", strings.Join(info.NonAwsNodes, ", "), awsProviderIdPrefix))
+	}
+
+	if expectedClusterArn != "" {
+		region, account, name, err := ParseEksClusterArn(expectedClusterArn)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			if expectedClusterName != "" && expectedClusterName != name {
+				problems = append(problems, fmt.Sprintf("expected cluster name %q does not match expected cluster ARN %q", expectedClusterName, expectedClusterArn))
+			}
+			expectedClusterName = name
+
+			switch {
+			case info.Region == "":
+				problems = append(problems, fmt.Sprintf("the region of the cluster could not be determined to compare with %q", expectedClusterArn))
+			case info.Region != region:
+				problems = append(problems, fmt.Sprintf("cluster is in region %q but expected cluster ARN %q is in region %q", info.Region, expectedClusterArn, region))
+			}
+
+			switch {
+			case info.Account == "" && info.AwsNodeRemoved:
+				// The account was compared before aws-node was removed
+			case info.Account == "":
+				problems = append(problems, fmt.Sprintf("the AWS account of the cluster could not be determined to compare with %q (looked for the IAM roles of aws-node and aws-auth)", expectedClusterArn))
+			case info.Account != account:
+				problems = append(problems, fmt.Sprintf("cluster is in account %q but expected cluster ARN %q is in account %q", info.Account, expectedClusterArn, account))
+			}
+		}
+	}
+
+	if expectedClusterName != "" {
+		switch {
+		case info.ClusterName == "" && info.AwsNodeRemoved:
+			// The name was compared before aws-node was removed
+		case info.ClusterName == "":
+			problems = append(problems, fmt.Sprintf("the name of the cluster could not be determined to compare with %q (looked for CLUSTER_NAME on aws-node and the %s node label)", expectedClusterName, eksctlClusterNameLabelName))
+		case info.ClusterName != expectedClusterName:
+			problems = append(problems, fmt.Sprintf("cluster is named %q but %q was expected", info.ClusterName, expectedClusterName))
+		}
+	}
+
+	return problems
+}
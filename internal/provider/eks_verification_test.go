@@ -0,0 +1,249 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEksClusterArn(t *testing.T) {
+	tests := []struct {
+		arn         string
+		wantRegion  string
+		wantAccount string
+		wantName    string
+		wantErr     bool
+	}{
+		{arn: "arn:aws:eks:eu-west-1:123456789012:cluster/prod", wantRegion: "eu-west-1", wantAccount: "123456789012", wantName: "prod"},
+		{arn: "arn:aws-us-gov:eks:us-gov-west-1:123456789012:cluster/my_cluster-1", wantRegion: "us-gov-west-1", wantAccount: "123456789012", wantName: "my_cluster-1"},
+		{arn: "arn:aws-cn:eks:cn-north-1:123456789012:cluster/Prod", wantRegion: "cn-north-1", wantAccount: "123456789012", wantName: "Prod"},
+		{arn: "", wantErr: true},
+		{arn: "prod", wantErr: true},
+		{arn: "arn:aws:ecs:eu-west-1:123456789012:cluster/prod", wantErr: true},
+		{arn: "arn:aws:eks:eu-west-1:12345:cluster/prod", wantErr: true},
+		{arn: "arn:aws:eks:eu-west-1:123456789012:nodegroup/prod/workers/1", wantErr: true},
+		{arn: "arn:aws:eks:eu-west-1:123456789012:cluster/-prod", wantErr: true},
+		{arn: "arn:aws:eks:eu-west-1:123456789012:cluster/", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.arn, func(t *testing.T) {
+			region, account, name, err := ParseEksClusterArn(test.arn)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseEksClusterArn() = %q, %q, %q, want an error", region, account, name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEksClusterArn() error: %s", err)
+			}
+			if region != test.wantRegion || account != test.wantAccount || name != test.wantName {
+				t.Errorf("ParseEksClusterArn() = %q, %q, %q, want %q, %q, %q", region, account, name, test.wantRegion, test.wantAccount, test.wantName)
+			}
+		})
+	}
+}
+
+func TestEksClusterInfoProblems(t *testing.T) {
+	eks := EksClusterInfo{
+		GitVersion:      "v1.29.4-eks-036c24b",
+		EksClusterRoles: 12,
+		ClusterName:     "prod",
+		Region:          "eu-west-1",
+		Account:         "123456789012",
+	}
+	with := func(change func(info *EksClusterInfo)) EksClusterInfo {
+		info := eks
+		change(&info)
+		return info
+	}
+	const prodArn = "arn:aws:eks:eu-west-1:123456789012:cluster/prod"
+
+	tests := []struct {
+		name                string
+		info                EksClusterInfo
+		expectedClusterName string
+		expectedClusterArn  string
+		want                []string
+	}{
+		{name: "eks cluster", info: eks},
+		{name: "expected name", info: eks, expectedClusterName: "prod"},
+		{name: "expected arn", info: eks, expectedClusterArn: prodArn},
+		{name: "expected name and arn", info: eks, expectedClusterName: "prod", expectedClusterArn: prodArn},
+		{
+			name: "not eks",
+			info: EksClusterInfo{GitVersion: "v1.29.4+k3s1", NonAwsNodes: []string{"node-a", "node-b"}},
+			want: []string{
+				`server version "v1.29.4+k3s1" is not an EKS version (no "-eks-" marker)`,
+				`no "eks:" prefixed cluster roles exist, EKS creates these in every cluster`,
+				`nodes node-a, node-b do not have an "aws:///" provider ID`,
+			},
+		},
+		{
+			name:                "other name",
+			info:                eks,
+			expectedClusterName: "staging",
+			want:                []string{`cluster is named "prod" but "staging" was expected`},
+		},
+		{
+			name:                "unknown name",
+			info:                with(func(info *EksClusterInfo) { info.ClusterName = "" }),
+			expectedClusterName: "prod",
+			want:                []string{`the name of the cluster could not be determined to compare with "prod" (looked for CLUSTER_NAME on aws-node and the alpha.eksctl.io/cluster-name node label)`},
+		},
+		{
+			name:               "arn of other cluster",
+			info:               eks,
+			expectedClusterArn: "arn:aws:eks:eu-west-1:123456789012:cluster/staging",
+			want:               []string{`cluster is named "prod" but "staging" was expected`},
+		},
+		{
+			name:               "arn in other region",
+			info:               eks,
+			expectedClusterArn: "arn:aws:eks:us-east-1:123456789012:cluster/prod",
+			want:               []string{`cluster is in region "eu-west-1" but expected cluster ARN "arn:aws:eks:us-east-1:123456789012:cluster/prod" is in region "us-east-1"`},
+		},
+		{
+			name:               "unknown region",
+			info:               with(func(info *EksClusterInfo) { info.Region = "" }),
+			expectedClusterArn: prodArn,
+			want:               []string{`the region of the cluster could not be determined to compare with "arn:aws:eks:eu-west-1:123456789012:cluster/prod"`},
+		},
+		{
+			name:               "arn in other account",
+			info:               eks,
+			expectedClusterArn: "arn:aws:eks:eu-west-1:210987654321:cluster/prod",
+			want:               []string{`cluster is in account "123456789012" but expected cluster ARN "arn:aws:eks:eu-west-1:210987654321:cluster/prod" is in account "210987654321"`},
+		},
+		{
+			name:               "unknown account",
+			info:               with(func(info *EksClusterInfo) { info.Account = "" }),
+			expectedClusterArn: prodArn,
+			want:               []string{`the AWS account of the cluster could not be determined to compare with "arn:aws:eks:eu-west-1:123456789012:cluster/prod" (looked for the IAM roles of aws-node and aws-auth)`},
+		},
+		{
+			// Only eksctl clusters have the name on the nodes as well
+			name: "aws-node removed by cleaneks",
+			info: with(func(info *EksClusterInfo) {
+				info.ClusterName = ""
+				info.Account = ""
+				info.AwsNodeRemoved = true
+			}),
+			expectedClusterName: "prod",
+			expectedClusterArn:  prodArn,
+		},
+		{
+			name: "aws-node removed by cleaneks in other region",
+			info: with(func(info *EksClusterInfo) {
+				info.ClusterName = ""
+				info.Account = ""
+				info.AwsNodeRemoved = true
+			}),
+			expectedClusterArn: "arn:aws:eks:us-east-1:123456789012:cluster/prod",
+			want:               []string{`cluster is in region "eu-west-1" but expected cluster ARN "arn:aws:eks:us-east-1:123456789012:cluster/prod" is in region "us-east-1"`},
+		},
+		{
+			name: "aws-node missing",
+			info: with(func(info *EksClusterInfo) {
+				info.ClusterName = ""
+				info.Account = ""
+			}),
+			expectedClusterArn: prodArn,
+			want: []string{
+				`the AWS account of the cluster could not be determined to compare with "arn:aws:eks:eu-west-1:123456789012:cluster/prod" (looked for the IAM roles of aws-node and aws-auth)`,
+				`the name of the cluster could not be determined to compare with "prod" (looked for CLUSTER_NAME on aws-node and the alpha.eksctl.io/cluster-name node label)`,
+			},
+		},
+		{
+			name:                "name and arn disagree",
+			info:                eks,
+			expectedClusterName: "staging",
+			expectedClusterArn:  prodArn,
+			want:                []string{`expected cluster name "staging" does not match expected cluster ARN "arn:aws:eks:eu-west-1:123456789012:cluster/prod"`},
+		},
+		{
+			name:                "invalid arn",
+			info:                eks,
+			expectedClusterName: "staging",
+			expectedClusterArn:  "prod",
+			want: []string{
+				`"prod" is not an EKS cluster ARN`,
+				`cluster is named "prod" but "staging" was expected`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.info.Problems(test.expectedClusterName, test.expectedClusterArn)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Problems()\n got: %q\nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEksClusterInfoUseClusterArn(t *testing.T) {
+	const pinnedArn = "arn:aws:eks:eu-west-1:123456789012:cluster/prod"
+
+	tests := []struct {
+		name string
+		info EksClusterInfo
+		arn  string
+		want EksClusterInfo
+	}{
+		{
+			name: "aws-node removed",
+			info: EksClusterInfo{Region: "eu-west-1", AwsNodeRemoved: true},
+			arn:  pinnedArn,
+			want: EksClusterInfo{ClusterName: "prod", Region: "eu-west-1", Account: "123456789012", AwsNodeRemoved: true},
+		},
+		{
+			name: "discovered wins",
+			info: EksClusterInfo{ClusterName: "staging", Region: "us-east-1", Account: "210987654321"},
+			arn:  pinnedArn,
+			want: EksClusterInfo{ClusterName: "staging", Region: "us-east-1", Account: "210987654321"},
+		},
+		{
+			name: "no arn",
+			info: EksClusterInfo{Region: "eu-west-1"},
+			want: EksClusterInfo{Region: "eu-west-1"},
+		},
+		{
+			name: "invalid arn",
+			info: EksClusterInfo{Region: "eu-west-1"},
+			arn:  "prod",
+			want: EksClusterInfo{Region: "eu-west-1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info := test.info
+			info.UseClusterArn(test.arn)
+			if !reflect.DeepEqual(info, test.want) {
+				t.Errorf("UseClusterArn()\n got: %+v\nwant: %+v", info, test.want)
+			}
+		})
+	}
+}
+
+func TestEksClusterInfoArn(t *testing.T) {
+	tests := []struct {
+		info EksClusterInfo
+		want string
+	}{
+		{info: EksClusterInfo{ClusterName: "prod", Region: "eu-west-1", Account: "123456789012"}, want: "arn:aws:eks:eu-west-1:123456789012:cluster/prod"},
+		{info: EksClusterInfo{ClusterName: "prod", Region: "cn-north-1", Account: "123456789012"}, want: "arn:aws-cn:eks:cn-north-1:123456789012:cluster/prod"},
+		{info: EksClusterInfo{ClusterName: "prod", Region: "us-gov-west-1", Account: "123456789012"}, want: "arn:aws-us-gov:eks:us-gov-west-1:123456789012:cluster/prod"},
+		{info: EksClusterInfo{Region: "eu-west-1", Account: "123456789012"}},
+		{info: EksClusterInfo{ClusterName: "prod", Account: "123456789012"}},
+		{info: EksClusterInfo{ClusterName: "prod", Region: "eu-west-1"}},
+	}
+
+	for _, test := range tests {
+		if got := test.info.Arn(); got != test.want {
+			t.Errorf("%+v.Arn() = %q, want %q", test.info, got, test.want)
+		}
+	}
+}
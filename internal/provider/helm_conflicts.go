@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const conflictPolicyFail string = "fail"
+const conflictPolicyTakeover string = "takeover"
+const conflictPolicySkip string = "skip"
+
+const helmReleaseSecretPrefix string = "sh.helm.release.v1."
+
+// CorednsHelmObjects are the objects that are adopted into the CoreDNS Helm release.
+var CorednsHelmObjects = []ClusterObject{
+	{Kind: "Deployment", Namespace: "kube-system", Name: "coredns"},
+	{Kind: "Service", Namespace: "kube-system", Name: "kube-dns"},
+	{Kind: "ServiceAccount", Namespace: "kube-system", Name: "coredns"},
+	{Kind: "ConfigMap", Namespace: "kube-system", Name: "coredns"},
+	{Kind: "PodDisruptionBudget", Namespace: "kube-system", Name: "coredns"},
+}
+
+// HelmConflict is an object that can't be adopted into the target release without taking it from its current owner.
+type HelmConflict struct {
+	Object ClusterObject
+	Owner  string
+}
+
+func (c HelmConflict) String() string {
+	return fmt.Sprintf("%s: %s", c.Object, c.Owner)
+}
+
+// HelmReleaseSecrets lists the Helm storage secrets of a release, which exist once the release has been installed.
+func HelmReleaseSecrets(ctx context.Context, clientset *kubernetes.Clientset, namespace string, releaseName string) (names []string, err error) {
+	selector := labels.SelectorFromSet(labels.Set{"owner": "helm", "name": releaseName})
+	secrets, err := clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, secret := range secrets.Items {
+		if strings.HasPrefix(secret.Name, helmReleaseSecretPrefix+releaseName+".") {
+			names = append(names, secret.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// HelmConflicts finds objects that are owned by a different Helm release, and objects that would be added to an
+// already installed target release without being part of it.
+func HelmConflicts(ctx context.Context, clientset *kubernetes.Clientset, objects []ClusterObject) (conflicts []HelmConflict, err error) {
+	releaseSecrets, err := HelmReleaseSecrets(ctx, clientset, helmReleaseNamespaceAnnotationValue, helmReleaseNameAnnotationValue)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, object := range objects {
+		objectMeta, err := GetObjectMeta(ctx, clientset, object)
+		if err != nil {
+			return nil, err
+		}
+		if objectMeta == nil {
+			continue
+		}
+
+		releaseName, hasReleaseName := objectMeta.Annotations[helmReleaseNameAnnotationName]
+		releaseNamespace := objectMeta.Annotations[helmReleaseNamespaceAnnotationName]
+		switch {
+		case hasReleaseName && (releaseName != helmReleaseNameAnnotationValue || (releaseNamespace != "" && releaseNamespace != helmReleaseNamespaceAnnotationValue)):
+			conflicts = append(conflicts, HelmConflict{
+				Object: object,
+				Owner:  fmt.Sprintf("owned by Helm release %s/%s", releaseNamespace, releaseName),
+			})
+		case !hasReleaseName && len(releaseSecrets) > 0:
+			conflicts = append(conflicts, HelmConflict{
+				Object: object,
+				Owner:  fmt.Sprintf("Helm release %s/%s is already installed (%s) and does not own it", helmReleaseNamespaceAnnotationValue, helmReleaseNameAnnotationValue, strings.Join(releaseSecrets, ", ")),
+			})
+		}
+	}
+
+	return conflicts, nil
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const fargateComputeTypeLabelName string = "eks.amazonaws.com/compute-type"
+const fargateComputeTypeLabelValue string = "fargate"
+
+// KubeProxyReplacementCheck describes how to detect a running kube-proxy replacement: an optional config map entry
+// that enables the replacement and a DaemonSet that has to have a ready pod on every node.
+type KubeProxyReplacementCheck struct {
+	Name               string
+	ConfigMapNamespace string
+	ConfigMapName      string
+	ConfigMapKey       string
+	ExpectedValues     []string
+	DaemonsetNamespace string
+	DaemonsetName      string
+}
+
+// CiliumKubeProxyReplacementCheck detects Cilium running with kube-proxy replacement enabled.
+var CiliumKubeProxyReplacementCheck = KubeProxyReplacementCheck{
+	Name:               "cilium",
+	ConfigMapNamespace: "kube-system",
+	ConfigMapName:      "cilium-config",
+	ConfigMapKey:       "kube-proxy-replacement",
+	ExpectedValues:     []string{"true", "strict"},
+	DaemonsetNamespace: "kube-system",
+	DaemonsetName:      "cilium",
+}
+
+// KubeProxyReplacementActive runs a check against the cluster. When the replacement is not active the reason describes
+// what is missing.
+func KubeProxyReplacementActive(ctx context.Context, clientset *kubernetes.Clientset, check KubeProxyReplacementCheck) (active bool, reason string, err error) {
+	if check.ConfigMapName != "" {
+		configMap, err := clientset.CoreV1().ConfigMaps(check.ConfigMapNamespace).Get(ctx, check.ConfigMapName, metav1.GetOptions{})
+		switch {
+		case err != nil && !errors.IsNotFound(err):
+			return false, "", err
+		case errors.IsNotFound(err):
+			return false, fmt.Sprintf("config map %s/%s not found", check.ConfigMapNamespace, check.ConfigMapName), nil
+		}
+
+		value, ok := configMap.Data[check.ConfigMapKey]
+		if !ok {
+			return false, fmt.Sprintf("config map %s/%s has no %s key", check.ConfigMapNamespace, check.ConfigMapName, check.ConfigMapKey), nil
+		}
+
+		enabled := false
+		for _, expectedValue := range check.ExpectedValues {
+			if strings.EqualFold(strings.TrimSpace(value), expectedValue) {
+				enabled = true
+				break
+			}
+		}
+		if !enabled {
+			return false, fmt.Sprintf("config map %s/%s has %s=%q, expected one of %q", check.ConfigMapNamespace, check.ConfigMapName, check.ConfigMapKey, value, check.ExpectedValues), nil
+		}
+	}
+
+	daemonset, err := clientset.AppsV1().DaemonSets(check.DaemonsetNamespace).Get(ctx, check.DaemonsetName, metav1.GetOptions{})
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return false, "", err
+	case errors.IsNotFound(err):
+		return false, fmt.Sprintf("daemonset %s/%s not found", check.DaemonsetNamespace, check.DaemonsetName), nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(daemonset.Spec.Selector)
+	if err != nil {
+		return false, "", err
+	}
+
+	pods, err := clientset.CoreV1().Pods(check.DaemonsetNamespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return false, "", err
+	}
+
+	readyNodes := map[string]bool{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" && PodIsReady(pod) {
+			readyNodes[pod.Spec.NodeName] = true
+		}
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, "", err
+	}
+
+	var missingNodes []string
+	for _, node := range nodes.Items {
+		// Fargate nodes never run DaemonSets, they don't use kube-proxy either
+		if node.Labels[fargateComputeTypeLabelName] == fargateComputeTypeLabelValue {
+			continue
+		}
+		if !readyNodes[node.Name] {
+			missingNodes = append(missingNodes, node.Name)
+		}
+	}
+
+	if len(missingNodes) > 0 {
+		sort.Strings(missingNodes)
+		return false, fmt.Sprintf("daemonset %s/%s has no ready pod on nodes %s", check.DaemonsetNamespace, check.DaemonsetName, strings.Join(missingNodes, ", ")), nil
+	}
+
+	return true, "", nil
+}
+
+// PodIsReady returns true when the pod reports the Ready condition as True.
+func PodIsReady(pod corev1.Pod) bool {
+	for _, podCondition := range pod.Status.Conditions {
+		if podCondition.Type == corev1.PodReady {
+			return podCondition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// ActiveKubeProxyReplacement runs the checks in order and returns the name of the first replacement that is active.
+// When none is, the reasons describe what each of them is missing.
+func ActiveKubeProxyReplacement(ctx context.Context, clientset *kubernetes.Clientset, checks []KubeProxyReplacementCheck) (replacement string, reasons []string, err error) {
+	for _, check := range checks {
+		active, reason, err := KubeProxyReplacementActive(ctx, clientset, check)
+		if err != nil {
+			return "", reasons, fmt.Errorf("%s: %w", check.Name, err)
+		}
+		if active {
+			return check.Name, nil, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %s", check.Name, reason))
+	}
+	return "", reasons, nil
+}
@@ -0,0 +1,251 @@
+package provider
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const leaseLockNamespace string = "kube-system"
+const leaseLockDuration = 60 * time.Second
+const leaseLockRenewInterval = 20 * time.Second
+const leaseLockPollInterval = 5 * time.Second
+
+var errLeaseTakenOver = goerrors.New("lease was taken over by another holder")
+
+// LeaseLock is a coordination.k8s.io/v1 Lease held by this process. It is renewed in the background until released.
+type LeaseLock struct {
+	clientset *kubernetes.Clientset
+	namespace string
+	name      string
+	identity  string
+
+	stop chan struct{}
+	done chan struct{}
+
+	mutex sync.Mutex
+	lost  error
+	// lostSignal is closed once the lease is found to be held by somebody else
+	lostSignal chan struct{}
+}
+
+// LeaseLockIdentity returns a holder identity that tells people where a lock is held from.
+func LeaseLockIdentity(version string) string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("cleaneks/%s %s/%d/%s", version, hostname, os.Getpid(), rand.String(5))
+}
+
+// AcquireLeaseLock takes the lease, waiting up to timeout for the current holder to release it or let it expire. When
+// it can't be taken the error names the holder.
+func AcquireLeaseLock(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, identity string, timeout time.Duration) (lock *LeaseLock, err error) {
+	holder := ""
+	condition := func(ctx context.Context) (bool, error) {
+		acquired, currentHolder, err := tryAcquireLease(ctx, clientset, namespace, name, identity)
+		holder = currentHolder
+		switch {
+		case errors.IsConflict(err) || errors.IsAlreadyExists(err):
+			// Somebody else got there first, look again on the next poll
+			return false, nil
+		case err != nil:
+			return false, err
+		default:
+			return acquired, nil
+		}
+	}
+
+	// Try once outside of the poll, so that a zero timeout still takes a free lease
+	acquired, err := condition(ctx)
+	if err == nil && !acquired {
+		err = wait.PollUntilContextTimeout(ctx, leaseLockPollInterval, timeout, false, condition)
+	}
+	if err != nil {
+		if wait.Interrupted(err) {
+			return nil, fmt.Errorf("lease %s/%s is held by %s, gave up after %s", namespace, name, holder, timeout)
+		}
+		return nil, err
+	}
+
+	lock = &LeaseLock{
+		clientset:  clientset,
+		namespace:  namespace,
+		name:       name,
+		identity:   identity,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+		lostSignal: make(chan struct{}),
+	}
+	go lock.renew()
+	return lock, nil
+}
+
+// tryAcquireLease creates the lease, or takes it over when it is free or expired. It returns the holder when it is
+// held by someone else.
+func tryAcquireLease(ctx context.Context, clientset *kubernetes.Clientset, namespace string, name string, identity string) (acquired bool, holder string, err error) {
+	now := metav1.NewMicroTime(time.Now())
+	durationSeconds := int32(leaseLockDuration.Seconds())
+
+	lease, err := clientset.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return false, "", err
+	case errors.IsNotFound(err):
+		_, err = clientset.CoordinationV1().Leases(namespace).Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &identity,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		return err == nil, "", err
+	}
+
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" && *lease.Spec.HolderIdentity != identity && !leaseExpired(lease) {
+		holder = *lease.Spec.HolderIdentity
+		if lease.Spec.RenewTime != nil {
+			holder = fmt.Sprintf("%s (last renewed %s)", holder, lease.Spec.RenewTime.Format(time.RFC3339))
+		}
+		return false, holder, nil
+	}
+
+	lease.Spec.HolderIdentity = &identity
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+	_, err = clientset.CoordinationV1().Leases(namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	return err == nil, "", err
+}
+
+// leaseExpired returns true when the holder hasn't renewed the lease within its duration.
+func leaseExpired(lease *coordinationv1.Lease) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second).Before(time.Now())
+}
+
+// renew keeps the lease alive until the lock is released.
+func (l *LeaseLock) renew() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(leaseLockRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), leaseLockRenewInterval)
+			err := l.update(ctx, func(lease *coordinationv1.Lease) {
+				now := metav1.NewMicroTime(time.Now())
+				lease.Spec.RenewTime = &now
+			})
+			cancel()
+			// A failed renewal is retried on the next tick, the lease only counts as lost once somebody else holds it
+			if goerrors.Is(err, errLeaseTakenOver) {
+				l.markLost(err)
+				return
+			}
+		}
+	}
+}
+
+// update changes the lease, as long as it is still held by this process.
+func (l *LeaseLock) update(ctx context.Context, change func(lease *coordinationv1.Lease)) error {
+	lease, err := l.clientset.CoordinationV1().Leases(l.namespace).Get(ctx, l.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := l.checkHolder(lease); err != nil {
+		return err
+	}
+
+	change(lease)
+	_, err = l.clientset.CoordinationV1().Leases(l.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// checkHolder returns errLeaseTakenOver, naming the new holder, when the lease isn't held by this process any more.
+func (l *LeaseLock) checkHolder(lease *coordinationv1.Lease) error {
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity == l.identity {
+		return nil
+	}
+	holder := "nobody"
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" {
+		holder = *lease.Spec.HolderIdentity
+	}
+	return fmt.Errorf("%s/%s is now held by %s: %w", l.namespace, l.name, holder, errLeaseTakenOver)
+}
+
+// markLost records that the lease was taken over and signals Lost, only the first loss is kept.
+func (l *LeaseLock) markLost(err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.lost == nil {
+		l.lost = err
+		close(l.lostSignal)
+	}
+}
+
+// Lost returns a channel that is closed once the lease is found to be held by somebody else, see Err.
+func (l *LeaseLock) Lost() <-chan struct{} {
+	return l.lostSignal
+}
+
+// Err returns why the lease was lost, nil while it is still held.
+func (l *LeaseLock) Err() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.lost
+}
+
+// Check reads the lease and returns an error naming the new holder when it was taken over, so that a run stops before
+// it changes anything else. Renewals only notice a take over every leaseLockRenewInterval.
+func (l *LeaseLock) Check(ctx context.Context) error {
+	if err := l.Err(); err != nil {
+		return err
+	}
+	lease, err := l.clientset.CoordinationV1().Leases(l.namespace).Get(ctx, l.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := l.checkHolder(lease); err != nil {
+		l.markLost(err)
+		return err
+	}
+	return nil
+}
+
+// Release stops renewing and gives up the lease. It returns an error when the lease was lost while it was held.
+func (l *LeaseLock) Release(ctx context.Context) error {
+	close(l.stop)
+	<-l.done
+
+	if lost := l.Err(); lost != nil {
+		return fmt.Errorf("lost lease %s/%s while it was held: %w", l.namespace, l.name, lost)
+	}
+
+	return l.update(ctx, func(lease *coordinationv1.Lease) {
+		lease.Spec.HolderIdentity = nil
+		lease.Spec.AcquireTime = nil
+		lease.Spec.RenewTime = nil
+	})
+}
@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Permission is one kind of call the provider makes to the API server, as checked with a SelfSubjectAccessReview and
+// granted by an RBAC rule. Either Resource or NonResourceURL is set. An empty Namespace is cluster wide (or all
+// namespaces), an empty Name is any object, which is always the case for create as RBAC can't restrict it by name.
+type Permission struct {
+	Verb           string
+	Group          string
+	Resource       string
+	Namespace      string
+	Name           string
+	NonResourceURL string
+}
+
+func (p Permission) String() string {
+	if p.NonResourceURL != "" {
+		return fmt.Sprintf("%s %s", p.Verb, p.NonResourceURL)
+	}
+
+	resource := p.Resource
+	if p.Group != "" {
+		resource = fmt.Sprintf("%s.%s", p.Resource, p.Group)
+	}
+	switch {
+	case p.Namespace != "" && p.Name != "":
+		return fmt.Sprintf("%s %s %s/%s", p.Verb, resource, p.Namespace, p.Name)
+	case p.Namespace != "":
+		return fmt.Sprintf("%s %s in %s", p.Verb, resource, p.Namespace)
+	case p.Name != "":
+		return fmt.Sprintf("%s %s %s", p.Verb, resource, p.Name)
+	default:
+		return fmt.Sprintf("%s %s", p.Verb, resource)
+	}
+}
+
+// GroupResource returns the API group and resource of a kind of object.
+func (o ClusterObject) GroupResource() (group string, resource string) {
+	switch o.Kind {
+	case "DaemonSet":
+		return "apps", "daemonsets"
+	case "Deployment":
+		return "apps", "deployments"
+	case "Service":
+		return "", "services"
+	case "ServiceAccount":
+		return "", "serviceaccounts"
+	case "ConfigMap":
+		return "", "configmaps"
+	case "PodDisruptionBudget":
+		return "policy", "poddisruptionbudgets"
+	case "Node":
+		return "", "nodes"
+	default:
+		return "", ""
+	}
+}
+
+// objectPermissions returns a permission for each verb on each object.
+func objectPermissions(objects []ClusterObject, verbs ...string) (permissions []Permission) {
+	for _, object := range objects {
+		group, resource := object.GroupResource()
+		for _, verb := range verbs {
+			name := object.Name
+			if verb == "create" {
+				name = ""
+			}
+			permissions = append(permissions, Permission{Verb: verb, Group: group, Resource: resource, Namespace: object.Namespace, Name: name})
+		}
+	}
+	return permissions
+}
+
+// identityPermissions are the calls made by GetClusterIdentity to read the cluster identity.
+func identityPermissions() []Permission {
+	return []Permission{
+		{Verb: "get", Resource: "namespaces", Name: clusterIdentityNamespace},
+		{Verb: "get", Group: "apps", Resource: "daemonsets", Namespace: "kube-system", Name: "aws-node"},
+		{Verb: "get", Resource: "serviceaccounts", Namespace: clusterIdentityNamespace, Name: "aws-node"},
+		{Verb: "get", Resource: "configmaps", Namespace: clusterIdentityNamespace, Name: awsAuthConfigMapName},
+		{Verb: "get", NonResourceURL: "/.well-known/openid-configuration"},
+	}
+}
+
+// eksVerificationPermissions are the calls made by DiscoverEksCluster.
+func eksVerificationPermissions() []Permission {
+	return []Permission{
+		{Verb: "get", NonResourceURL: "/version"},
+		{Verb: "list", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+		{Verb: "list", Resource: "nodes"},
+		{Verb: "get", Group: "apps", Resource: "daemonsets", Namespace: "kube-system", Name: "aws-node"},
+		{Verb: "get", Resource: "configmaps", Namespace: auditLedgerNamespace, Name: auditLedgerConfigMapName},
+		{Verb: "get", Resource: "serviceaccounts", Namespace: clusterIdentityNamespace, Name: "aws-node"},
+		{Verb: "get", Resource: "configmaps", Namespace: clusterIdentityNamespace, Name: awsAuthConfigMapName},
+		{Verb: "get", NonResourceURL: "/.well-known/openid-configuration"},
+	}
+}
+
+// lockPermissions are the calls made to take and release the lock_lease_name Lease.
+func lockPermissions(options JobOptions) []Permission {
+	return []Permission{
+		{Verb: "get", Group: "coordination.k8s.io", Resource: "leases", Namespace: leaseLockNamespace, Name: options.LockLeaseName},
+		{Verb: "create", Group: "coordination.k8s.io", Resource: "leases", Namespace: leaseLockNamespace},
+		{Verb: "update", Group: "coordination.k8s.io", Resource: "leases", Namespace: leaseLockNamespace, Name: options.LockLeaseName},
+	}
+}
+
+// waitForPermissions are the calls made to check the wait_for conditions.
+func waitForPermissions(options JobOptions) (permissions []Permission) {
+	for _, condition := range options.WaitFor {
+		switch {
+		case condition.Kind == waitForKindNode && condition.Name == "":
+			permissions = append(permissions, Permission{Verb: "list", Resource: "nodes"})
+		case condition.Kind == waitForKindNode:
+			permissions = append(permissions, Permission{Verb: "get", Resource: "nodes", Name: condition.Name})
+		default:
+			permissions = append(permissions, objectPermissions([]ClusterObject{{Kind: condition.Kind, Namespace: condition.Namespace, Name: condition.Name}}, "get")...)
+		}
+	}
+	return permissions
+}
+
+// auditPermissions are the calls made to snapshot the objects and, except during a dry run, to record an event on every
+// changed object and the ledger entries.
+func auditPermissions(options JobOptions) []Permission {
+	permissions := objectPermissions(AuditedObjects, "get")
+	if options.DryRun {
+		return permissions
+	}
+	return append(permissions,
+		Permission{Verb: "create", Resource: "events", Namespace: auditLedgerNamespace},
+		Permission{Verb: "get", Resource: "configmaps", Namespace: auditLedgerNamespace, Name: auditLedgerConfigMapName},
+		Permission{Verb: "create", Resource: "configmaps", Namespace: auditLedgerNamespace},
+		Permission{Verb: "update", Resource: "configmaps", Namespace: auditLedgerNamespace, Name: auditLedgerConfigMapName},
+	)
+}
+
+// awsCniGuardPermissions are the calls made by AwsCniDependencies.
+func awsCniGuardPermissions() []Permission {
+	return []Permission{
+		{Verb: "get", Group: "apps", Resource: "daemonsets", Namespace: "kube-system", Name: "aws-node"},
+		{Verb: "get", Resource: "configmaps", Namespace: "kube-system", Name: awsCniConfigMapName},
+		{Verb: "list", Resource: "pods"},
+		{Verb: "list", Group: "networking.k8s.aws", Resource: "policyendpoints"},
+		{Verb: "list", Group: "networking.k8s.io", Resource: "networkpolicies"},
+		{Verb: "list", Group: "crd.k8s.amazonaws.com", Resource: "eniconfigs"},
+	}
+}
+
+// kubeProxyGuardPermissions are the calls made to look for a kube-proxy replacement.
+func kubeProxyGuardPermissions(options JobOptions) []Permission {
+	permissions := objectPermissions(KubeProxyObjects[:1], "get")
+	for _, check := range append([]KubeProxyReplacementCheck{CiliumKubeProxyReplacementCheck}, options.KubeProxyReplacementChecks...) {
+		if check.ConfigMapName != "" {
+			permissions = append(permissions, Permission{Verb: "get", Resource: "configmaps", Namespace: check.ConfigMapNamespace, Name: check.ConfigMapName})
+		}
+		permissions = append(permissions,
+			Permission{Verb: "get", Group: "apps", Resource: "daemonsets", Namespace: check.DaemonsetNamespace, Name: check.DaemonsetName},
+			Permission{Verb: "list", Resource: "pods", Namespace: check.DaemonsetNamespace},
+			Permission{Verb: "list", Resource: "nodes"},
+		)
+	}
+	return permissions
+}
+
+// helmConflictPermissions are the calls made by HelmConflicts.
+func helmConflictPermissions() []Permission {
+	return append(objectPermissions(CorednsHelmObjects, "get"),
+		Permission{Verb: "list", Resource: "secrets", Namespace: helmReleaseNamespaceAnnotationValue})
+}
+
+// RequiredPermissions returns every permission a run with the options needs: waiting for the API server, the steps it
+// runs, followed by the reads of the cluster identity and of the objects the resource reports on. Each permission is
+// listed once.
+func RequiredPermissions(options JobOptions) (permissions []Permission) {
+	if options.ReadyTimeout > 0 {
+		permissions = append(permissions, Permission{Verb: "get", NonResourceURL: "/readyz"})
+	}
+	for _, step := range JobSteps(options) {
+		permissions = append(permissions, step.Permissions...)
+	}
+	permissions = append(permissions, identityPermissions()...)
+	permissions = append(permissions, objectPermissions(options.ReadObjects, "get")...)
+
+	seen := map[Permission]bool{}
+	unique := make([]Permission, 0, len(permissions))
+	for _, permission := range permissions {
+		if !seen[permission] {
+			seen[permission] = true
+			unique = append(unique, permission)
+		}
+	}
+	return unique
+}
+
+// PermissionsToStrings describes each permission.
+func PermissionsToStrings(permissions []Permission) []string {
+	out := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		out = append(out, permission.String())
+	}
+	return out
+}
+
+// DeniedPermissions checks every permission with a SelfSubjectAccessReview and returns the ones that are not allowed.
+func DeniedPermissions(ctx context.Context, clientset *kubernetes.Clientset, permissions []Permission) (denied []Permission, err error) {
+	for _, permission := range permissions {
+		review := &authorizationv1.SelfSubjectAccessReview{}
+		if permission.NonResourceURL != "" {
+			review.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+				Path: permission.NonResourceURL,
+				Verb: permission.Verb,
+			}
+		} else {
+			review.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+				Namespace: permission.Namespace,
+				Verb:      permission.Verb,
+				Group:     permission.Group,
+				Resource:  permission.Resource,
+				Name:      permission.Name,
+			}
+		}
+
+		result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("checking %s: %w", permission, err)
+		}
+		if !result.Status.Allowed {
+			denied = append(denied, permission)
+		}
+	}
+	return denied, nil
+}
+
+// admissionGuardPermissions are the calls made to apply or delete the guard.
+func admissionGuardPermissions(name string, verbs ...string) (permissions []Permission) {
+	for _, resource := range []string{"validatingadmissionpolicies", "validatingadmissionpolicybindings"} {
+		for _, verb := range verbs {
+			permission := Permission{Verb: verb, Group: "admissionregistration.k8s.io", Resource: resource, Name: name}
+			if verb == "create" {
+				permission.Name = ""
+			}
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
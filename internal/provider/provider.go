@@ -2,9 +2,15 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/hashicorp/terraform-plugin-framework-validators/boolvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/function"
@@ -17,6 +23,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -31,31 +38,55 @@ type CleanEksProvider struct {
 	Version   string
 	clientSet *kubernetes.Clientset
 	model     CleanEksProviderModel
+
+	// awsConfig and eksCluster are loaded on first use
+	awsConfig  *aws.Config
+	eksCluster *EksCluster
 }
 
 type CleanEksProviderModel struct {
-	Host                  types.String `tfsdk:"host"`
-	Username              types.String `tfsdk:"username"`
-	Password              types.String `tfsdk:"password"`
-	Insecure              types.Bool   `tfsdk:"insecure"`
-	TLSServerName         types.String `tfsdk:"tls_server_name"`
-	ClientCertificate     types.String `tfsdk:"client_certificate"`
-	ClientKey             types.String `tfsdk:"client_key"`
-	ClusterCACertificate  types.String `tfsdk:"cluster_ca_certificate"`
-	ConfigPaths           []string     `tfsdk:"config_paths"`
-	ConfigPath            types.String `tfsdk:"config_path"`
-	ConfigContext         types.String `tfsdk:"config_context"`
-	ConfigContextAuthInfo types.String `tfsdk:"config_context_auth_info"`
-	ConfigContextCluster  types.String `tfsdk:"config_context_cluster"`
-	Token                 types.String `tfsdk:"token"`
-	ProxyURL              types.String `tfsdk:"proxy_url"`
+	Host                  types.String        `tfsdk:"host"`
+	ClusterName           types.String        `tfsdk:"cluster_name"`
+	Region                types.String        `tfsdk:"region"`
+	EksEndpoint           types.String        `tfsdk:"eks_endpoint"`
+	Username              types.String        `tfsdk:"username"`
+	Password              types.String        `tfsdk:"password"`
+	Insecure              types.Bool          `tfsdk:"insecure"`
+	TLSServerName         types.String        `tfsdk:"tls_server_name"`
+	ClientCertificate     types.String        `tfsdk:"client_certificate"`
+	ClientKey             types.String        `tfsdk:"client_key"`
+	ClusterCACertificate  types.String        `tfsdk:"cluster_ca_certificate"`
+	ConfigPaths           []string            `tfsdk:"config_paths"`
+	ConfigPath            types.String        `tfsdk:"config_path"`
+	ConfigContext         types.String        `tfsdk:"config_context"`
+	ConfigContextAuthInfo types.String        `tfsdk:"config_context_auth_info"`
+	ConfigContextCluster  types.String        `tfsdk:"config_context_cluster"`
+	Token                 types.String        `tfsdk:"token"`
+	ProxyURL              types.String        `tfsdk:"proxy_url"`
+	ImpersonateUser       types.String        `tfsdk:"impersonate_user"`
+	ImpersonateUid        types.String        `tfsdk:"impersonate_uid"`
+	ImpersonateGroups     []string            `tfsdk:"impersonate_groups"`
+	ImpersonateExtra      map[string][]string `tfsdk:"impersonate_extra"`
 	Exec                  []struct {
 		APIVersion types.String      `tfsdk:"api_version"`
 		Command    types.String      `tfsdk:"command"`
 		Env        map[string]string `tfsdk:"env"`
 		Args       []string          `tfsdk:"args"`
 	} `tfsdk:"exec"`
+	EksAuth []struct {
+		ClusterName types.String `tfsdk:"cluster_name"`
+		Region      types.String `tfsdk:"region"`
+		RoleArn     types.String `tfsdk:"role_arn"`
+		Profile     types.String `tfsdk:"profile"`
+	} `tfsdk:"eks_auth"`
 	BurstLimit types.Int64 `tfsdk:"burst_limit"`
+	DryRun     types.Bool  `tfsdk:"dry_run"`
+
+	RetryAttempts   types.Int64  `tfsdk:"retry_attempts"`
+	RetryBackoff    types.String `tfsdk:"retry_backoff"`
+	RetryMaxBackoff types.String `tfsdk:"retry_max_backoff"`
+	RetryOn         types.Set    `tfsdk:"retry_on"`
+	ReadyTimeout    types.String `tfsdk:"ready_timeout"`
 }
 
 func (p *CleanEksProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -69,9 +100,30 @@ func (p *CleanEksProvider) Schema(_ context.Context, _ provider.SchemaRequest, r
 		Description:         "A provider to bootstrap an EKS cluster by removing AWS CNI and Kube-Proxy. It will also add the required annotations and labels to CoreDNS so that Helm can manage CoreDNS. It will also drop managed by AWS labels from CoreDNS deployment and service.",
 		Attributes: map[string]providerSchema.Attribute{
 			"host": providerSchema.StringAttribute{
-				MarkdownDescription: "The hostname (in form of URI) of Kubernetes master.",
-				Description:         "The hostname (in form of URI) of Kubernetes master.",
-				Required:            true,
+				MarkdownDescription: "The hostname (in form of URI) of Kubernetes master. Not needed when **cluster_name** is set.",
+				Description:         "The hostname (in form of URI) of Kubernetes master. Not needed when cluster_name is set.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.AtLeastOneOf(path.MatchRoot("cluster_name")),
+				},
+			},
+
+			"cluster_name": providerSchema.StringAttribute{
+				MarkdownDescription: "Name of the EKS cluster. When **host** isn't set, the endpoint and CA of the cluster are looked up with EKS `DescribeCluster` the first time the cluster is used, so that they don't have to be known when the provider is configured.",
+				Description:         "Name of the EKS cluster. When host isn't set, the endpoint and CA of the cluster are looked up with EKS DescribeCluster the first time the cluster is used, so that they don't have to be known when the provider is configured.",
+				Optional:            true,
+			},
+
+			"region": providerSchema.StringAttribute{
+				MarkdownDescription: "AWS region of the EKS cluster. Defaults to the region of the AWS configuration, e.g. `AWS_REGION`.",
+				Description:         "AWS region of the EKS cluster. Defaults to the region of the AWS configuration, e.g. AWS_REGION.",
+				Optional:            true,
+			},
+
+			"eks_endpoint": providerSchema.StringAttribute{
+				MarkdownDescription: "URL of the EKS API to use instead of the regional endpoint, e.g. a local stand-in for testing. Can also be set with `AWS_ENDPOINT_URL_EKS` environment variable.",
+				Description:         "URL of the EKS API to use instead of the regional endpoint, e.g. a local stand-in for testing. Can also be set with AWS_ENDPOINT_URL_EKS environment variable.",
+				Optional:            true,
 			},
 
 			"username": resourceSchema.StringAttribute{
@@ -206,6 +258,36 @@ func (p *CleanEksProvider) Schema(_ context.Context, _ provider.SchemaRequest, r
 				Default:             EnvDefaultString("KUBE_PROXY_URL", ""),
 			},
 
+			"impersonate_user": resourceSchema.StringAttribute{
+				MarkdownDescription: "Username to impersonate for every request to the API server. Can be set with `KUBE_IMPERSONATE_USER` environment variable.",
+				Description:         "Username to impersonate for every request to the API server. Can be set with KUBE_IMPERSONATE_USER environment variable.",
+				Optional:            true,
+				Computed:            true,
+				Default:             EnvDefaultString("KUBE_IMPERSONATE_USER", ""),
+			},
+
+			"impersonate_uid": resourceSchema.StringAttribute{
+				MarkdownDescription: "UID to impersonate for every request to the API server. Requires **impersonate_user**. Can be set with `KUBE_IMPERSONATE_UID` environment variable.",
+				Description:         "UID to impersonate for every request to the API server. Requires impersonate_user. Can be set with KUBE_IMPERSONATE_UID environment variable.",
+				Optional:            true,
+				Computed:            true,
+				Default:             EnvDefaultString("KUBE_IMPERSONATE_UID", ""),
+			},
+
+			"impersonate_groups": providerSchema.ListAttribute{
+				MarkdownDescription: "Groups to impersonate for every request to the API server, e.g. a break-glass group that is only used for cluster bootstrap. Requires **impersonate_user**. Can be set with `KUBE_IMPERSONATE_GROUPS` environment variable as a comma separated list.",
+				Description:         "Groups to impersonate for every request to the API server, e.g. a break-glass group that is only used for cluster bootstrap. Requires impersonate_user. Can be set with KUBE_IMPERSONATE_GROUPS environment variable as a comma separated list.",
+				Optional:            true,
+				ElementType:         types.StringType,
+			},
+
+			"impersonate_extra": providerSchema.MapAttribute{
+				MarkdownDescription: "Extra fields of the user to impersonate for every request to the API server, e.g. `{ reason = [\"bootstrap\"] }`. Requires **impersonate_user**. Can be set with `KUBE_IMPERSONATE_EXTRA` environment variable as comma separated `key=value` pairs, a key that is repeated has several values.",
+				Description:         "Extra fields of the user to impersonate for every request to the API server, e.g. { reason = [\"bootstrap\"] }. Requires impersonate_user. Can be set with KUBE_IMPERSONATE_EXTRA environment variable as comma separated key=value pairs, a key that is repeated has several values.",
+				Optional:            true,
+				ElementType:         types.ListType{ElemType: types.StringType},
+			},
+
 			"burst_limit": resourceSchema.Int64Attribute{
 				Description: "Helm burst limit. Increase this if you have a cluster with many CRDs",
 				Optional:    true,
@@ -215,8 +297,93 @@ func (p *CleanEksProvider) Schema(_ context.Context, _ provider.SchemaRequest, r
 					int64validator.AtMost(100000),
 				},
 			},
+
+			"dry_run": resourceSchema.BoolAttribute{
+				MarkdownDescription: "Run all changes as server-side dry runs, so that the API server validates them without persisting anything. Can be overridden per job. Can be set with `CLEANEKS_DRY_RUN` environment variable.",
+				Description:         "Run all changes as server-side dry runs, so that the API server validates them without persisting anything. Can be overridden per job. Can be set with CLEANEKS_DRY_RUN environment variable.",
+				Optional:            true,
+				Computed:            true,
+				Default:             EnvDefaultBool("CLEANEKS_DRY_RUN", false),
+			},
+
+			"retry_attempts": providerSchema.Int64Attribute{
+				MarkdownDescription: "How many times a request to the API server is attempted before giving up, including the first attempt. `1` disables retries. Defaults to `10`.",
+				Description:         "How many times a request to the API server is attempted before giving up, including the first attempt. 1 disables retries. Defaults to 10.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+
+			"retry_backoff": providerSchema.StringAttribute{
+				MarkdownDescription: "How long to wait before retrying a failed request to the API server, e.g. `2s`. It doubles after every attempt up to **retry_max_backoff**. Defaults to `1s`.",
+				Description:         "How long to wait before retrying a failed request to the API server, e.g. 2s. It doubles after every attempt up to retry_max_backoff. Defaults to 1s.",
+				Optional:            true,
+				Validators: []validator.String{
+					DurationValidator(),
+				},
+			},
+
+			"retry_max_backoff": providerSchema.StringAttribute{
+				MarkdownDescription: "The longest wait between two attempts of a request to the API server, e.g. `1m`. Defaults to `30s`.",
+				Description:         "The longest wait between two attempts of a request to the API server, e.g. 1m. Defaults to 30s.",
+				Optional:            true,
+				Validators: []validator.String{
+					DurationValidator(),
+				},
+			},
+
+			"retry_on": providerSchema.SetAttribute{
+				MarkdownDescription: "The errors from the API server that are retried. Any of `connection_refused`, `unauthorized` (401, e.g. while access entries or aws-auth propagate), `too_many_requests` (429) or `server_error` (5xx). Only `connection_refused` is retried for requests that change objects, the others are only retried for reads. Defaults to all of them.",
+				Description:         "The errors from the API server that are retried. Any of connection_refused, unauthorized (401, e.g. while access entries or aws-auth propagate), too_many_requests (429) or server_error (5xx). Only connection_refused is retried for requests that change objects, the others are only retried for reads. Defaults to all of them.",
+				Optional:            true,
+				ElementType:         types.StringType,
+				Validators: []validator.Set{
+					setvalidator.ValueStringsAre(stringvalidator.OneOf(retryOnAll...)),
+				},
+			},
+
+			"ready_timeout": providerSchema.StringAttribute{
+				MarkdownDescription: "How long to wait for `/readyz` of the API server to succeed before a job starts, e.g. `10m`. `0s` doesn't wait. Defaults to `5m`.",
+				Description:         "How long to wait for /readyz of the API server to succeed before a job starts, e.g. 10m. 0s doesn't wait. Defaults to 5m.",
+				Optional:            true,
+				Validators: []validator.String{
+					DurationValidator(),
+				},
+			},
 		},
 		Blocks: map[string]providerSchema.Block{
+			"eks_auth": providerSchema.ListNestedBlock{
+				MarkdownDescription: "Authenticate to EKS with a token generated in-process from the standard AWS credential sources, like `aws eks get-token` but without an exec plugin. The token is replaced before it expires, so that long runs don't fail mid-apply. It takes precedence over the other ways to authenticate.",
+				Description:         "Authenticate to EKS with a token generated in-process from the standard AWS credential sources, like aws eks get-token but without an exec plugin. The token is replaced before it expires, so that long runs don't fail mid-apply. It takes precedence over the other ways to authenticate.",
+				Validators: []validator.List{
+					listvalidator.SizeAtMost(1),
+				},
+				NestedObject: providerSchema.NestedBlockObject{
+					Attributes: map[string]providerSchema.Attribute{
+						"cluster_name": providerSchema.StringAttribute{
+							MarkdownDescription: "Name of the EKS cluster. Defaults to the provider **cluster_name**.",
+							Description:         "Name of the EKS cluster. Defaults to the provider cluster_name.",
+							Optional:            true,
+						},
+						"region": providerSchema.StringAttribute{
+							MarkdownDescription: "AWS region of the EKS cluster. Defaults to the provider **region**, or the region of the AWS configuration, e.g. `AWS_REGION`.",
+							Description:         "AWS region of the EKS cluster. Defaults to the provider region, or the region of the AWS configuration, e.g. AWS_REGION.",
+							Optional:            true,
+						},
+						"role_arn": providerSchema.StringAttribute{
+							Description: "ARN of an IAM role to assume to generate the token.",
+							Optional:    true,
+						},
+						"profile": providerSchema.StringAttribute{
+							MarkdownDescription: "AWS shared config profile to use. Defaults to `AWS_PROFILE` or the default profile.",
+							Description:         "AWS shared config profile to use. Defaults to AWS_PROFILE or the default profile.",
+							Optional:            true,
+						},
+					},
+				},
+			},
+
 			"exec": providerSchema.ListNestedBlock{
 				NestedObject: providerSchema.NestedBlockObject{
 					Attributes: map[string]providerSchema.Attribute{
@@ -250,11 +417,16 @@ func (p *CleanEksProvider) Schema(_ context.Context, _ provider.SchemaRequest, r
 func (p *CleanEksProvider) Resources(context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewJobResource,
+		NewAwsCniRemovalResource,
+		NewKubeProxyRemovalResource,
+		NewCorednsAdoptionResource,
 	}
 }
 
 func (p *CleanEksProvider) DataSources(context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{}
+	return []func() datasource.DataSource{
+		NewRequiredRbacDataSource,
+	}
 }
 
 func (p *CleanEksProvider) Functions(context.Context) []func() function.Function {
@@ -271,10 +443,23 @@ func New(version string) func() provider.Provider {
 
 func (p *CleanEksProvider) GetClientSet(ctx context.Context) (*kubernetes.Clientset, error) {
 	var clientSet *kubernetes.Clientset
-	restConfig, err := newKubernetesClientConfig(ctx, p.model)
+	model := p.model
+	if err := p.resolveEksCluster(ctx, &model); err != nil {
+		return nil, err
+	}
+	restConfig, err := newKubernetesClientConfig(ctx, model)
 	if err != nil {
 		return nil, err
 	} else {
+		if len(p.model.EksAuth) > 0 {
+			tokenSource, err := p.EksTokenSource(ctx)
+			if err != nil {
+				return nil, err
+			}
+			tflog.Debug(ctx, "Using eks_auth for authentication")
+			restConfig.Wrap(tokenSource.WrapTransport)
+		}
+		restConfig.Wrap(p.RetryPolicy().WrapTransport)
 		clientSet, err = kubernetes.NewForConfig(restConfig)
 		if err != nil {
 			return nil, err
@@ -283,3 +468,143 @@ func (p *CleanEksProvider) GetClientSet(ctx context.Context) (*kubernetes.Client
 
 	return clientSet, nil
 }
+
+// AwsOptions returns the AWS settings of the provider, those of the eks_auth block win.
+func (p *CleanEksProvider) AwsOptions() EksAuthOptions {
+	options := EksAuthOptions{
+		ClusterName: p.model.ClusterName.ValueString(),
+		Region:      p.model.Region.ValueString(),
+	}
+	if len(p.model.EksAuth) > 0 {
+		eksAuth := p.model.EksAuth[0]
+		if v := eksAuth.ClusterName.ValueString(); v != "" {
+			options.ClusterName = v
+		}
+		if v := eksAuth.Region.ValueString(); v != "" {
+			options.Region = v
+		}
+		options.Profile = eksAuth.Profile.ValueString()
+		options.RoleArn = eksAuth.RoleArn.ValueString()
+	}
+	return options
+}
+
+// AwsConfig returns the AWS configuration, loading it on first use.
+func (p *CleanEksProvider) AwsConfig(ctx context.Context) (aws.Config, error) {
+	if p.awsConfig == nil {
+		cfg, err := LoadAwsConfig(ctx, p.AwsOptions())
+		if err != nil {
+			return cfg, err
+		}
+		p.awsConfig = &cfg
+	}
+	return *p.awsConfig, nil
+}
+
+// EksClient returns an EKS API client, against eks_endpoint when it is set.
+func (p *CleanEksProvider) EksClient(ctx context.Context) (*eks.Client, error) {
+	cfg, err := p.AwsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewEksClient(cfg, p.model.EksEndpoint.ValueString()), nil
+}
+
+// EksTokenSource returns the token source of the eks_auth block.
+func (p *CleanEksProvider) EksTokenSource(ctx context.Context) (*EksTokenSource, error) {
+	options := p.AwsOptions()
+	if options.ClusterName == "" {
+		return nil, errors.New("eks_auth needs a cluster_name, in the block or in the provider")
+	}
+	cfg, err := p.AwsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewEksTokenSource(cfg, options.ClusterName), nil
+}
+
+// Host returns the host of the cluster, the endpoint of the EKS cluster once it has been resolved from cluster_name.
+func (p *CleanEksProvider) Host() string {
+	if p.ResolvesHost() && p.eksCluster != nil {
+		return p.eksCluster.Endpoint
+	}
+	return p.model.Host.ValueString()
+}
+
+// EksClusterArn returns the ARN of the cluster once it has been resolved from cluster_name, empty otherwise.
+func (p *CleanEksProvider) EksClusterArn() string {
+	if p.ResolvesHost() && p.eksCluster != nil {
+		return p.eksCluster.Arn
+	}
+	return ""
+}
+
+// ResolvesHost returns true when host isn't set and is looked up from cluster_name on first use.
+func (p *CleanEksProvider) ResolvesHost() bool {
+	return p.model.Host.ValueString() == "" && !p.model.Host.IsUnknown() && p.model.ClusterName.ValueString() != ""
+}
+
+// resolveEksCluster sets host and cluster_ca_certificate of model from EKS DescribeCluster when host isn't set. The
+// cluster is only described once.
+func (p *CleanEksProvider) resolveEksCluster(ctx context.Context, model *CleanEksProviderModel) error {
+	if !p.ResolvesHost() {
+		return nil
+	}
+
+	if p.eksCluster == nil {
+		client, err := p.EksClient(ctx)
+		if err != nil {
+			return err
+		}
+		cluster, err := DescribeEksCluster(ctx, client, p.model.ClusterName.ValueString())
+		if err != nil {
+			return err
+		}
+		tflog.Debug(ctx, "Resolved EKS cluster", map[string]interface{}{
+			"clusterName": p.model.ClusterName.ValueString(),
+			"endpoint":    cluster.Endpoint,
+		})
+		p.eksCluster = &cluster
+	}
+
+	model.Host = types.StringValue(p.eksCluster.Endpoint)
+	if model.ClusterCACertificate.ValueString() == "" && !model.Insecure.ValueBool() {
+		model.ClusterCACertificate = types.StringValue(p.eksCluster.CertificateAuthority)
+	}
+	return nil
+}
+
+// RetryPolicy returns the retry_* settings, using the defaults for the ones that aren't set.
+func (p *CleanEksProvider) RetryPolicy() RetryPolicy {
+	policy := RetryPolicy{
+		Attempts:   defaultRetryAttempts,
+		Backoff:    providerDuration(p.model.RetryBackoff, defaultRetryBackoff),
+		MaxBackoff: providerDuration(p.model.RetryMaxBackoff, defaultRetryMaxBackoff),
+		RetryOn:    retryOnAll,
+	}
+	if !p.model.RetryAttempts.IsNull() && !p.model.RetryAttempts.IsUnknown() {
+		policy.Attempts = int(p.model.RetryAttempts.ValueInt64())
+	}
+	if !p.model.RetryOn.IsNull() && !p.model.RetryOn.IsUnknown() {
+		policy.RetryOn = StringSetToStrings(p.model.RetryOn)
+	}
+	return policy
+}
+
+// ReadyTimeout returns how long a job waits for the API server to be ready, zero when it doesn't wait.
+func (p *CleanEksProvider) ReadyTimeout() time.Duration {
+	return providerDuration(p.model.ReadyTimeout, defaultReadyTimeout)
+}
+
+// providerDuration parses a duration setting of the provider, which has already been validated.
+func providerDuration(value types.String, defaultValue time.Duration) time.Duration {
+	if value.IsNull() || value.IsUnknown() {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value.ValueString())
+	if err != nil {
+		return defaultValue
+	}
+	return duration
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
@@ -73,9 +74,13 @@ func (p *CleanEksProvider) Configure(ctx context.Context, req provider.Configure
 		"configContext":         p.model.ConfigContext.ValueString(),
 		"configContextCluster":  p.model.ConfigContextCluster.ValueString(),
 		"configContextAuthInfo": p.model.ConfigContextAuthInfo.ValueString(),
+		"impersonateUser":       p.model.ImpersonateUser.ValueString(),
+		"impersonateGroups":     p.model.ImpersonateGroups,
+		"dryRun":                p.model.DryRun.ValueBool(),
 	})
 
-	if p.clientSet == nil {
+	// The EKS cluster is only described when it is first used, it might not exist yet
+	if p.clientSet == nil && !p.ResolvesHost() {
 		clientSet, err := p.GetClientSet(ctx)
 		if err != nil {
 			if errors.Is(err, clientcmd.ErrEmptyConfig) {
@@ -197,6 +202,35 @@ func newKubernetesClientConfig(ctx context.Context, data CleanEksProviderModel)
 
 	overrides.ClusterDefaults.ProxyURL = data.ProxyURL.ValueString()
 
+	overrides.AuthInfo.Impersonate = data.ImpersonateUser.ValueString()
+	overrides.AuthInfo.ImpersonateUID = data.ImpersonateUid.ValueString()
+	overrides.AuthInfo.ImpersonateGroups = data.ImpersonateGroups
+	if data.ImpersonateGroups == nil {
+		if v := os.Getenv("KUBE_IMPERSONATE_GROUPS"); v != "" {
+			overrides.AuthInfo.ImpersonateGroups = strings.Split(v, ",")
+		}
+	}
+	overrides.AuthInfo.ImpersonateUserExtra = data.ImpersonateExtra
+	if data.ImpersonateExtra == nil {
+		if v := os.Getenv("KUBE_IMPERSONATE_EXTRA"); v != "" {
+			extra, err := parseImpersonateExtra(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse KUBE_IMPERSONATE_EXTRA: %s", err)
+			}
+			overrides.AuthInfo.ImpersonateUserExtra = extra
+		}
+	}
+	if overrides.AuthInfo.Impersonate != "" {
+		tflog.Debug(ctx, "Using impersonation", map[string]interface{}{
+			"user":   overrides.AuthInfo.Impersonate,
+			"uid":    overrides.AuthInfo.ImpersonateUID,
+			"groups": overrides.AuthInfo.ImpersonateGroups,
+			"extra":  overrides.AuthInfo.ImpersonateUserExtra,
+		})
+	} else if overrides.AuthInfo.ImpersonateUID != "" || len(overrides.AuthInfo.ImpersonateGroups) > 0 || len(overrides.AuthInfo.ImpersonateUserExtra) > 0 {
+		return nil, errors.New("impersonate_user is required to impersonate a uid, groups or extra fields")
+	}
+
 	if len(data.Exec) > 0 {
 		execData := data.Exec[0]
 
@@ -231,3 +265,16 @@ func newKubernetesClientConfig(ctx context.Context, data CleanEksProviderModel)
 	}
 	return cfg, nil
 }
+
+// parseImpersonateExtra parses comma separated key=value pairs, a key that is repeated has several values.
+func parseImpersonateExtra(value string) (map[string][]string, error) {
+	extra := map[string][]string{}
+	for _, pair := range strings.Split(value, ",") {
+		key, val, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		extra[key] = append(extra[key], val)
+	}
+	return extra, nil
+}
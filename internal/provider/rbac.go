@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// RbacRules are the RBAC rules that grant a set of permissions: the ClusterRole rules for cluster scoped resources,
+// calls across all namespaces and non resource URLs, and the Role rules of each namespace.
+type RbacRules struct {
+	ClusterRole []rbacv1.PolicyRule
+	Roles       map[string][]rbacv1.PolicyRule
+}
+
+// Namespaces returns the namespaces that need a Role, sorted.
+func (r RbacRules) Namespaces() []string {
+	namespaces := make([]string, 0, len(r.Roles))
+	for namespace := range r.Roles {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+type rbacVerbKey struct {
+	namespace string
+	group     string
+	resource  string
+	verb      string
+}
+
+type rbacRuleKey struct {
+	namespace string
+	group     string
+	resource  string
+	// names are the sorted resource names joined by a comma, empty for any object
+	names string
+}
+
+// PermissionsToRbacRules returns the smallest rules that grant the permissions. A verb is restricted to the names of
+// the objects it is used on, unless it is also used without a name. Verbs with the same names share a rule.
+func PermissionsToRbacRules(permissions []Permission) RbacRules {
+	names := map[rbacVerbKey]map[string]bool{}
+	anyName := map[rbacVerbKey]bool{}
+	urlVerbs := map[string]map[string]bool{}
+	for _, permission := range permissions {
+		if permission.NonResourceURL != "" {
+			if urlVerbs[permission.NonResourceURL] == nil {
+				urlVerbs[permission.NonResourceURL] = map[string]bool{}
+			}
+			urlVerbs[permission.NonResourceURL][permission.Verb] = true
+			continue
+		}
+
+		key := rbacVerbKey{namespace: permission.Namespace, group: permission.Group, resource: permission.Resource, verb: permission.Verb}
+		if permission.Name == "" {
+			anyName[key] = true
+			continue
+		}
+		if names[key] == nil {
+			names[key] = map[string]bool{}
+		}
+		names[key][permission.Name] = true
+	}
+
+	ruleVerbs := map[rbacRuleKey]map[string]bool{}
+	addVerb := func(key rbacVerbKey, names string) {
+		// A verb granted across all namespaces covers every namespace
+		if key.namespace != "" && anyName[rbacVerbKey{group: key.group, resource: key.resource, verb: key.verb}] {
+			return
+		}
+		rule := rbacRuleKey{namespace: key.namespace, group: key.group, resource: key.resource, names: names}
+		if ruleVerbs[rule] == nil {
+			ruleVerbs[rule] = map[string]bool{}
+		}
+		ruleVerbs[rule][key.verb] = true
+	}
+	for key := range anyName {
+		addVerb(key, "")
+	}
+	for key, set := range names {
+		if !anyName[key] {
+			addVerb(key, strings.Join(sortedKeys(set), ","))
+		}
+	}
+
+	rules := RbacRules{Roles: map[string][]rbacv1.PolicyRule{}}
+	ruleKeys := make([]rbacRuleKey, 0, len(ruleVerbs))
+	for key := range ruleVerbs {
+		ruleKeys = append(ruleKeys, key)
+	}
+	sort.Slice(ruleKeys, func(i, j int) bool {
+		a, b := ruleKeys[i], ruleKeys[j]
+		if a.group != b.group {
+			return a.group < b.group
+		}
+		if a.resource != b.resource {
+			return a.resource < b.resource
+		}
+		return a.names < b.names
+	})
+	for _, key := range ruleKeys {
+		rule := rbacv1.PolicyRule{
+			APIGroups: []string{key.group},
+			Resources: []string{key.resource},
+			Verbs:     sortedKeys(ruleVerbs[key]),
+		}
+		if key.names != "" {
+			rule.ResourceNames = strings.Split(key.names, ",")
+		}
+		if key.namespace == "" {
+			rules.ClusterRole = append(rules.ClusterRole, rule)
+		} else {
+			rules.Roles[key.namespace] = append(rules.Roles[key.namespace], rule)
+		}
+	}
+
+	// Non resource URLs that are used with the same verbs share a rule
+	urls := map[string][]string{}
+	for url, verbs := range urlVerbs {
+		key := strings.Join(sortedKeys(verbs), ",")
+		urls[key] = append(urls[key], url)
+	}
+	verbKeys := make([]string, 0, len(urls))
+	for key := range urls {
+		verbKeys = append(verbKeys, key)
+	}
+	sort.Strings(verbKeys)
+	for _, key := range verbKeys {
+		sort.Strings(urls[key])
+		rules.ClusterRole = append(rules.ClusterRole, rbacv1.PolicyRule{
+			NonResourceURLs: urls[key],
+			Verbs:           strings.Split(key, ","),
+		})
+	}
+
+	return rules
+}
+
+// ClusterRoleYaml returns the ClusterRole manifest with the rules.
+func (r RbacRules) ClusterRoleYaml(name string) (string, error) {
+	return rbacManifest("ClusterRole", name, "", r.ClusterRole)
+}
+
+// RoleYaml returns the Role manifests of every namespace, one YAML document each.
+func (r RbacRules) RoleYaml(name string) (string, error) {
+	documents := make([]string, 0, len(r.Roles))
+	for _, namespace := range r.Namespaces() {
+		document, err := rbacManifest("Role", name, namespace, r.Roles[namespace])
+		if err != nil {
+			return "", err
+		}
+		documents = append(documents, document)
+	}
+	return strings.Join(documents, "---\n"), nil
+}
+
+// rbacManifest returns the YAML of a ClusterRole or Role. It is built from a map rather than the typed object, which
+// would add an empty creationTimestamp to the metadata.
+func rbacManifest(kind string, name string, namespace string, rules []rbacv1.PolicyRule) (string, error) {
+	metadata := map[string]interface{}{"name": name}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	if rules == nil {
+		rules = []rbacv1.PolicyRule{}
+	}
+	data, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": rbacv1.SchemeGroupVersion.String(),
+		"kind":       kind,
+		"metadata":   metadata,
+		"rules":      rules,
+	})
+	return string(data), err
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestPermissionsToRbacRules(t *testing.T) {
+	jobOptions := func(options JobOptions) JobOptions {
+		options.VerifyEks = true
+		options.LockLeaseName = defaultLockLeaseName
+		options.ReadyTimeout = defaultReadyTimeout
+		options.ReadObjects = jobReadObjects
+		return options
+	}
+
+	tests := []struct {
+		name        string
+		permissions []Permission
+		want        RbacRules
+	}{
+		{
+			name: "named and unnamed verbs",
+			permissions: []Permission{
+				// get on one config map is covered by get on any config map
+				{Verb: "get", Resource: "configmaps", Namespace: "kube-system", Name: "coredns"},
+				{Verb: "get", Resource: "configmaps", Namespace: "kube-system"},
+				{Verb: "update", Resource: "configmaps", Namespace: "kube-system", Name: "coredns"},
+				// Names of the same verb share a rule
+				{Verb: "get", Group: "apps", Resource: "daemonsets", Namespace: "kube-system", Name: "aws-node"},
+				{Verb: "delete", Group: "apps", Resource: "daemonsets", Namespace: "kube-system", Name: "aws-node"},
+				{Verb: "delete", Group: "apps", Resource: "daemonsets", Namespace: "kube-system", Name: "kube-proxy"},
+				// A namespaced verb is covered by the same verb across all namespaces
+				{Verb: "list", Resource: "pods", Namespace: "kube-system"},
+				{Verb: "list", Resource: "pods"},
+				// Non resource URLs with the same verbs share a rule
+				{Verb: "get", NonResourceURL: "/version"},
+				{Verb: "get", NonResourceURL: "/readyz"},
+				{Verb: "post", NonResourceURL: "/readyz"},
+			},
+			want: RbacRules{
+				ClusterRole: []rbacv1.PolicyRule{
+					{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}},
+					{NonResourceURLs: []string{"/version"}, Verbs: []string{"get"}},
+					{NonResourceURLs: []string{"/readyz"}, Verbs: []string{"get", "post"}},
+				},
+				Roles: map[string][]rbacv1.PolicyRule{
+					"kube-system": {
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}},
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"coredns"}, Verbs: []string{"update"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"daemonsets"}, ResourceNames: []string{"aws-node"}, Verbs: []string{"get"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"daemonsets"}, ResourceNames: []string{"aws-node", "kube-proxy"}, Verbs: []string{"delete"}},
+					},
+				},
+			},
+		},
+		{
+			name:        "remove all",
+			permissions: RequiredPermissions(jobOptions(JobOptions{AwsCni: componentActionRemove, KubeProxy: componentActionRemove, Coredns: componentActionRemove})),
+			want: RbacRules{
+				ClusterRole: []rbacv1.PolicyRule{
+					{APIGroups: []string{""}, Resources: []string{"namespaces"}, ResourceNames: []string{"kube-system"}, Verbs: []string{"get"}},
+					{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"list"}},
+					{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}},
+					{APIGroups: []string{"crd.k8s.amazonaws.com"}, Resources: []string{"eniconfigs"}, Verbs: []string{"list"}},
+					{APIGroups: []string{"networking.k8s.aws"}, Resources: []string{"policyendpoints"}, Verbs: []string{"list"}},
+					{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"networkpolicies"}, Verbs: []string{"list"}},
+					{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"clusterroles"}, Verbs: []string{"list"}},
+					{NonResourceURLs: []string{"/.well-known/openid-configuration", "/readyz", "/version"}, Verbs: []string{"get"}},
+				},
+				Roles: map[string][]rbacv1.PolicyRule{
+					"default": {
+						{APIGroups: []string{""}, Resources: []string{"services"}, ResourceNames: []string{"kubernetes"}, Verbs: []string{"get"}},
+					},
+					"kube-system": {
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}},
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"amazon-vpc-cni", "aws-auth", "cilium-config", "cleaneks-ledger", "coredns", "kube-proxy"}, Verbs: []string{"get"}},
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"cleaneks-ledger"}, Verbs: []string{"update"}},
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"coredns", "kube-proxy"}, Verbs: []string{"delete"}},
+						{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+						{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, ResourceNames: []string{"aws-node", "coredns"}, Verbs: []string{"get"}},
+						{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, ResourceNames: []string{"coredns"}, Verbs: []string{"delete"}},
+						{APIGroups: []string{""}, Resources: []string{"services"}, ResourceNames: []string{"kube-dns"}, Verbs: []string{"delete", "get"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"daemonsets"}, ResourceNames: []string{"aws-node", "cilium", "kube-proxy"}, Verbs: []string{"get"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"daemonsets"}, ResourceNames: []string{"aws-node", "kube-proxy"}, Verbs: []string{"delete"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, ResourceNames: []string{"coredns"}, Verbs: []string{"delete", "get"}},
+						{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: []string{"create"}},
+						{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, ResourceNames: []string{"cleaneks-job"}, Verbs: []string{"get", "update"}},
+						{APIGroups: []string{"policy"}, Resources: []string{"poddisruptionbudgets"}, ResourceNames: []string{"coredns"}, Verbs: []string{"delete", "get"}},
+					},
+				},
+			},
+		},
+		{
+			name:        "adopt coredns",
+			permissions: RequiredPermissions(jobOptions(JobOptions{Coredns: componentActionAdopt})),
+			want: RbacRules{
+				ClusterRole: []rbacv1.PolicyRule{
+					{APIGroups: []string{""}, Resources: []string{"namespaces"}, ResourceNames: []string{"kube-system"}, Verbs: []string{"get"}},
+					{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"list"}},
+					{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"clusterroles"}, Verbs: []string{"list"}},
+					{NonResourceURLs: []string{"/.well-known/openid-configuration", "/readyz", "/version"}, Verbs: []string{"get"}},
+				},
+				Roles: map[string][]rbacv1.PolicyRule{
+					"default": {
+						{APIGroups: []string{""}, Resources: []string{"services"}, ResourceNames: []string{"kubernetes"}, Verbs: []string{"get"}},
+					},
+					"kube-system": {
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"create"}},
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"aws-auth", "cleaneks-ledger", "coredns", "kube-proxy"}, Verbs: []string{"get"}},
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"cleaneks-ledger", "coredns"}, Verbs: []string{"update"}},
+						{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+						{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"list"}},
+						{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, ResourceNames: []string{"aws-node", "coredns"}, Verbs: []string{"get"}},
+						{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, ResourceNames: []string{"coredns"}, Verbs: []string{"update"}},
+						{APIGroups: []string{""}, Resources: []string{"services"}, ResourceNames: []string{"kube-dns"}, Verbs: []string{"get", "update"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"daemonsets"}, ResourceNames: []string{"aws-node", "kube-proxy"}, Verbs: []string{"get"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, ResourceNames: []string{"coredns"}, Verbs: []string{"get", "update"}},
+						{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: []string{"create"}},
+						{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, ResourceNames: []string{"cleaneks-job"}, Verbs: []string{"get", "update"}},
+						{APIGroups: []string{"policy"}, Resources: []string{"poddisruptionbudgets"}, ResourceNames: []string{"coredns"}, Verbs: []string{"get", "update"}},
+					},
+				},
+			},
+		},
+		{
+			// No lock is taken and no audit trail written, but the server side dry run still needs delete
+			name:        "dry run",
+			permissions: RequiredPermissions(jobOptions(JobOptions{AwsCni: componentActionRemove, KubeProxy: componentActionRemove, DryRun: true})),
+			want: RbacRules{
+				ClusterRole: []rbacv1.PolicyRule{
+					{APIGroups: []string{""}, Resources: []string{"namespaces"}, ResourceNames: []string{"kube-system"}, Verbs: []string{"get"}},
+					{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"list"}},
+					{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}},
+					{APIGroups: []string{"crd.k8s.amazonaws.com"}, Resources: []string{"eniconfigs"}, Verbs: []string{"list"}},
+					{APIGroups: []string{"networking.k8s.aws"}, Resources: []string{"policyendpoints"}, Verbs: []string{"list"}},
+					{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"networkpolicies"}, Verbs: []string{"list"}},
+					{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"clusterroles"}, Verbs: []string{"list"}},
+					{NonResourceURLs: []string{"/.well-known/openid-configuration", "/readyz", "/version"}, Verbs: []string{"get"}},
+				},
+				Roles: map[string][]rbacv1.PolicyRule{
+					"default": {
+						{APIGroups: []string{""}, Resources: []string{"services"}, ResourceNames: []string{"kubernetes"}, Verbs: []string{"get"}},
+					},
+					"kube-system": {
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"amazon-vpc-cni", "aws-auth", "cilium-config", "cleaneks-ledger", "coredns", "kube-proxy"}, Verbs: []string{"get"}},
+						{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"kube-proxy"}, Verbs: []string{"delete"}},
+						{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, ResourceNames: []string{"aws-node", "coredns"}, Verbs: []string{"get"}},
+						{APIGroups: []string{""}, Resources: []string{"services"}, ResourceNames: []string{"kube-dns"}, Verbs: []string{"get"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"daemonsets"}, ResourceNames: []string{"aws-node", "cilium", "kube-proxy"}, Verbs: []string{"get"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"daemonsets"}, ResourceNames: []string{"aws-node", "kube-proxy"}, Verbs: []string{"delete"}},
+						{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, ResourceNames: []string{"coredns"}, Verbs: []string{"get"}},
+						{APIGroups: []string{"policy"}, Resources: []string{"poddisruptionbudgets"}, ResourceNames: []string{"coredns"}, Verbs: []string{"get"}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := PermissionsToRbacRules(test.permissions)
+			if !reflect.DeepEqual(got.ClusterRole, test.want.ClusterRole) {
+				t.Errorf("ClusterRole rules\n got: %v\nwant: %v", got.ClusterRole, test.want.ClusterRole)
+			}
+			if !reflect.DeepEqual(got.Roles, test.want.Roles) {
+				t.Errorf("Role rules\n got: %v\nwant: %v", got.Roles, test.want.Roles)
+			}
+		})
+	}
+}
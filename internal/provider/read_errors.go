@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// ReadError is a failed read-only call to the API server, tagged with the verb and the object it was made for, e.g.
+// get PodDisruptionBudget kube-system/coredns.
+type ReadError struct {
+	Verb   string
+	Object string
+	Err    error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Verb, e.Object, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// readError tags err with the verb and object, keeping the tag of an error that already has one.
+func readError(verb string, object string, err error) error {
+	var tagged *ReadError
+	if err == nil || errors.As(err, &tagged) {
+		return err
+	}
+	return &ReadError{Verb: verb, Object: object, Err: err}
+}
+
+// ReadErrors collects every failure of a read path, which keeps going after a failure so that all of them, e.g. every
+// missing RBAC permission, are reported at once.
+type ReadErrors []*ReadError
+
+// Add records err, tagged with the verb and object unless it already is, and returns whether there was an error.
+func (e *ReadErrors) Add(verb string, object string, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var tagged *ReadError
+	if !errors.As(err, &tagged) {
+		tagged = &ReadError{Verb: verb, Object: object, Err: err}
+	}
+	*e = append(*e, tagged)
+	return true
+}
+
+// Diagnostics returns an error diagnostic for every failure.
+func (e ReadErrors) Diagnostics() (diags diag.Diagnostics) {
+	for _, err := range e {
+		diags.AddError(
+			fmt.Sprintf("Error reading %s", err.Object),
+			fmt.Sprintf("Error reading %s, %s failed: %s", err.Object, err.Verb, err.Err),
+		)
+	}
+	return diags
+}
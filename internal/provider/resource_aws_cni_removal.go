@@ -0,0 +1,251 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &AwsCniRemovalResource{}
+var _ resource.ResourceWithImportState = &AwsCniRemovalResource{}
+var _ resource.ResourceWithValidateConfig = &AwsCniRemovalResource{}
+var _ resource.ResourceWithModifyPlan = &AwsCniRemovalResource{}
+
+func NewAwsCniRemovalResource() resource.Resource {
+	return &AwsCniRemovalResource{}
+}
+
+// AwsCniRemovalResource removes AWS CNI on its own, so that it can be ordered after the replacement CNI is installed.
+type AwsCniRemovalResource struct {
+	provider *CleanEksProvider
+}
+
+type AwsCniRemovalResourceModel struct {
+	ID types.String `tfsdk:"id"`
+
+	DryRun types.Bool `tfsdk:"dry_run"`
+
+	VerifyEks           types.Bool   `tfsdk:"verify_eks"`
+	ExpectedClusterName types.String `tfsdk:"expected_cluster_name"`
+	ExpectedClusterArn  types.String `tfsdk:"expected_cluster_arn"`
+
+	AllowClusterReplacement types.Bool   `tfsdk:"allow_cluster_replacement"`
+	ClusterUid              types.String `tfsdk:"cluster_uid"`
+	ClusterArn              types.String `tfsdk:"cluster_arn"`
+
+	LockLeaseName types.String `tfsdk:"lock_lease_name"`
+	LockTimeout   types.String `tfsdk:"lock_timeout"`
+
+	AcknowledgedAwsCniDependencies types.Set `tfsdk:"acknowledged_aws_cni_dependencies"`
+
+	WaitFor        []JobWaitForModel `tfsdk:"wait_for"`
+	WaitForTimeout types.String      `tfsdk:"wait_for_timeout"`
+
+	DryRunChanges types.List `tfsdk:"dry_run_changes"`
+
+	RequiredPermissions types.List `tfsdk:"required_permissions"`
+
+	Components types.List `tfsdk:"components"`
+}
+
+// Options returns the settings of the removal for the step engine.
+func (m AwsCniRemovalResourceModel) Options(p *CleanEksProvider) (options JobOptions, diags diag.Diagnostics) {
+	options = JobOptions{
+		AwsCni:                         componentActionRemove,
+		DryRun:                         dryRunValue(p, m.DryRun),
+		ReadyTimeout:                   p.ReadyTimeout(),
+		ReadObjects:                    AwsCniObjects,
+		VerifyEks:                      m.VerifyEks.ValueBool(),
+		ExpectedClusterName:            m.ExpectedClusterName.ValueString(),
+		ExpectedClusterArn:             m.ExpectedClusterArn.ValueString(),
+		LockLeaseName:                  m.LockLeaseName.ValueString(),
+		AcknowledgedAwsCniDependencies: StringSetToStrings(m.AcknowledgedAwsCniDependencies),
+		WaitFor:                        waitConditions(m.WaitFor),
+	}
+
+	var durationDiags diag.Diagnostics
+	options.LockTimeout, durationDiags = durationValue("lock_timeout", m.LockTimeout)
+	diags.Append(durationDiags...)
+	options.WaitForTimeout, durationDiags = durationValue("wait_for_timeout", m.WaitForTimeout)
+	diags.Append(durationDiags...)
+	return options, diags
+}
+
+// Store records what was found in the cluster.
+func (m *AwsCniRemovalResourceModel) Store(result *componentResult) {
+	m.ID = types.StringValue(result.Host)
+	m.ClusterUid = types.StringValue(result.Identity.Uid)
+	m.ClusterArn = types.StringValue(result.Identity.Arn)
+	m.Components = result.Components
+	if result.Run != nil || m.DryRunChanges.IsUnknown() || m.DryRunChanges.IsNull() {
+		m.DryRunChanges = result.DryRunChanges()
+	}
+	if result.Run != nil || m.RequiredPermissions.IsUnknown() || m.RequiredPermissions.IsNull() {
+		m.RequiredPermissions = result.RequiredPermissions()
+	}
+}
+
+func (r *AwsCniRemovalResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_aws_cni_removal"
+}
+
+func (r *AwsCniRemovalResource) Schema(_ context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Removes the default AWS-CNI from an EKS cluster. Use depends_on to remove it only once the " +
+			"replacement CNI is installed.",
+		Attributes: mergeAttributes(runAttributes("Status of the AWS-CNI daemonset."), map[string]schema.Attribute{
+			"acknowledged_aws_cni_dependencies": acknowledgedAwsCniDependenciesAttribute(),
+
+			"wait_for_timeout": waitForTimeoutAttribute(),
+		}),
+		Blocks: map[string]schema.Block{
+			"wait_for": waitForBlock(),
+		},
+	}
+}
+
+func (r *AwsCniRemovalResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var model AwsCniRemovalResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateWaitFor(model.WaitFor)...)
+}
+
+func (r *AwsCniRemovalResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	cleanEksProvider, diags := configureProvider(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+	if cleanEksProvider != nil {
+		r.provider = cleanEksProvider
+	}
+}
+
+func (r *AwsCniRemovalResource) Create(ctx context.Context, req resource.CreateRequest, res *resource.CreateResponse) {
+	tflog.Debug(ctx, "Removing AWS CNI")
+
+	var model AwsCniRemovalResourceModel
+	res.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+	tflog.Debug(ctx, "Loaded AWS CNI removal configuration", map[string]interface{}{
+		"config": fmt.Sprintf("%+v", model),
+	})
+
+	res.Diagnostics.Append(r.apply(ctx, "AwsCniRemovalResource.Create", AwsCniRemovalResourceModel{}, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+func (r *AwsCniRemovalResource) Read(ctx context.Context, req resource.ReadRequest, res *resource.ReadResponse) {
+	tflog.Debug(ctx, "Reading AWS CNI removal")
+
+	var model AwsCniRemovalResourceModel
+	res.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	pinned := ClusterIdentity{Uid: model.ClusterUid.ValueString(), Arn: model.ClusterArn.ValueString()}
+	result, diags := applyComponent(ctx, r.provider, "AwsCniRemovalResource.Read", model.ID, pinned, model.AllowClusterReplacement.ValueBool(), nil, AwsCniObjects, model.Components)
+	res.Diagnostics.Append(diags...)
+	if res.Diagnostics.HasError() || result == nil {
+		return
+	}
+
+	model.Store(result)
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+// ModifyPlan plans an update when the state read from the cluster shows that AWS CNI is back, e.g. reinstalled by an
+// EKS add-on update, so that it is removed again.
+func (r *AwsCniRemovalResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing drifts before the removal is created or once it is destroyed
+	if r.provider == nil || req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var state AwsCniRemovalResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	// A dry run never removed it
+	if resp.Diagnostics.HasError() || dryRunValue(r.provider, state.DryRun) {
+		return
+	}
+
+	statuses, diags := componentStatuses(ctx, state.Components)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	planDrift(ctx, resp, UnsatisfiedActions(JobOptions{AwsCni: componentActionRemove}, statuses, nil))
+}
+
+func (r *AwsCniRemovalResource) Update(ctx context.Context, req resource.UpdateRequest, res *resource.UpdateResponse) {
+	tflog.Debug(ctx, "Updating AWS CNI removal")
+
+	var model AwsCniRemovalResourceModel
+	res.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	var state AwsCniRemovalResourceModel
+	res.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	res.Diagnostics.Append(r.apply(ctx, "AwsCniRemovalResource.Update", state, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+func (r *AwsCniRemovalResource) Delete(ctx context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+	// NO-OP: Returning no error is enough for the framework to remove the resource from state.
+	tflog.Debug(ctx, "Removing AWS CNI removal from state")
+}
+
+func (r *AwsCniRemovalResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// apply removes AWS CNI and populates the model. state is the prior state, empty on create.
+func (r *AwsCniRemovalResource) apply(ctx context.Context, operation string, state AwsCniRemovalResourceModel, model *AwsCniRemovalResourceModel) (diags diag.Diagnostics) {
+	if r.provider == nil {
+		diags.AddError(
+			"Provider not configured",
+			fmt.Sprintf("Provider not configured"),
+		)
+		return diags
+	}
+
+	options, diags := model.Options(r.provider)
+	if diags.HasError() {
+		return diags
+	}
+
+	pinned := ClusterIdentity{Uid: state.ClusterUid.ValueString(), Arn: state.ClusterArn.ValueString()}
+	result, applyDiags := applyComponent(ctx, r.provider, operation, model.ID, pinned, model.AllowClusterReplacement.ValueBool(), &options, AwsCniObjects, state.Components)
+	diags.Append(applyDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	model.Store(result)
+	return diags
+}
@@ -0,0 +1,745 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ResourceClientSet returns the Kubernetes client for a resource operation such as "JobResource.Create", creating it
+// on first use. id is the ID of the resource, the host it was created against, which is used when the provider host is
+// not known yet.
+func (p *CleanEksProvider) ResourceClientSet(ctx context.Context, id types.String, operation string) (*kubernetes.Clientset, error) {
+	if p.model.Host.IsUnknown() && !(id.IsUnknown() || id.IsNull()) {
+		p.model.Host = id
+	}
+
+	if p.model.ClientCertificate.IsUnknown() && p.model.Insecure.IsUnknown() {
+		p.model.Insecure = types.BoolValue(true)
+	}
+
+	execCommand := ""
+	execArgs := []string{}
+	execEnv := map[string]string{}
+	if len(p.model.Exec) > 0 {
+		execCommand = p.model.Exec[0].Command.ValueString()
+		execArgs = p.model.Exec[0].Args
+		execEnv = p.model.Exec[0].Env
+	}
+	password := ""
+	if len(p.model.Password.ValueString()) > 0 {
+		password = passwordMask
+	}
+
+	clientKey := ""
+	if len(p.model.ClientKey.ValueString()) > 0 {
+		clientKey = passwordMask
+	}
+	tflog.Debug(ctx, fmt.Sprintf("Loaded provider configuration during %s", operation), map[string]interface{}{
+		"host":                  p.model.Host.ValueString(),
+		"burtLimit":             p.model.BurstLimit.ValueInt64(),
+		"token":                 p.model.Token.ValueString(),
+		"insecure":              p.model.Insecure.ValueBool(),
+		"clusterCACertificate":  p.model.ClusterCACertificate.ValueString(),
+		"tlsServerName":         p.model.TLSServerName.ValueString(),
+		"username":              p.model.Username.ValueString(),
+		"password":              password,
+		"clientCertificate":     p.model.ClientCertificate.ValueString(),
+		"clientKey":             clientKey,
+		"execCommand":           execCommand,
+		"execArgs":              execArgs,
+		"execEnv":               execEnv,
+		"configPaths":           p.model.ConfigPaths,
+		"configContext":         p.model.ConfigContext.ValueString(),
+		"configContextCluster":  p.model.ConfigContextCluster.ValueString(),
+		"configContextAuthInfo": p.model.ConfigContextAuthInfo.ValueString(),
+		"impersonateUser":       p.model.ImpersonateUser.ValueString(),
+		"impersonateGroups":     p.model.ImpersonateGroups,
+	})
+
+	if p.clientSet == nil {
+		clientSet, err := p.GetClientSet(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.clientSet = clientSet
+	}
+	return p.clientSet, nil
+}
+
+// configureProvider returns the provider passed to a resource Configure.
+func configureProvider(providerData any) (cleanEksProvider *CleanEksProvider, diags diag.Diagnostics) {
+	// Prevent panic if the provider has not been configured.
+	if providerData == nil {
+		return nil, diags
+	}
+
+	cleanEksProvider, ok := providerData.(*CleanEksProvider)
+	if !ok {
+		diags.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *CleanEksProviderResourceData, got: %T. Please report this issue to the provider developers.", providerData),
+		)
+	}
+	return cleanEksProvider, diags
+}
+
+// checkClusterIdentity reads the identity of the live cluster. When an identity was pinned before, the live cluster has
+// to match it unless allowReplacement is set, so that a changed kubeconfig context can't point a resource at a
+// different cluster. eksArn is the ARN from the EKS API, empty when the host wasn't resolved from it.
+func checkClusterIdentity(ctx context.Context, clientSet *kubernetes.Clientset, pinned ClusterIdentity, allowReplacement bool, eksArn string) (identity ClusterIdentity, diags diag.Diagnostics) {
+	identity, err := GetClusterIdentity(ctx, clientSet)
+	if err != nil {
+		var errs ReadErrors
+		errs.Add("get", "cluster identity", err)
+		return identity, errs.Diagnostics()
+	}
+	if eksArn != "" {
+		// The EKS API knows the ARN of the endpoint for certain, what is discovered in the cluster is a best guess
+		identity.Arn = eksArn
+	}
+
+	mismatches := identity.Mismatches(pinned)
+	if len(mismatches) > 0 {
+		if !allowReplacement {
+			diags.AddError(
+				"Refusing to act on a different cluster",
+				fmt.Sprintf("The cluster does not match the one pinned in state:\n  - %s\n\nCheck the provider is pointing at the right cluster, or set allow_cluster_replacement = true if the cluster was intentionally rebuilt.", strings.Join(mismatches, "\n  - ")),
+			)
+			return identity, diags
+		}
+
+		diags.AddWarning(
+			"Cluster replaced",
+			fmt.Sprintf("allow_cluster_replacement is set, so the new cluster identity is pinned:\n  - %s", strings.Join(mismatches, "\n  - ")),
+		)
+	}
+
+	return identity.Pin(pinned), diags
+}
+
+// dryRunValue returns the dry_run of a resource, which defaults to the provider dry_run setting.
+func dryRunValue(p *CleanEksProvider, dryRun types.Bool) bool {
+	if !(dryRun.IsNull() || dryRun.IsUnknown()) {
+		return dryRun.ValueBool()
+	}
+	return p.model.DryRun.ValueBool()
+}
+
+// durationValue parses a duration attribute, zero when it isn't set.
+func durationValue(attribute string, value types.String) (duration time.Duration, diags diag.Diagnostics) {
+	if value.IsNull() || value.IsUnknown() {
+		return 0, diags
+	}
+
+	duration, err := time.ParseDuration(value.ValueString())
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root(attribute),
+			fmt.Sprintf("Invalid %s", attribute),
+			fmt.Sprintf("Invalid %s: %s", attribute, err),
+		)
+	}
+	return duration, diags
+}
+
+// waitConditions converts wait_for blocks.
+func waitConditions(waitFor []JobWaitForModel) []WaitCondition {
+	conditions := make([]WaitCondition, 0, len(waitFor))
+	for _, condition := range waitFor {
+		conditions = append(conditions, condition.Condition())
+	}
+	return conditions
+}
+
+// kubeProxyReplacementChecks converts kube_proxy_replacement_check blocks.
+func kubeProxyReplacementChecks(checks []JobKubeProxyReplacementCheckModel) []KubeProxyReplacementCheck {
+	out := make([]KubeProxyReplacementCheck, 0, len(checks))
+	for _, check := range checks {
+		out = append(out, check.Check())
+	}
+	return out
+}
+
+// componentsValue builds the components list of a resource from the status of its objects. The last action of an
+// object is the one made in this run, or otherwise the one in previous.
+func componentsValue(ctx context.Context, objects []ClusterObject, statuses map[ClusterObject]ComponentStatus, previous types.List, actions map[ClusterObject]string) (components types.List, diags diag.Diagnostics) {
+	lastActions := map[ClusterObject]string{}
+	if !(previous.IsNull() || previous.IsUnknown()) {
+		var previousComponents []JobComponentModel
+		diags.Append(previous.ElementsAs(ctx, &previousComponents, false)...)
+		for _, component := range previousComponents {
+			lastActions[ClusterObject{Kind: component.Kind.ValueString(), Namespace: component.Namespace.ValueString(), Name: component.Name.ValueString()}] = component.LastAction.ValueString()
+		}
+	}
+	for object, action := range actions {
+		lastActions[object] = action
+	}
+
+	models := make([]JobComponentModel, 0, len(objects))
+	for _, object := range objects {
+		status := statuses[object]
+
+		adoption, adoptionDiags := types.MapValueFrom(ctx, types.BoolType, status.Adoption)
+		diags.Append(adoptionDiags...)
+
+		models = append(models, JobComponentModel{
+			Kind:           types.StringValue(object.Kind),
+			Namespace:      types.StringValue(object.Namespace),
+			Name:           types.StringValue(object.Name),
+			Exists:         types.BoolValue(status.Exists),
+			IsEksManaged:   types.BoolValue(status.IsEksManaged),
+			IsAddonManaged: types.BoolValue(status.IsAddonManaged),
+			Adoption:       adoption,
+			LastAction:     types.StringValue(lastActions[object]),
+		})
+	}
+
+	components, componentsDiags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: jobComponentAttributeTypes}, models)
+	diags.Append(componentsDiags...)
+	return components, diags
+}
+
+// componentStatuses returns the statuses a components list was built from by componentsValue.
+func componentStatuses(ctx context.Context, components types.List) (statuses map[ClusterObject]ComponentStatus, diags diag.Diagnostics) {
+	statuses = map[ClusterObject]ComponentStatus{}
+	if components.IsNull() || components.IsUnknown() {
+		return statuses, diags
+	}
+
+	var models []JobComponentModel
+	diags.Append(components.ElementsAs(ctx, &models, false)...)
+	for _, component := range models {
+		object := ClusterObject{Kind: component.Kind.ValueString(), Namespace: component.Namespace.ValueString(), Name: component.Name.ValueString()}
+		adoption := map[string]bool{}
+		if !(component.Adoption.IsNull() || component.Adoption.IsUnknown()) {
+			diags.Append(component.Adoption.ElementsAs(ctx, &adoption, false)...)
+		}
+		statuses[object] = ComponentStatus{
+			Object:         object,
+			Exists:         component.Exists.ValueBool(),
+			IsEksManaged:   component.IsEksManaged.ValueBool(),
+			IsAddonManaged: component.IsAddonManaged.ValueBool(),
+			Adoption:       adoption,
+		}
+	}
+	return statuses, diags
+}
+
+// planDrift plans an update of a resource whose actions no longer hold in the cluster, e.g. after an EKS add-on update
+// put a removed component back. Read only records the drift in the computed attributes, which are planned as unknown
+// here the way Terraform plans them when the configuration changes, so that the update applies the actions again and
+// stores what it finds.
+func planDrift(ctx context.Context, resp *resource.ModifyPlanResponse, drift []string) {
+	if len(drift) == 0 {
+		return
+	}
+	tflog.Warn(ctx, "The cluster drifted, planning an update to apply the actions again", map[string]interface{}{
+		"drift": drift,
+	})
+
+	for name, attribute := range resp.Plan.Schema.GetAttributes() {
+		// The ID is the host, which an update doesn't change
+		if !attribute.IsComputed() || attribute.IsOptional() || name == "id" {
+			continue
+		}
+		attributeType := attribute.GetType()
+		unknown, err := attributeType.ValueFromTerraform(ctx, tftypes.NewValue(attributeType.TerraformType(ctx), tftypes.UnknownValue))
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(name),
+				"Error planning drift",
+				fmt.Sprintf("Error planning %s as unknown: %s", name, err),
+			)
+			return
+		}
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(name), unknown)...)
+	}
+}
+
+// runAttributes are the attributes that every resource that changes the cluster has: where it runs, how it makes sure
+// it runs against the right cluster, and what it found.
+func runAttributes(componentsDescription string) map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"id": schema.StringAttribute{
+			Description: `ID of the job.`,
+			Computed:    true,
+		},
+
+		"dry_run": schema.BoolAttribute{
+			MarkdownDescription: "Run every delete and Helm import as a server-side dry run (`metav1.DryRunAll`), so that admission, RBAC and conflicts are validated without persisting anything. Defaults to the provider `dry_run` setting.",
+			Description:         "Run every delete and Helm import as a server-side dry run (metav1.DryRunAll), so that admission, RBAC and conflicts are validated without persisting anything. Defaults to the provider dry_run setting.",
+			Optional:            true,
+		},
+
+		"verify_eks": schema.BoolAttribute{
+			MarkdownDescription: "Before making any change, confirm that the cluster is an EKS cluster from its server version (`-eks-`), the `eks:` cluster roles and the `aws:///` provider ID of every node. Changes are refused when any of these don't match.",
+			Description:         "Before making any change, confirm that the cluster is an EKS cluster from its server version (-eks-), the eks: cluster roles and the aws:/// provider ID of every node. Changes are refused when any of these don't match.",
+			Optional:            true,
+			Computed:            true,
+			Default:             booldefault.StaticBool(true),
+		},
+
+		"expected_cluster_name": schema.StringAttribute{
+			MarkdownDescription: "Name of the EKS cluster the changes are meant for. It is compared with `CLUSTER_NAME` of the `aws-node` daemonset or the `alpha.eksctl.io/cluster-name` node label, falling back to the EKS API when the provider resolves the host from `cluster_name`, or to **cluster_arn**. Changes are refused when it doesn't match or can't be determined, unless `aws-node` was removed by cleaneks.",
+			Description:         "Name of the EKS cluster the changes are meant for. It is compared with CLUSTER_NAME of the aws-node daemonset or the alpha.eksctl.io/cluster-name node label, falling back to the EKS API when the provider resolves the host from cluster_name, or to cluster_arn. Changes are refused when it doesn't match or can't be determined, unless aws-node was removed by cleaneks.",
+			Optional:            true,
+			Validators: []validator.String{
+				stringvalidator.LengthAtLeast(1),
+			},
+		},
+
+		"expected_cluster_arn": schema.StringAttribute{
+			MarkdownDescription: "ARN of the EKS cluster the changes are meant for, e.g. `arn:aws:eks:eu-west-1:123456789012:cluster/example`. The cluster name, region and account are compared, the account with the IAM roles of `aws-node` and `aws-auth`, and changes are refused when any of them doesn't match or can't be determined.",
+			Description:         "ARN of the EKS cluster the changes are meant for, e.g. arn:aws:eks:eu-west-1:123456789012:cluster/example. The cluster name, region and account are compared, the account with the IAM roles of aws-node and aws-auth, and changes are refused when any of them doesn't match or can't be determined.",
+			Optional:            true,
+			Validators: []validator.String{
+				stringvalidator.RegexMatches(eksClusterArnRegexp, "value must be an EKS cluster ARN"),
+			},
+		},
+
+		"allow_cluster_replacement": schema.BoolAttribute{
+			MarkdownDescription: "Accept a cluster whose identity doesn't match **cluster_uid** or **cluster_arn**, e.g. after an intentional cluster rebuild, and pin the new identity.",
+			Description:         "Accept a cluster whose identity doesn't match cluster_uid or cluster_arn, e.g. after an intentional cluster rebuild, and pin the new identity.",
+			Optional:            true,
+			Computed:            true,
+			Default:             booldefault.StaticBool(false),
+		},
+
+		"cluster_uid": schema.StringAttribute{
+			MarkdownDescription: "UID of the `kube-system` namespace of the cluster the job ran against. Read and update refuse to act on a cluster with a different UID.",
+			Description:         "UID of the kube-system namespace of the cluster the job ran against. Read and update refuse to act on a cluster with a different UID.",
+			Computed:            true,
+		},
+
+		"cluster_arn": schema.StringAttribute{
+			MarkdownDescription: "ARN of the EKS cluster the job ran against, from the EKS API when the provider resolves the host from `cluster_name`, otherwise when it could be determined from `aws-node` and `aws-auth`. It is kept once `aws-node` is removed. Read and update refuse to act on a cluster with a different ARN.",
+			Description:         "ARN of the EKS cluster the job ran against, from the EKS API when the provider resolves the host from cluster_name, otherwise when it could be determined from aws-node and aws-auth. It is kept once aws-node is removed. Read and update refuse to act on a cluster with a different ARN.",
+			Computed:            true,
+		},
+
+		"lock_lease_name": schema.StringAttribute{
+			MarkdownDescription: "Name of the `coordination.k8s.io/v1` Lease in `kube-system` that is held while changes are made, so that concurrent runs against the same cluster don't race each other. A run whose lease is taken over stops before its next change.",
+			Description:         "Name of the coordination.k8s.io/v1 Lease in kube-system that is held while changes are made, so that concurrent runs against the same cluster don't race each other. A run whose lease is taken over stops before its next change.",
+			Optional:            true,
+			Computed:            true,
+			Default:             stringdefault.StaticString(defaultLockLeaseName),
+			Validators: []validator.String{
+				stringvalidator.LengthAtLeast(1),
+			},
+		},
+
+		"lock_timeout": schema.StringAttribute{
+			MarkdownDescription: "How long to wait for another run to release the **lock_lease_name** Lease before giving up, e.g. `5m`. `0s` fails straight away when the lock is held.",
+			Description:         "How long to wait for another run to release the lock_lease_name Lease before giving up, e.g. 5m. 0s fails straight away when the lock is held.",
+			Optional:            true,
+			Computed:            true,
+			Default:             stringdefault.StaticString("5m"),
+			Validators: []validator.String{
+				DurationValidator(),
+			},
+		},
+
+		"dry_run_changes": schema.ListAttribute{
+			MarkdownDescription: "When **dry_run** is enabled, the unified diff of every object that would be changed and every delete that would happen.",
+			Description:         "When dry_run is enabled, the unified diff of every object that would be changed and every delete that would happen.",
+			Computed:            true,
+			ElementType:         types.StringType,
+		},
+
+		"required_permissions": schema.ListAttribute{
+			MarkdownDescription: "Every (verb, group, resource, namespace, name) the configured actions need, e.g. `delete daemonsets.apps kube-system/aws-node`. They are all checked with a `SelfSubjectAccessReview` before anything is changed.",
+			Description:         "Every (verb, group, resource, namespace, name) the configured actions need, e.g. delete daemonsets.apps kube-system/aws-node. They are all checked with a SelfSubjectAccessReview before anything is changed.",
+			Computed:            true,
+			ElementType:         types.StringType,
+		},
+
+		"components": schema.ListNestedAttribute{
+			Description: componentsDescription + " When an action no longer holds, e.g. a removed object is back, the next plan updates the resource to apply it again.",
+			Computed:    true,
+			NestedObject: schema.NestedAttributeObject{
+				Attributes: map[string]schema.Attribute{
+					"kind": schema.StringAttribute{
+						Description: "Kind of the object.",
+						Computed:    true,
+					},
+					"namespace": schema.StringAttribute{
+						Description: "Namespace of the object.",
+						Computed:    true,
+					},
+					"name": schema.StringAttribute{
+						Description: "Name of the object.",
+						Computed:    true,
+					},
+					"exists": schema.BoolAttribute{
+						Description: "Does the object exist.",
+						Computed:    true,
+					},
+					"is_eks_managed": schema.BoolAttribute{
+						MarkdownDescription: "Does the object have the **eks.amazonaws.com/component** label.",
+						Description:         "Does the object have the eks.amazonaws.com/component label.",
+						Computed:            true,
+					},
+					"is_addon_managed": schema.BoolAttribute{
+						MarkdownDescription: "Does an EKS managed add-on own the object, i.e. the `eks` field manager applied fields of it. Changes to such an object are undone when the add-on reconciles.",
+						Description:         "Does an EKS managed add-on own the object, i.e. the eks field manager applied fields of it. Changes to such an object are undone when the add-on reconciles.",
+						Computed:            true,
+					},
+					"adoption": schema.MapAttribute{
+						MarkdownDescription: "For each Helm metadata key (**meta.helm.sh/release-name**, **meta.helm.sh/release-namespace**, **app.kubernetes.io/managed-by** and the removed **eks.amazonaws.com/component**), whether it has the value Helm expects. Empty when the object does not exist.",
+						Description:         "For each Helm metadata key (meta.helm.sh/release-name, meta.helm.sh/release-namespace, app.kubernetes.io/managed-by and the removed eks.amazonaws.com/component), whether it has the value Helm expects. Empty when the object does not exist.",
+						Computed:            true,
+						ElementType:         types.BoolType,
+					},
+					"last_action": schema.StringAttribute{
+						MarkdownDescription: "Last change the job made to the object, `removed` or `adopted`. Empty when the job hasn't changed it.",
+						Description:         "Last change the job made to the object, removed or adopted. Empty when the job hasn't changed it.",
+						Computed:            true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func forceAttribute() schema.Attribute {
+	return schema.BoolAttribute{
+		MarkdownDescription: "Remove **Kube-Proxy** even when no kube-proxy replacement could be detected. Without a replacement every ClusterIP service stops working.",
+		Description:         "Remove Kube-Proxy even when no kube-proxy replacement could be detected. Without a replacement every ClusterIP service stops working.",
+		Optional:            true,
+		Computed:            true,
+		Default:             booldefault.StaticBool(false),
+	}
+}
+
+func acknowledgedAwsCniDependenciesAttribute() schema.Attribute {
+	return schema.SetAttribute{
+		MarkdownDescription: "VPC CNI features that are known to be in use and that may break when **AWS-CNI** is removed. One of `pod_eni` (security groups for pods), `network_policy` (VPC CNI network policy agent) or `custom_networking` (ENIConfig custom networking). Removal is refused while an unacknowledged feature is detected.",
+		Description:         "VPC CNI features that are known to be in use and that may break when AWS-CNI is removed. One of pod_eni (security groups for pods), network_policy (VPC CNI network policy agent) or custom_networking (ENIConfig custom networking). Removal is refused while an unacknowledged feature is detected.",
+		Optional:            true,
+		ElementType:         types.StringType,
+		Validators: []validator.Set{
+			setvalidator.ValueStringsAre(stringvalidator.OneOf(awsCniDependencyPodEni, awsCniDependencyNetworkPolicy, awsCniDependencyCustomNetworking)),
+		},
+	}
+}
+
+func conflictPolicyAttribute() schema.Attribute {
+	return schema.StringAttribute{
+		MarkdownDescription: "What to do when a CoreDNS object is owned by a different Helm release, or the `coredns` Helm release is already installed and doesn't own it. One of `fail` (make no changes), `takeover` (adopt it anyway) or `skip` (leave the conflicting objects alone).",
+		Description:         "What to do when a CoreDNS object is owned by a different Helm release, or the coredns Helm release is already installed and doesn't own it. One of fail (make no changes), takeover (adopt it anyway) or skip (leave the conflicting objects alone).",
+		Optional:            true,
+		Computed:            true,
+		Default:             stringdefault.StaticString(conflictPolicyFail),
+		Validators: []validator.String{
+			stringvalidator.OneOf(conflictPolicyFail, conflictPolicyTakeover, conflictPolicySkip),
+		},
+	}
+}
+
+func helmConflictsAttribute() schema.Attribute {
+	return schema.ListAttribute{
+		MarkdownDescription: "The CoreDNS objects that had a conflicting Helm owner when they were adopted, and who that owner was.",
+		Description:         "The CoreDNS objects that had a conflicting Helm owner when they were adopted, and who that owner was.",
+		Computed:            true,
+		ElementType:         types.StringType,
+	}
+}
+
+func waitForTimeoutAttribute() schema.Attribute {
+	return schema.StringAttribute{
+		MarkdownDescription: "How long to wait for all **wait_for** conditions to hold before giving up, e.g. `10m`.",
+		Description:         "How long to wait for all wait_for conditions to hold before giving up, e.g. 10m.",
+		Optional:            true,
+		Computed:            true,
+		Default:             stringdefault.StaticString("10m"),
+		Validators: []validator.String{
+			DurationValidator(),
+		},
+	}
+}
+
+func waitForBlock() schema.Block {
+	return schema.ListNestedBlock{
+		MarkdownDescription: "Conditions that have to hold before **AWS CNI**, **Kube-Proxy** or **CoreDNS** are removed, e.g. the replacement CNI being ready on every node.",
+		Description:         "Conditions that have to hold before AWS CNI, Kube-Proxy or CoreDNS are removed, e.g. the replacement CNI being ready on every node.",
+		NestedObject: schema.NestedBlockObject{
+			Attributes: map[string]schema.Attribute{
+				"kind": schema.StringAttribute{
+					MarkdownDescription: "Kind of object to wait for. One of `DaemonSet`, `Deployment` or `Node`.",
+					Description:         "Kind of object to wait for. One of DaemonSet, Deployment or Node.",
+					Required:            true,
+					Validators: []validator.String{
+						stringvalidator.OneOf(waitForKindDaemonSet, waitForKindDeployment, waitForKindNode),
+					},
+				},
+				"namespace": schema.StringAttribute{
+					Description: "Namespace of the DaemonSet or Deployment.",
+					Optional:    true,
+					Computed:    true,
+					Default:     stringdefault.StaticString("kube-system"),
+				},
+				"name": schema.StringAttribute{
+					MarkdownDescription: "Name of the DaemonSet or Deployment. For `Node` it optionally selects a single node, otherwise all nodes are checked.",
+					Description:         "Name of the DaemonSet or Deployment. For Node it optionally selects a single node, otherwise all nodes are checked.",
+					Optional:            true,
+				},
+				"min_ready": schema.Int64Attribute{
+					Description: "Minimum number of ready pods (or nodes). Defaults to everything that is desired being ready.",
+					Optional:    true,
+					Validators: []validator.Int64{
+						int64validator.AtLeast(0),
+					},
+				},
+			},
+		},
+	}
+}
+
+func admissionGuardBlock() schema.Block {
+	return schema.ListNestedBlock{
+		MarkdownDescription: "Install a ValidatingAdmissionPolicy and binding that deny creating the removed `kube-system/aws-node` and `kube-system/kube-proxy` daemonsets, and the objects of the `object` blocks, again with the **eks.amazonaws.com/component** label, e.g. by an EKS platform upgrade or an add-on install. Objects without the label, such as a replacement installed by a Helm chart, are let through. Removing the block or the job deletes the policy and binding. Needs Kubernetes 1.30 or later.",
+		Description:         "Install a ValidatingAdmissionPolicy and binding that deny creating the removed kube-system/aws-node and kube-system/kube-proxy daemonsets, and the objects of the object blocks, again with the eks.amazonaws.com/component label, e.g. by an EKS platform upgrade or an add-on install. Objects without the label, such as a replacement installed by a Helm chart, are let through. Removing the block or the job deletes the policy and binding. Needs Kubernetes 1.30 or later.",
+		Validators: []validator.List{
+			listvalidator.SizeAtMost(1),
+		},
+		NestedObject: schema.NestedBlockObject{
+			Attributes: map[string]schema.Attribute{
+				"name": schema.StringAttribute{
+					MarkdownDescription: "Name of the policy and the binding. Defaults to `cleaneks-guard`.",
+					Description:         "Name of the policy and the binding. Defaults to cleaneks-guard.",
+					Optional:            true,
+					Computed:            true,
+					Default:             stringdefault.StaticString(defaultAdmissionGuardName),
+				},
+			},
+			Blocks: map[string]schema.Block{
+				"object": schema.ListNestedBlock{
+					Description: "Another object that must not be created again with the EKS component label.",
+					NestedObject: schema.NestedBlockObject{
+						Attributes: map[string]schema.Attribute{
+							"kind": schema.StringAttribute{
+								MarkdownDescription: "Kind of the object. One of `DaemonSet`, `Deployment`, `Service`, `ServiceAccount`, `ConfigMap` or `PodDisruptionBudget`.",
+								Description:         "Kind of the object. One of DaemonSet, Deployment, Service, ServiceAccount, ConfigMap or PodDisruptionBudget.",
+								Required:            true,
+								Validators: []validator.String{
+									stringvalidator.OneOf("DaemonSet", "Deployment", "Service", "ServiceAccount", "ConfigMap", "PodDisruptionBudget"),
+								},
+							},
+							"namespace": schema.StringAttribute{
+								Description: "Namespace of the object.",
+								Optional:    true,
+								Computed:    true,
+								Default:     stringdefault.StaticString("kube-system"),
+							},
+							"name": schema.StringAttribute{
+								Description: "Name of the object.",
+								Required:    true,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func kubeProxyReplacementCheckBlock() schema.Block {
+	return schema.ListNestedBlock{
+		MarkdownDescription: "Additional ways to detect a kube-proxy replacement before **Kube-Proxy** is removed. Cilium with `kube-proxy-replacement` enabled in `kube-system/cilium-config` is always detected.",
+		Description:         "Additional ways to detect a kube-proxy replacement before Kube-Proxy is removed. Cilium with kube-proxy-replacement enabled in kube-system/cilium-config is always detected.",
+		NestedObject: schema.NestedBlockObject{
+			Attributes: map[string]schema.Attribute{
+				"name": schema.StringAttribute{
+					Description: "Name of the replacement, used in error messages.",
+					Optional:    true,
+				},
+				"config_map_namespace": schema.StringAttribute{
+					Description: "Namespace of the config map that enables the replacement.",
+					Optional:    true,
+					Computed:    true,
+					Default:     stringdefault.StaticString("kube-system"),
+				},
+				"config_map_name": schema.StringAttribute{
+					Description: "Name of the config map that enables the replacement. When not set only the daemonset is checked.",
+					Optional:    true,
+				},
+				"config_map_key": schema.StringAttribute{
+					Description: "Key in the config map that enables the replacement.",
+					Optional:    true,
+				},
+				"expected_values": schema.ListAttribute{
+					Description: "Values of the config map key that mean the replacement is enabled, compared case-insensitively.",
+					Optional:    true,
+					ElementType: types.StringType,
+				},
+				"daemonset_namespace": schema.StringAttribute{
+					Description: "Namespace of the daemonset that runs the replacement.",
+					Optional:    true,
+					Computed:    true,
+					Default:     stringdefault.StaticString("kube-system"),
+				},
+				"daemonset_name": schema.StringAttribute{
+					Description: "Name of the daemonset that runs the replacement. It has to have a ready pod on every node.",
+					Required:    true,
+				},
+			},
+		},
+	}
+}
+
+// validateWaitFor checks the wait_for blocks that the schema can't.
+func validateWaitFor(waitFor []JobWaitForModel) (diags diag.Diagnostics) {
+	for i, condition := range waitFor {
+		if condition.Kind.ValueString() == waitForKindNode || condition.Name.IsUnknown() {
+			continue
+		}
+		if condition.Name.ValueString() == "" {
+			diags.AddAttributeError(
+				path.Root("wait_for").AtListIndex(i).AtName("name"),
+				"Missing wait_for name",
+				fmt.Sprintf("A name is required when waiting for a %s.", condition.Kind.ValueString()),
+			)
+		}
+	}
+	return diags
+}
+
+// validateKubeProxyReplacementChecks checks the kube_proxy_replacement_check blocks that the schema can't.
+func validateKubeProxyReplacementChecks(checks []JobKubeProxyReplacementCheckModel) (diags diag.Diagnostics) {
+	for i, check := range checks {
+		if check.ConfigMapName.IsNull() || check.ConfigMapName.IsUnknown() {
+			continue
+		}
+		if check.ConfigMapKey.IsNull() || check.ExpectedValues.IsNull() {
+			diags.AddAttributeError(
+				path.Root("kube_proxy_replacement_check").AtListIndex(i),
+				"Incomplete kube_proxy_replacement_check",
+				"config_map_key and expected_values are required when config_map_name is set.",
+			)
+		}
+	}
+	return diags
+}
+
+// mergeAttributes combines schema attributes, later maps win.
+func mergeAttributes(attributes ...map[string]schema.Attribute) map[string]schema.Attribute {
+	merged := map[string]schema.Attribute{}
+	for _, m := range attributes {
+		for name, attribute := range m {
+			merged[name] = attribute
+		}
+	}
+	return merged
+}
+
+// componentResult is what a component resource found in the cluster after applying or reading it.
+type componentResult struct {
+	// Run is nil when nothing was applied
+	Run        *JobRun
+	Identity   ClusterIdentity
+	Components types.List
+	Host       string
+}
+
+// applyComponent pins the cluster identity, runs the steps for options when it isn't nil, and checks the objects of the
+// component. A nil result without errors means the cluster can't be reached yet during a read.
+func applyComponent(ctx context.Context, p *CleanEksProvider, operation string, id types.String, pinned ClusterIdentity, allowReplacement bool, options *JobOptions, objects []ClusterObject, previous types.List) (result *componentResult, diags diag.Diagnostics) {
+	if p == nil {
+		diags.AddError(
+			"Provider not configured",
+			fmt.Sprintf("Provider not configured"),
+		)
+		return nil, diags
+	}
+
+	clientSet, err := p.ResourceClientSet(ctx, id, operation)
+	if err != nil {
+		if options == nil && errors.Is(err, clientcmd.ErrEmptyConfig) && p.model.Host.IsUnknown() {
+			// We don't want to throw error here as we EKS cluster might not exist yet
+			diags.Append(diag.NewWarningDiagnostic("Host configuration is not know yet. Provider operations likely to fail. Failed to initialize Kubernetes client configuration, this could be because credentials are not available during provider initialization", err.Error()))
+			return nil, diags
+		}
+		diags.AddError(
+			fmt.Sprintf("Error getting Kubernetes client during %s", operation),
+			fmt.Sprintf("Error getting Kubernetes client during %s: %s", operation, err),
+		)
+		return nil, diags
+	}
+
+	result = &componentResult{Host: p.Host()}
+
+	// Changes are often made straight after the cluster is created, so it is waited for before the identity is read
+	if options != nil {
+		diags.Append(waitForApiServer(ctx, clientSet, options.ReadyTimeout)...)
+		if diags.HasError() {
+			return nil, diags
+		}
+	}
+
+	// A read keeps going to report every failure, changes are only made once the cluster identity is confirmed
+	identity, identityDiags := checkClusterIdentity(ctx, clientSet, pinned, allowReplacement, p.EksClusterArn())
+	diags.Append(identityDiags...)
+	if diags.HasError() && options != nil {
+		return nil, diags
+	}
+	result.Identity = identity
+
+	var actions map[ClusterObject]string
+	if options != nil {
+		options.ClusterArn = identity.Arn
+		run, runDiags := RunJob(ctx, clientSet, p.Version, *options)
+		diags.Append(runDiags...)
+		if diags.HasError() {
+			return nil, diags
+		}
+		result.Run = run
+		actions = run.Audit.Actions()
+	}
+
+	statuses, errs := CheckComponents(ctx, clientSet, objects)
+	diags.Append(errs.Diagnostics()...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	components, componentsDiags := componentsValue(ctx, objects, statuses, previous, actions)
+	diags.Append(componentsDiags...)
+	result.Components = components
+	return result, diags
+}
+
+// RequiredPermissions returns the required_permissions of a component resource.
+func (r *componentResult) RequiredPermissions() types.List {
+	if r.Run == nil {
+		return StringsToList(nil)
+	}
+	return StringsToList(PermissionsToStrings(RequiredPermissions(r.Run.Options)))
+}
+
+// DryRunChanges returns the dry_run_changes of a component resource.
+func (r *componentResult) DryRunChanges() types.List {
+	if r.Run == nil || !r.Run.Options.DryRun {
+		return StringsToList(nil)
+	}
+	return StringsToList(r.Run.Changes)
+}
@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestPlanDrift(t *testing.T) {
+	ctx := context.Background()
+	planSchema := schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id":         schema.StringAttribute{Computed: true},
+			"dry_run":    schema.BoolAttribute{Optional: true},
+			"verify_eks": schema.BoolAttribute{Optional: true, Computed: true},
+			"exists":     schema.BoolAttribute{Computed: true},
+			"components": schema.ListAttribute{ElementType: types.StringType, Computed: true},
+		},
+	}
+	objectType := planSchema.Type().TerraformType(ctx).(tftypes.Object)
+	componentsType := tftypes.List{ElementType: tftypes.String}
+	plan := func(exists tftypes.Value, components tftypes.Value) tftypes.Value {
+		return tftypes.NewValue(objectType, map[string]tftypes.Value{
+			"id":         tftypes.NewValue(tftypes.String, "https://example.com"),
+			"dry_run":    tftypes.NewValue(tftypes.Bool, nil),
+			"verify_eks": tftypes.NewValue(tftypes.Bool, true),
+			"exists":     exists,
+			"components": components,
+		})
+	}
+	known := plan(tftypes.NewValue(tftypes.Bool, true), tftypes.NewValue(componentsType, []tftypes.Value{tftypes.NewValue(tftypes.String, "aws-node")}))
+
+	tests := []struct {
+		name  string
+		drift []string
+		want  tftypes.Value
+	}{
+		{
+			name: "no drift",
+			want: known,
+		},
+		{
+			name:  "drift",
+			drift: []string{"aws_cni"},
+			want:  plan(tftypes.NewValue(tftypes.Bool, tftypes.UnknownValue), tftypes.NewValue(componentsType, tftypes.UnknownValue)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &resource.ModifyPlanResponse{Plan: tfsdk.Plan{Schema: planSchema, Raw: known.Copy()}}
+			planDrift(ctx, resp, test.drift)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+			}
+			if !resp.Plan.Raw.Equal(test.want) {
+				t.Errorf("planDrift()\n got: %s\nwant: %s", resp.Plan.Raw, test.want)
+			}
+		})
+	}
+}
+
+func TestComponentStatuses(t *testing.T) {
+	ctx := context.Background()
+	statuses := map[ClusterObject]ComponentStatus{
+		AwsCniObjects[0]: {Object: AwsCniObjects[0], Exists: true, IsEksManaged: true, IsAddonManaged: true, Adoption: map[string]bool{}},
+		CorednsHelmObjects[0]: {Object: CorednsHelmObjects[0], Exists: true, Adoption: map[string]bool{
+			helmReleaseNameAnnotationName:      true,
+			helmReleaseNamespaceAnnotationName: false,
+		}},
+		KubeProxyObjects[0]: {Object: KubeProxyObjects[0], Adoption: map[string]bool{}},
+	}
+	objects := []ClusterObject{AwsCniObjects[0], CorednsHelmObjects[0], KubeProxyObjects[0]}
+
+	components, diags := componentsValue(ctx, objects, statuses, types.ListNull(types.ObjectType{AttrTypes: jobComponentAttributeTypes}), nil)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	got, diags := componentStatuses(ctx, components)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if !reflect.DeepEqual(got, statuses) {
+		t.Errorf("componentStatuses()\n got: %v\nwant: %v", got, statuses)
+	}
+
+	got, diags = componentStatuses(ctx, types.ListNull(types.ObjectType{AttrTypes: jobComponentAttributeTypes}))
+	if diags.HasError() || len(got) != 0 {
+		t.Errorf("componentStatuses() of a null list = %v, %v, want no statuses", got, diags)
+	}
+}
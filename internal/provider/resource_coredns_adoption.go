@@ -0,0 +1,238 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &CorednsAdoptionResource{}
+var _ resource.ResourceWithImportState = &CorednsAdoptionResource{}
+var _ resource.ResourceWithModifyPlan = &CorednsAdoptionResource{}
+
+func NewCorednsAdoptionResource() resource.Resource {
+	return &CorednsAdoptionResource{}
+}
+
+// CorednsAdoptionResource adopts CoreDNS into Helm on its own, so that it can be ordered before the Helm release is
+// installed.
+type CorednsAdoptionResource struct {
+	provider *CleanEksProvider
+}
+
+type CorednsAdoptionResourceModel struct {
+	ID types.String `tfsdk:"id"`
+
+	DryRun types.Bool `tfsdk:"dry_run"`
+
+	VerifyEks           types.Bool   `tfsdk:"verify_eks"`
+	ExpectedClusterName types.String `tfsdk:"expected_cluster_name"`
+	ExpectedClusterArn  types.String `tfsdk:"expected_cluster_arn"`
+
+	AllowClusterReplacement types.Bool   `tfsdk:"allow_cluster_replacement"`
+	ClusterUid              types.String `tfsdk:"cluster_uid"`
+	ClusterArn              types.String `tfsdk:"cluster_arn"`
+
+	LockLeaseName types.String `tfsdk:"lock_lease_name"`
+	LockTimeout   types.String `tfsdk:"lock_timeout"`
+
+	ConflictPolicy types.String `tfsdk:"conflict_policy"`
+	HelmConflicts  types.List   `tfsdk:"helm_conflicts"`
+
+	DryRunChanges types.List `tfsdk:"dry_run_changes"`
+
+	RequiredPermissions types.List `tfsdk:"required_permissions"`
+
+	Components types.List `tfsdk:"components"`
+}
+
+// Options returns the settings of the adoption for the step engine.
+func (m CorednsAdoptionResourceModel) Options(p *CleanEksProvider) (options JobOptions, diags diag.Diagnostics) {
+	options = JobOptions{
+		Coredns:             componentActionAdopt,
+		DryRun:              dryRunValue(p, m.DryRun),
+		ReadyTimeout:        p.ReadyTimeout(),
+		ReadObjects:         CorednsHelmObjects,
+		VerifyEks:           m.VerifyEks.ValueBool(),
+		ExpectedClusterName: m.ExpectedClusterName.ValueString(),
+		ExpectedClusterArn:  m.ExpectedClusterArn.ValueString(),
+		ConflictPolicy:      m.ConflictPolicy.ValueString(),
+		LockLeaseName:       m.LockLeaseName.ValueString(),
+	}
+
+	options.LockTimeout, diags = durationValue("lock_timeout", m.LockTimeout)
+	return options, diags
+}
+
+// Store records what was found in the cluster.
+func (m *CorednsAdoptionResourceModel) Store(result *componentResult) {
+	m.ID = types.StringValue(result.Host)
+	m.ClusterUid = types.StringValue(result.Identity.Uid)
+	m.ClusterArn = types.StringValue(result.Identity.Arn)
+	m.Components = result.Components
+	if result.Run != nil || m.DryRunChanges.IsUnknown() || m.DryRunChanges.IsNull() {
+		m.DryRunChanges = result.DryRunChanges()
+	}
+	if result.Run != nil || m.RequiredPermissions.IsUnknown() || m.RequiredPermissions.IsNull() {
+		m.RequiredPermissions = result.RequiredPermissions()
+	}
+	if result.Run != nil && result.Run.HelmConflicts != nil {
+		m.HelmConflicts = StringsToList(result.Run.HelmConflicts)
+	}
+	if m.HelmConflicts.IsUnknown() || m.HelmConflicts.IsNull() {
+		m.HelmConflicts = StringsToList(nil)
+	}
+}
+
+func (r *CorednsAdoptionResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_coredns_adoption"
+}
+
+func (r *CorednsAdoptionResource) Schema(_ context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Imports the CoreDNS deployment, service and the objects that go with them into Helm. By importing " +
+			"CoreDNS into Helm, we don't loose DNS at any point and we can manage CoreDNS using a Helm chart.",
+		Attributes: mergeAttributes(runAttributes("Status of every CoreDNS object that is adopted."), map[string]schema.Attribute{
+			"conflict_policy": conflictPolicyAttribute(),
+
+			"helm_conflicts": helmConflictsAttribute(),
+		}),
+	}
+}
+
+func (r *CorednsAdoptionResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	cleanEksProvider, diags := configureProvider(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+	if cleanEksProvider != nil {
+		r.provider = cleanEksProvider
+	}
+}
+
+func (r *CorednsAdoptionResource) Create(ctx context.Context, req resource.CreateRequest, res *resource.CreateResponse) {
+	tflog.Debug(ctx, "Adopting CoreDNS")
+
+	var model CorednsAdoptionResourceModel
+	res.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+	tflog.Debug(ctx, "Loaded CoreDNS adoption configuration", map[string]interface{}{
+		"config": fmt.Sprintf("%+v", model),
+	})
+
+	res.Diagnostics.Append(r.apply(ctx, "CorednsAdoptionResource.Create", CorednsAdoptionResourceModel{}, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+func (r *CorednsAdoptionResource) Read(ctx context.Context, req resource.ReadRequest, res *resource.ReadResponse) {
+	tflog.Debug(ctx, "Reading CoreDNS adoption")
+
+	var model CorednsAdoptionResourceModel
+	res.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	pinned := ClusterIdentity{Uid: model.ClusterUid.ValueString(), Arn: model.ClusterArn.ValueString()}
+	result, diags := applyComponent(ctx, r.provider, "CorednsAdoptionResource.Read", model.ID, pinned, model.AllowClusterReplacement.ValueBool(), nil, CorednsHelmObjects, model.Components)
+	res.Diagnostics.Append(diags...)
+	if res.Diagnostics.HasError() || result == nil {
+		return
+	}
+
+	model.Store(result)
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+// ModifyPlan plans an update when the state read from the cluster shows that the Helm metadata of CoreDNS is gone,
+// e.g. after an EKS add-on update, so that it is adopted again.
+func (r *CorednsAdoptionResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing drifts before the adoption is created or once it is destroyed
+	if r.provider == nil || req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var state CorednsAdoptionResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	// A dry run never adopted it
+	if resp.Diagnostics.HasError() || dryRunValue(r.provider, state.DryRun) {
+		return
+	}
+
+	statuses, diags := componentStatuses(ctx, state.Components)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	options := JobOptions{Coredns: componentActionAdopt, ConflictPolicy: state.ConflictPolicy.ValueString()}
+	planDrift(ctx, resp, UnsatisfiedActions(options, statuses, StringListToStrings(state.HelmConflicts)))
+}
+
+func (r *CorednsAdoptionResource) Update(ctx context.Context, req resource.UpdateRequest, res *resource.UpdateResponse) {
+	tflog.Debug(ctx, "Updating CoreDNS adoption")
+
+	var model CorednsAdoptionResourceModel
+	res.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	var state CorednsAdoptionResourceModel
+	res.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	res.Diagnostics.Append(r.apply(ctx, "CorednsAdoptionResource.Update", state, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+func (r *CorednsAdoptionResource) Delete(ctx context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+	// NO-OP: Returning no error is enough for the framework to remove the resource from state.
+	tflog.Debug(ctx, "Removing CoreDNS adoption from state")
+}
+
+func (r *CorednsAdoptionResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// apply adopts CoreDNS and populates the model. state is the prior state, empty on create.
+func (r *CorednsAdoptionResource) apply(ctx context.Context, operation string, state CorednsAdoptionResourceModel, model *CorednsAdoptionResourceModel) (diags diag.Diagnostics) {
+	if r.provider == nil {
+		diags.AddError(
+			"Provider not configured",
+			fmt.Sprintf("Provider not configured"),
+		)
+		return diags
+	}
+
+	options, diags := model.Options(r.provider)
+	if diags.HasError() {
+		return diags
+	}
+
+	pinned := ClusterIdentity{Uid: state.ClusterUid.ValueString(), Arn: state.ClusterArn.ValueString()}
+	result, applyDiags := applyComponent(ctx, r.provider, operation, model.ID, pinned, model.AllowClusterReplacement.ValueBool(), &options, CorednsHelmObjects, state.Components)
+	diags.Append(applyDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	model.Store(result)
+	return diags
+}
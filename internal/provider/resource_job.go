@@ -2,25 +2,47 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &JobResource{}
 var _ resource.ResourceWithImportState = &JobResource{}
+var _ resource.ResourceWithValidateConfig = &JobResource{}
+var _ resource.ResourceWithUpgradeState = &JobResource{}
+var _ resource.ResourceWithModifyPlan = &JobResource{}
+
+// Default timeouts of the job. Create and update include waiting for the lease lock and the wait_for conditions.
+const (
+	jobCreateTimeout = 30 * time.Minute
+	jobReadTimeout   = 5 * time.Minute
+	jobUpdateTimeout = 30 * time.Minute
+	jobDeleteTimeout = 5 * time.Minute
+)
+
+// jobReadObjects are the objects the job reads to report on them. The kubernetes service is read to derive the CoreDNS
+// service cluster IP when the CoreDNS service doesn't exist.
+var jobReadObjects = append(append([]ClusterObject{}, AuditedObjects...), ClusterObject{Kind: "Service", Namespace: "default", Name: "kubernetes"})
 
 func NewJobResource() resource.Resource {
 	return &JobResource{}
@@ -33,10 +55,47 @@ type JobResource struct {
 type JobResourceModel struct {
 	ID types.String `tfsdk:"id"`
 
-	RemoveAwsCni        types.Bool `tfsdk:"remove_aws_cni"`
-	RemoveKubeProxy     types.Bool `tfsdk:"remove_kube_proxy"`
-	RemoveCoreDns       types.Bool `tfsdk:"remove_core_dns"`
-	ImportCorednsToHelm types.Bool `tfsdk:"import_coredns_to_helm"`
+	AwsCni    *JobComponentActionModel `tfsdk:"aws_cni"`
+	KubeProxy *JobComponentActionModel `tfsdk:"kube_proxy"`
+	Coredns   *JobComponentActionModel `tfsdk:"coredns"`
+
+	DryRun types.Bool `tfsdk:"dry_run"`
+	Force  types.Bool `tfsdk:"force"`
+
+	ManageEksAddons      types.Bool `tfsdk:"manage_eks_addons"`
+	StrictAddonOwnership types.Bool `tfsdk:"strict_addon_ownership"`
+
+	VerifyEks           types.Bool   `tfsdk:"verify_eks"`
+	ExpectedClusterName types.String `tfsdk:"expected_cluster_name"`
+	ExpectedClusterArn  types.String `tfsdk:"expected_cluster_arn"`
+
+	AllowClusterReplacement types.Bool   `tfsdk:"allow_cluster_replacement"`
+	ClusterUid              types.String `tfsdk:"cluster_uid"`
+	ClusterArn              types.String `tfsdk:"cluster_arn"`
+
+	ConflictPolicy types.String `tfsdk:"conflict_policy"`
+	HelmConflicts  types.List   `tfsdk:"helm_conflicts"`
+
+	LockLeaseName types.String `tfsdk:"lock_lease_name"`
+	LockTimeout   types.String `tfsdk:"lock_timeout"`
+
+	AcknowledgedAwsCniDependencies types.Set `tfsdk:"acknowledged_aws_cni_dependencies"`
+
+	KubeProxyReplacementChecks []JobKubeProxyReplacementCheckModel `tfsdk:"kube_proxy_replacement_check"`
+
+	WaitFor        []JobWaitForModel `tfsdk:"wait_for"`
+	WaitForTimeout types.String      `tfsdk:"wait_for_timeout"`
+
+	AdmissionGuard       []JobAdmissionGuardModel `tfsdk:"admission_guard"`
+	AdmissionGuardExists types.Bool               `tfsdk:"admission_guard_exists"`
+
+	DryRunChanges types.List `tfsdk:"dry_run_changes"`
+
+	RequiredPermissions types.List `tfsdk:"required_permissions"`
+
+	Components types.List `tfsdk:"components"`
+
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 
 	AwsCniDaemonsetExists    types.Bool `tfsdk:"aws_cni_daemonset_exists"`
 	KubeProxyDaemonsetExists types.Bool `tfsdk:"kube_proxy_daemonset_exists"`
@@ -75,50 +134,185 @@ type JobResourceModel struct {
 	CorednsPodDistruptionBudgetLabelAmazonManagedRemoved    types.Bool `tfsdk:"coredns_pod_disruption_budget_label_amazon_managed_removed"`
 }
 
+type JobWaitForModel struct {
+	Kind      types.String `tfsdk:"kind"`
+	Namespace types.String `tfsdk:"namespace"`
+	Name      types.String `tfsdk:"name"`
+	MinReady  types.Int64  `tfsdk:"min_ready"`
+}
+
+func (m JobWaitForModel) Condition() WaitCondition {
+	condition := WaitCondition{
+		Kind:      m.Kind.ValueString(),
+		Namespace: m.Namespace.ValueString(),
+		Name:      m.Name.ValueString(),
+	}
+	if !(m.MinReady.IsNull() || m.MinReady.IsUnknown()) {
+		minReady := m.MinReady.ValueInt64()
+		condition.MinReady = &minReady
+	}
+	return condition
+}
+
+// JobAdmissionGuardModel is the policy that stops the removed components from being created again.
+type JobAdmissionGuardModel struct {
+	Name    types.String                   `tfsdk:"name"`
+	Objects []JobAdmissionGuardObjectModel `tfsdk:"object"`
+}
+
+type JobAdmissionGuardObjectModel struct {
+	Kind      types.String `tfsdk:"kind"`
+	Namespace types.String `tfsdk:"namespace"`
+	Name      types.String `tfsdk:"name"`
+}
+
+// admissionGuard returns the guard of the admission_guard block, nil when the block is absent.
+func (m JobResourceModel) admissionGuard() *AdmissionGuard {
+	return admissionGuardValue(m.AdmissionGuard, m.AwsCni.ValueAction(), m.KubeProxy.ValueAction())
+}
+
+// admissionGuardValue returns the guard of an admission_guard block for the component actions, nil when the block is
+// absent.
+func admissionGuardValue(blocks []JobAdmissionGuardModel, awsCni string, kubeProxy string) *AdmissionGuard {
+	if len(blocks) == 0 {
+		return nil
+	}
+	extra := make([]ClusterObject, 0, len(blocks[0].Objects))
+	for _, object := range blocks[0].Objects {
+		namespace := object.Namespace.ValueString()
+		if namespace == "" {
+			namespace = "kube-system"
+		}
+		extra = append(extra, ClusterObject{Kind: object.Kind.ValueString(), Namespace: namespace, Name: object.Name.ValueString()})
+	}
+	name := blocks[0].Name.ValueString()
+	if name == "" {
+		name = defaultAdmissionGuardName
+	}
+	return &AdmissionGuard{
+		Name:    name,
+		Objects: AdmissionGuardObjects(awsCni, kubeProxy, extra),
+	}
+}
+
+// validateAdmissionGuard rejects an admission_guard block that has nothing to protect, neither a removed daemonset nor
+// an object of its own.
+func validateAdmissionGuard(blocks []JobAdmissionGuardModel, awsCni *JobComponentActionModel, kubeProxy *JobComponentActionModel) (diags diag.Diagnostics) {
+	actionUnknown := (awsCni != nil && awsCni.Action.IsUnknown()) || (kubeProxy != nil && kubeProxy.Action.IsUnknown())
+	if len(blocks) > 0 && len(blocks[0].Objects) == 0 && !actionUnknown &&
+		awsCni.ValueAction() != componentActionRemove && kubeProxy.ValueAction() != componentActionRemove {
+		diags.AddAttributeError(
+			path.Root("admission_guard"),
+			"Invalid attribute combination",
+			"admission_guard only applies when aws_cni.action or kube_proxy.action is \"remove\", or when it has object blocks.",
+		)
+	}
+	return diags
+}
+
+// JobComponentActionModel is what the job does with a component.
+type JobComponentActionModel struct {
+	Action types.String `tfsdk:"action"`
+}
+
+// ValueAction returns the configured action, keep when the component block is absent.
+func (m *JobComponentActionModel) ValueAction() string {
+	if m == nil || m.Action.IsNull() || m.Action.IsUnknown() {
+		return componentActionKeep
+	}
+	return m.Action.ValueString()
+}
+
+// JobComponentModel is the status of one object the job removes or adopts.
+type JobComponentModel struct {
+	Kind           types.String `tfsdk:"kind"`
+	Namespace      types.String `tfsdk:"namespace"`
+	Name           types.String `tfsdk:"name"`
+	Exists         types.Bool   `tfsdk:"exists"`
+	IsEksManaged   types.Bool   `tfsdk:"is_eks_managed"`
+	IsAddonManaged types.Bool   `tfsdk:"is_addon_managed"`
+	Adoption       types.Map    `tfsdk:"adoption"`
+	LastAction     types.String `tfsdk:"last_action"`
+}
+
+var jobComponentAttributeTypes = map[string]attr.Type{
+	"kind":             types.StringType,
+	"namespace":        types.StringType,
+	"name":             types.StringType,
+	"exists":           types.BoolType,
+	"is_eks_managed":   types.BoolType,
+	"is_addon_managed": types.BoolType,
+	"adoption":         types.MapType{ElemType: types.BoolType},
+	"last_action":      types.StringType,
+}
+
+type JobKubeProxyReplacementCheckModel struct {
+	Name               types.String `tfsdk:"name"`
+	ConfigMapNamespace types.String `tfsdk:"config_map_namespace"`
+	ConfigMapName      types.String `tfsdk:"config_map_name"`
+	ConfigMapKey       types.String `tfsdk:"config_map_key"`
+	ExpectedValues     types.List   `tfsdk:"expected_values"`
+	DaemonsetNamespace types.String `tfsdk:"daemonset_namespace"`
+	DaemonsetName      types.String `tfsdk:"daemonset_name"`
+}
+
+func (m JobKubeProxyReplacementCheckModel) Check() KubeProxyReplacementCheck {
+	name := m.Name.ValueString()
+	if name == "" {
+		name = fmt.Sprintf("%s/%s", m.DaemonsetNamespace.ValueString(), m.DaemonsetName.ValueString())
+	}
+	return KubeProxyReplacementCheck{
+		Name:               name,
+		ConfigMapNamespace: m.ConfigMapNamespace.ValueString(),
+		ConfigMapName:      m.ConfigMapName.ValueString(),
+		ConfigMapKey:       m.ConfigMapKey.ValueString(),
+		ExpectedValues:     StringListToStrings(m.ExpectedValues),
+		DaemonsetNamespace: m.DaemonsetNamespace.ValueString(),
+		DaemonsetName:      m.DaemonsetName.ValueString(),
+	}
+}
+
 func (r *JobResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_job"
 }
 
-func (r *JobResource) Schema(_ context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+func (r *JobResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version: 1,
 		Description: "Cleans an EKS cluster of default AWS-CNI, Kube-Proxy and imports CoreDNS deployment " +
 			"and service into Helm. By importing CoreDNS into Helm, we don't loose DNS at any point and we " +
 			"can manage CoreDNS using a Helm chart.",
-		Attributes: map[string]schema.Attribute{
-			"id": schema.StringAttribute{
-				Description: `ID of the job.`,
-				Computed:    true,
-			},
+		Attributes: mergeAttributes(runAttributes("Status of every object the job removes or adopts."), map[string]schema.Attribute{
+			"acknowledged_aws_cni_dependencies": acknowledgedAwsCniDependenciesAttribute(),
 
-			"remove_aws_cni": schema.BoolAttribute{
-				MarkdownDescription: "Remove **AWS-CNI** from EKS cluster",
-				Description:         "Remove AWS-CNI from EKS cluster",
-				Optional:            true,
-				Computed:            true,
-				Default:             booldefault.StaticBool(true),
-			},
+			"force": forceAttribute(),
+
+			"conflict_policy": conflictPolicyAttribute(),
+
+			"helm_conflicts": helmConflictsAttribute(),
+
+			"wait_for_timeout": waitForTimeoutAttribute(),
 
-			"remove_kube_proxy": schema.BoolAttribute{
-				MarkdownDescription: "Remove **Kube-Proxy** from EKS cluster",
-				Description:         "Remove Kube-Proxy from EKS cluster",
+			"manage_eks_addons": schema.BoolAttribute{
+				MarkdownDescription: "Release the components from their EKS managed add-ons (`vpc-cni`, `kube-proxy` and `coredns`), as EKS puts back deleted objects the next time an add-on reconciles. Before a component is removed its add-on is deleted, before CoreDNS is adopted its add-on is deleted with `preserve` so that the objects are kept, and once CoreDNS is restored the add-on is created again. Every change waits for the add-on status. Uses the provider `cluster_name`, or **expected_cluster_name**, and `eks_endpoint`.",
+				Description:         "Release the components from their EKS managed add-ons (vpc-cni, kube-proxy and coredns), as EKS puts back deleted objects the next time an add-on reconciles. Before a component is removed its add-on is deleted, before CoreDNS is adopted its add-on is deleted with preserve so that the objects are kept, and once CoreDNS is restored the add-on is created again. Every change waits for the add-on status. Uses the provider cluster_name, or expected_cluster_name, and eks_endpoint.",
 				Optional:            true,
 				Computed:            true,
-				Default:             booldefault.StaticBool(true),
+				Default:             booldefault.StaticBool(false),
 			},
 
-			"remove_core_dns": schema.BoolAttribute{
-				MarkdownDescription: "Remove **CoreDNS** from EKS cluster",
-				Description:         "Remove CoreDNS from EKS cluster",
+			"strict_addon_ownership": schema.BoolAttribute{
+				MarkdownDescription: "Fail instead of warning when objects that are about to be removed or adopted are owned by an EKS managed add-on, which undoes the change the next time it reconciles. Ownership is read from the `eks` field manager in **metadata.managedFields**. Not checked with `manage_eks_addons`, which releases the add-ons first.",
+				Description:         "Fail instead of warning when objects that are about to be removed or adopted are owned by an EKS managed add-on, which undoes the change the next time it reconciles. Ownership is read from the eks field manager in metadata.managedFields. Not checked with manage_eks_addons, which releases the add-ons first.",
 				Optional:            true,
 				Computed:            true,
-				Default:             booldefault.StaticBool(true),
+				Default:             booldefault.StaticBool(false),
 			},
 
-			"import_coredns_to_helm": schema.BoolAttribute{
-				Description: "Add helm attributes to CoreDns service and deployment, so that it can be managed by Helm.",
-				Optional:    true,
-				Computed:    true,
-				Default:     booldefault.StaticBool(false),
+			"admission_guard_exists": schema.BoolAttribute{
+				MarkdownDescription: "Do the ValidatingAdmissionPolicy and binding of `admission_guard` exist. When they were deleted, the next plan updates the job to install them again.",
+				Description:         "Do the ValidatingAdmissionPolicy and binding of admission_guard exist. When they were deleted, the next plan updates the job to install them again.",
+				Computed:            true,
 			},
 
 			"aws_cni_daemonset_exists": schema.BoolAttribute{
@@ -180,142 +374,381 @@ func (r *JobResource) Schema(_ context.Context, req resource.SchemaRequest, resp
 				MarkdownDescription: "Does CoreDNS deployment have label **meta.helm.sh/release-name** with value of **coredns**. Returns **true** if deployment does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS deployment have label meta.helm.sh/release-name with value of coredns. Returns true if deployment does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_deployment_label_helm_release_namespace_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS deployment have label **meta.helm.sh/release-namespace** with value of **kube-system**. Returns **true** if deployment does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS deployment have label meta.helm.sh/release-namespace with value of kube-system. Returns true if deployment does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_deployment_label_managed_by_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS deployment have label **app.kubernetes.io/managed-by** with value of **Helm**. Returns **true** if deployment does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS deployment have label app.kubernetes.io/managed-by with value of Helm. Returns true if deployment does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_deployment_label_amazon_managed_removed": schema.BoolAttribute{
 				MarkdownDescription: "Is label **eks.amazonaws.com/component** removed. Returns **true** if deployment does not exist as Helm chart can be deployed.",
 				Description:         "Is label eks.amazonaws.com/component removed. Returns true if deployment does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_service_label_helm_release_name_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **meta.helm.sh/release-name** with value of **coredns**. Returns **true** if service does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label meta.helm.sh/release-name with value of coredns. Returns true if service does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_service_label_helm_release_namespace_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **meta.helm.sh/release-namespace** with value of **kube-system**. Returns **true** if service does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label meta.helm.sh/release-namespace with value of kube-system. Returns true if service does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_service_label_managed_by_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **app.kubernetes.io/managed-by** with value of **Helm**. Returns **true** if service does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label app.kubernetes.io/managed-by with value of Helm. Returns true if service does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_service_label_amazon_managed_removed": schema.BoolAttribute{
 				MarkdownDescription: "Is label **eks.amazonaws.com/component** removed. Returns **true** if service does not exist as Helm chart can be deployed.",
 				Description:         "Is label eks.amazonaws.com/component removed. Returns true if service does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_service_account_label_helm_release_name_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **meta.helm.sh/release-name** with value of **coredns**. Returns **true** if service account does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label meta.helm.sh/release-name with value of coredns. Returns true if service account does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_service_account_label_helm_release_namespace_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **meta.helm.sh/release-namespace** with value of **kube-system**. Returns **true** if service account does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label meta.helm.sh/release-namespace with value of kube-system. Returns true if service account does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_service_account_label_managed_by_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **app.kubernetes.io/managed-by** with value of **Helm**. Returns **true** if service account does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label app.kubernetes.io/managed-by with value of Helm. Returns true if service account does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_service_account_label_amazon_managed_removed": schema.BoolAttribute{
 				MarkdownDescription: "Is label **eks.amazonaws.com/component** removed. Returns **true** if service account does not exist as Helm chart can be deployed.",
 				Description:         "Is label eks.amazonaws.com/component removed. Returns true if service account does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_config_map_label_helm_release_name_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **meta.helm.sh/release-name** with value of **coredns**. Returns **true** if config map does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label meta.helm.sh/release-name with value of coredns. Returns true if config map does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_config_map_label_helm_release_namespace_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **meta.helm.sh/release-namespace** with value of **kube-system**. Returns **true** if config map does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label meta.helm.sh/release-namespace with value of kube-system. Returns true if config map does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_config_map_label_managed_by_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **app.kubernetes.io/managed-by** with value of **Helm**. Returns **true** if config map does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label app.kubernetes.io/managed-by with value of Helm. Returns true if config map does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_config_map_label_amazon_managed_removed": schema.BoolAttribute{
 				MarkdownDescription: "Is label **eks.amazonaws.com/component** removed. Returns **true** if config map does not exist as Helm chart can be deployed.",
 				Description:         "Is label eks.amazonaws.com/component removed. Returns true if config map does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_pod_disruption_budget_label_helm_release_name_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **meta.helm.sh/release-name** with value of **coredns**. Returns **true** if pod disruption budget does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label meta.helm.sh/release-name with value of coredns. Returns true if pod disruption budget does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_pod_disruption_budget_label_helm_release_namespace_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **meta.helm.sh/release-namespace** with value of **kube-system**. Returns **true** if pod disruption budget does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label meta.helm.sh/release-namespace with value of kube-system. Returns true if pod disruption budget does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_pod_disruption_budget_label_managed_by_set": schema.BoolAttribute{
 				MarkdownDescription: "Does CoreDNS service have label **app.kubernetes.io/managed-by** with value of **Helm**. Returns **true** if pod disruption budget does not exist as Helm chart can be deployed.",
 				Description:         "Does CoreDNS service have label app.kubernetes.io/managed-by with value of Helm. Returns true if pod disruption budget does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
 			},
 
 			"coredns_pod_disruption_budget_label_amazon_managed_removed": schema.BoolAttribute{
 				MarkdownDescription: "Is label **eks.amazonaws.com/component** removed. Returns **true** if pod disruption budget does not exist as Helm chart can be deployed.",
 				Description:         "Is label eks.amazonaws.com/component removed. Returns true if pod disruption budget does not exist as Helm chart can be deployed.",
 				Computed:            true,
+				DeprecationMessage:  "Use components instead.",
+			},
+		}),
+		Blocks: map[string]schema.Block{
+			"aws_cni": schema.SingleNestedBlock{
+				MarkdownDescription: "What to do with **AWS-CNI**. Left alone when the block is absent.",
+				Description:         "What to do with AWS-CNI. Left alone when the block is absent.",
+				Attributes: map[string]schema.Attribute{
+					"action": schema.StringAttribute{
+						MarkdownDescription: "One of `keep`, `remove`. `remove` deletes the `aws-node` daemonset.",
+						Description:         "One of keep, remove. remove deletes the aws-node daemonset.",
+						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.OneOf(componentActionKeep, componentActionRemove),
+						},
+					},
+				},
+			},
+
+			"kube_proxy": schema.SingleNestedBlock{
+				MarkdownDescription: "What to do with **Kube-Proxy**. Left alone when the block is absent.",
+				Description:         "What to do with Kube-Proxy. Left alone when the block is absent.",
+				Attributes: map[string]schema.Attribute{
+					"action": schema.StringAttribute{
+						MarkdownDescription: "One of `keep`, `remove`. `remove` deletes the `kube-proxy` daemonset and config map.",
+						Description:         "One of keep, remove. remove deletes the kube-proxy daemonset and config map.",
+						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.OneOf(componentActionKeep, componentActionRemove),
+						},
+					},
+				},
 			},
+
+			"coredns": schema.SingleNestedBlock{
+				MarkdownDescription: "What to do with **CoreDNS**. Left alone when the block is absent.",
+				Description:         "What to do with CoreDNS. Left alone when the block is absent.",
+				Attributes: map[string]schema.Attribute{
+					"action": schema.StringAttribute{
+						MarkdownDescription: "One of `keep`, `remove`, `adopt`, `restore`. `remove` deletes the AWS CoreDNS objects, `adopt` adds Helm metadata to them so that a Helm chart can take them over without losing DNS, and `restore` undoes an adoption.",
+						Description:         "One of keep, remove, adopt, restore. remove deletes the AWS CoreDNS objects, adopt adds Helm metadata to them so that a Helm chart can take them over without losing DNS, and restore undoes an adoption.",
+						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.OneOf(componentActionKeep, componentActionRemove, componentActionAdopt, componentActionRestore),
+						},
+					},
+				},
+			},
+
+			"kube_proxy_replacement_check": kubeProxyReplacementCheckBlock(),
+
+			"wait_for": waitForBlock(),
+
+			"admission_guard": admissionGuardBlock(),
+
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create:            true,
+				CreateDescription: "How long creating the job may take, including waiting for the lease lock and the wait_for conditions, e.g. `45m`. Defaults to `30m`.",
+				Read:              true,
+				ReadDescription:   "How long reading the job may take, e.g. `10m`. Defaults to `5m`.",
+				Update:            true,
+				UpdateDescription: "How long updating the job may take, including waiting for the lease lock and the wait_for conditions, e.g. `45m`. Defaults to `30m`.",
+				Delete:            true,
+				DeleteDescription: "How long deleting the job may take, e.g. `10m`. Defaults to `5m`.",
+			}),
 		},
 	}
 }
 
-func (r *JobResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
+func (r *JobResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var model JobResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	cleanEksProviderResourceData, ok := req.ProviderData.(*CleanEksProvider)
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *CleanEksProviderResourceData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+	resp.Diagnostics.Append(validateWaitFor(model.WaitFor)...)
+	resp.Diagnostics.Append(validateKubeProxyReplacementChecks(model.KubeProxyReplacementChecks)...)
+
+	// Settings that only apply to an action are rejected when the component is given another action
+	requireAction := func(attribute string, set bool, block string, component *JobComponentActionModel, action string) {
+		if !set || (component != nil && component.Action.IsUnknown()) || component.ValueAction() == action {
+			return
+		}
+		resp.Diagnostics.AddAttributeError(
+			path.Root(attribute),
+			"Invalid attribute combination",
+			fmt.Sprintf("%s only applies when %s.action is %q.", attribute, block, action),
 		)
+	}
+	requireAction("force", model.Force.ValueBool(), "kube_proxy", model.KubeProxy, componentActionRemove)
+	requireAction("acknowledged_aws_cni_dependencies", !model.AcknowledgedAwsCniDependencies.IsNull(), "aws_cni", model.AwsCni, componentActionRemove)
+	requireAction("kube_proxy_replacement_check", len(model.KubeProxyReplacementChecks) > 0, "kube_proxy", model.KubeProxy, componentActionRemove)
+	requireAction("conflict_policy", !model.ConflictPolicy.IsNull(), "coredns", model.Coredns, componentActionAdopt)
 
+	resp.Diagnostics.Append(validateAdmissionGuard(model.AdmissionGuard, model.AwsCni, model.KubeProxy)...)
+}
+
+// ModifyPlan plans an update when the state read from the cluster shows that the job no longer holds.
+func (r *JobResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing drifts before the job is created or once it is destroyed
+	if r.provider == nil || req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
 		return
-	} else {
-		r.provider = cleanEksProviderResourceData
 	}
+
+	var state, plan JobResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	statuses, diags := componentStatuses(ctx, state.Components)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	planDrift(ctx, resp, jobDrift(state, plan, statuses, dryRunValue(r.provider, state.DryRun)))
+}
+
+// jobDrift returns what the planned job applies that state shows is missing from the cluster, e.g. a component put
+// back by an EKS add-on update. statuses are the components in state. A dry run never applied anything.
+func jobDrift(state JobResourceModel, plan JobResourceModel, statuses map[ClusterObject]ComponentStatus, dryRun bool) (drift []string) {
+	if dryRun {
+		return nil
+	}
+	options := JobOptions{
+		AwsCni:         plan.AwsCni.ValueAction(),
+		KubeProxy:      plan.KubeProxy.ValueAction(),
+		Coredns:        plan.Coredns.ValueAction(),
+		ConflictPolicy: plan.ConflictPolicy.ValueString(),
+	}
+	drift = UnsatisfiedActions(options, statuses, StringListToStrings(state.HelmConflicts))
+	// A guard that was deleted behind our back is installed again
+	if plan.admissionGuard() != nil && !state.AdmissionGuardExists.ValueBool() {
+		drift = append(drift, "admission_guard")
+	}
+	return drift
+}
+
+func (r *JobResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// Version 0 had a boolean per change, version 1 has an action block per component
+		0: {
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var state map[string]interface{}
+				err := json.Unmarshal(req.RawState.JSON, &state)
+				if err != nil {
+					resp.Diagnostics.AddError("Error upgrading state", fmt.Sprintf("Error upgrading state: %s", err))
+					return
+				}
+
+				flag := func(name string) bool {
+					set, _ := state[name].(bool)
+					delete(state, name)
+					return set
+				}
+				action := func(action string) map[string]interface{} {
+					return map[string]interface{}{"action": action}
+				}
+
+				state["aws_cni"] = action(componentActionKeep)
+				if flag("remove_aws_cni") {
+					state["aws_cni"] = action(componentActionRemove)
+				}
+				state["kube_proxy"] = action(componentActionKeep)
+				if flag("remove_kube_proxy") {
+					state["kube_proxy"] = action(componentActionRemove)
+				}
+				removeCoreDns := flag("remove_core_dns")
+				importCorednsToHelm := flag("import_coredns_to_helm")
+				switch {
+				case removeCoreDns:
+					state["coredns"] = action(componentActionRemove)
+				case importCorednsToHelm:
+					state["coredns"] = action(componentActionAdopt)
+				default:
+					state["coredns"] = action(componentActionKeep)
+				}
+
+				upgraded, err := json.Marshal(state)
+				if err != nil {
+					resp.Diagnostics.AddError("Error upgrading state", fmt.Sprintf("Error upgrading state: %s", err))
+					return
+				}
+				resp.DynamicValue = &tfprotov6.DynamicValue{JSON: upgraded}
+			},
+		},
+	}
+}
+
+func (r *JobResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	cleanEksProvider, diags := configureProvider(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+	if cleanEksProvider != nil {
+		r.provider = cleanEksProvider
+	}
+}
+
+// Options returns the settings of the job for the step engine.
+func (m JobResourceModel) Options(p *CleanEksProvider) (options JobOptions, diags diag.Diagnostics) {
+	options = JobOptions{
+		AwsCni:                         m.AwsCni.ValueAction(),
+		KubeProxy:                      m.KubeProxy.ValueAction(),
+		Coredns:                        m.Coredns.ValueAction(),
+		DryRun:                         dryRunValue(p, m.DryRun),
+		ReadyTimeout:                   p.ReadyTimeout(),
+		ReadObjects:                    jobReadObjects,
+		Force:                          m.Force.ValueBool(),
+		VerifyEks:                      m.VerifyEks.ValueBool(),
+		ExpectedClusterName:            m.ExpectedClusterName.ValueString(),
+		ExpectedClusterArn:             m.ExpectedClusterArn.ValueString(),
+		ConflictPolicy:                 m.ConflictPolicy.ValueString(),
+		LockLeaseName:                  m.LockLeaseName.ValueString(),
+		AcknowledgedAwsCniDependencies: StringSetToStrings(m.AcknowledgedAwsCniDependencies),
+		KubeProxyReplacementChecks:     kubeProxyReplacementChecks(m.KubeProxyReplacementChecks),
+		WaitFor:                        waitConditions(m.WaitFor),
+		StrictAddonOwnership:           m.StrictAddonOwnership.ValueBool(),
+		AdmissionGuard:                 m.admissionGuard(),
+	}
+
+	if m.ManageEksAddons.ValueBool() {
+		clusterName := p.AwsOptions().ClusterName
+		if clusterName == "" {
+			clusterName = options.ExpectedClusterName
+		}
+		if clusterName == "" {
+			diags.AddAttributeError(
+				path.Root("manage_eks_addons"),
+				"Missing EKS cluster name",
+				"manage_eks_addons needs the name of the EKS cluster, set cluster_name of the provider or expected_cluster_name.",
+			)
+		}
+		options.EksAddons = &EksAddons{ClusterName: clusterName, Client: p.EksClient}
+	}
+
+	var durationDiags diag.Diagnostics
+	options.LockTimeout, durationDiags = durationValue("lock_timeout", m.LockTimeout)
+	diags.Append(durationDiags...)
+	options.WaitForTimeout, durationDiags = durationValue("wait_for_timeout", m.WaitForTimeout)
+	diags.Append(durationDiags...)
+	return options, diags
 }
 
 func (r *JobResource) Create(ctx context.Context, req resource.CreateRequest, res *resource.CreateResponse) {
@@ -331,8 +764,35 @@ func (r *JobResource) Create(ctx context.Context, req resource.CreateRequest, re
 		"jobConfig": fmt.Sprintf("%+v", model),
 	})
 
-	cleanEksProviderResourceData := r.provider
-	if cleanEksProviderResourceData == nil {
+	createTimeout, diags := model.Timeouts.Create(ctx, jobCreateTimeout)
+	res.Diagnostics.Append(diags...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	res.Diagnostics.Append(r.apply(ctx, "JobResource.Create", JobResourceModel{}, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	// Finally, set the state
+	tflog.Debug(ctx, "Storing job info into the state")
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+func (r *JobResource) Read(ctx context.Context, req resource.ReadRequest, res *resource.ReadResponse) {
+	tflog.Debug(ctx, "Reading job from state")
+
+	// Load entire configuration into the model
+	var model JobResourceModel
+	res.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	tflog.Debug(ctx, "Loaded job configuration", map[string]interface{}{
+		"jobConfig": fmt.Sprintf("%+v", model),
+	})
+
+	if r.provider == nil {
 		res.Diagnostics.AddError(
 			"Provider not configured",
 			fmt.Sprintf("Provider not configured"),
@@ -340,105 +800,234 @@ func (r *JobResource) Create(ctx context.Context, req resource.CreateRequest, re
 		return
 	}
 
-	var err error
+	readTimeout, diags := model.Timeouts.Read(ctx, jobReadTimeout)
+	res.Diagnostics.Append(diags...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
 
-	if r.provider.model.Host.IsUnknown() && !(model.ID.IsUnknown() || model.ID.IsNull()) {
-		r.provider.model.Host = model.ID
+	clientSet, err := r.provider.ResourceClientSet(ctx, model.ID, "JobResource.Read")
+	if err != nil {
+		if errors.Is(err, clientcmd.ErrEmptyConfig) && r.provider.model.Host.IsUnknown() {
+			// We don't want to throw error here as we EKS cluster might not exist yet
+			res.Diagnostics.Append(diag.NewWarningDiagnostic("Host configuration is not know yet. Provider operations likely to fail. Failed to initialize Kubernetes client configuration, this could be because credentials are not available during provider initialization", err.Error()))
+			return
+		}
+		res.Diagnostics.AddError(
+			"Error getting Kubernetes client during JobResource.Read",
+			fmt.Sprintf("Error getting Kubernetes client during JobResource.Read: %s", err),
+		)
+		return
 	}
 
-	if r.provider.model.ClientCertificate.IsUnknown() && r.provider.model.Insecure.IsUnknown() {
-		r.provider.model.Insecure = types.BoolValue(true)
+	// Nothing is changed here, so every check runs even when an earlier one failed and all failures are reported at
+	// once. The state is only stored when they all succeeded.
+	res.Diagnostics.Append(r.pinClusterIdentity(ctx, clientSet, model, &model)...)
+	if timedOut := stepTimedOut(ctx, "pin_cluster_identity"); timedOut.HasError() {
+		res.Diagnostics.Append(timedOut...)
+		return
 	}
 
-	execCommand := ""
-	execArgs := []string{}
-	execEnv := map[string]string{}
-	if len(r.provider.model.Exec) > 0 {
-		execCommand = r.provider.model.Exec[0].Command.ValueString()
-		execArgs = r.provider.model.Exec[0].Args
-		execEnv = r.provider.model.Exec[0].Env
+	// Read kubernetes to populate model
+	clusterIps, diags := r.corednsServiceClusterIps(ctx, clientSet, model.AwsCoreDnsServiceClusterIps)
+	res.Diagnostics.Append(diags...)
+	if timedOut := stepTimedOut(ctx, "read_coredns_service"); timedOut.HasError() {
+		res.Diagnostics.Append(timedOut...)
+		return
 	}
-	password := ""
-	if len(r.provider.model.Password.ValueString()) > 0 {
-		password = passwordMask
+
+	res.Diagnostics.Append(r.readComponentsExist(ctx, clientSet, &model)...)
+	if timedOut := stepTimedOut(ctx, "read_components"); timedOut.HasError() {
+		res.Diagnostics.Append(timedOut...)
+		return
 	}
 
-	clientKey := ""
-	if len(r.provider.model.ClientKey.ValueString()) > 0 {
-		clientKey = passwordMask
+	res.Diagnostics.Append(r.checkComponents(ctx, clientSet, &model, model.Components, nil)...)
+	res.Diagnostics.Append(stepTimedOut(ctx, "check_components")...)
+	if res.Diagnostics.HasError() {
+		return
 	}
-	tflog.Debug(ctx, "Loaded provider configuration during Job.Create", map[string]interface{}{
-		"host":                  r.provider.model.Host.ValueString(),
-		"burtLimit":             r.provider.model.BurstLimit.ValueInt64(),
-		"token":                 r.provider.model.Token.ValueString(),
-		"insecure":              r.provider.model.Insecure.ValueBool(),
-		"clusterCACertificate":  r.provider.model.ClusterCACertificate.ValueString(),
-		"tlsServerName":         r.provider.model.TLSServerName.ValueString(),
-		"username":              r.provider.model.Username.ValueString(),
-		"password":              password,
-		"clientCertificate":     r.provider.model.ClientCertificate.ValueString(),
-		"clientKey":             clientKey,
-		"execCommand":           execCommand,
-		"execArgs":              execArgs,
-		"execEnv":               execEnv,
-		"configPaths":           r.provider.model.ConfigPaths,
-		"configContext":         r.provider.model.ConfigContext.ValueString(),
-		"configContextCluster":  r.provider.model.ConfigContextCluster.ValueString(),
-		"configContextAuthInfo": r.provider.model.ConfigContextAuthInfo.ValueString(),
-	})
 
-	clientSet := cleanEksProviderResourceData.clientSet
-	if clientSet == nil {
-		clientSet, err = cleanEksProviderResourceData.GetClientSet(ctx)
-		if err != nil {
-			res.Diagnostics.AddError(
-				"Error getting Kubernetes client during JobResource.Create",
-				fmt.Sprintf("Error getting Kubernetes client during JobResource.Create: %s", err),
-			)
-			return
-		}
-		cleanEksProviderResourceData.clientSet = clientSet
+	res.Diagnostics.Append(r.readAdmissionGuard(ctx, clientSet, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	setClusterIps(&model, clusterIps)
+	if model.HelmConflicts.IsUnknown() || model.HelmConflicts.IsNull() {
+		model.HelmConflicts = StringsToList(nil)
+	}
+	if model.DryRunChanges.IsUnknown() || model.DryRunChanges.IsNull() {
+		model.DryRunChanges = StringsToList(nil)
+	}
+	if model.RequiredPermissions.IsUnknown() || model.RequiredPermissions.IsNull() {
+		model.RequiredPermissions = StringsToList(nil)
+	}
+	if model.ManageEksAddons.IsNull() {
+		model.ManageEksAddons = types.BoolValue(false)
+	}
+	if model.StrictAddonOwnership.IsNull() {
+		model.StrictAddonOwnership = types.BoolValue(false)
+	}
+
+	model.ID = basetypes.NewStringValue(r.provider.Host())
+
+	// Finally, set the state
+	tflog.Debug(ctx, "Storing job info into the state")
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+func (r *JobResource) Update(ctx context.Context, req resource.UpdateRequest, res *resource.UpdateResponse) {
+	tflog.Debug(ctx, "Updating job")
+
+	// Load entire configuration into the model
+	var model JobResourceModel
+	res.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
 	}
+	tflog.Debug(ctx, "Loaded job configuration", map[string]interface{}{
+		"jobConfig": fmt.Sprintf("%+v", model),
+	})
 
-	removeAwsCni := true
-	if !(model.RemoveAwsCni.IsNull() || model.RemoveAwsCni.IsUnknown()) {
-		removeAwsCni = model.RemoveAwsCni.ValueBool()
+	var state JobResourceModel
+	res.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if res.Diagnostics.HasError() {
+		return
 	}
 
-	removeKubeProxy := true
-	if !(model.RemoveKubeProxy.IsNull() || model.RemoveKubeProxy.IsUnknown()) {
-		removeKubeProxy = model.RemoveKubeProxy.ValueBool()
+	updateTimeout, diags := model.Timeouts.Update(ctx, jobUpdateTimeout)
+	res.Diagnostics.Append(diags...)
+	if res.Diagnostics.HasError() {
+		return
 	}
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
 
-	removeCoreDns := true
-	var clusterIps []string
-	if !(model.RemoveCoreDns.IsNull() || model.RemoveCoreDns.IsUnknown()) {
-		removeCoreDns = model.RemoveCoreDns.ValueBool()
+	res.Diagnostics.Append(r.apply(ctx, "JobResource.Update", state, &model)...)
+	if res.Diagnostics.HasError() {
+		return
 	}
 
-	importCorednsToHelm := false
-	if !(model.ImportCorednsToHelm.IsNull() || model.ImportCorednsToHelm.IsUnknown()) {
-		importCorednsToHelm = model.ImportCorednsToHelm.ValueBool()
+	// Finally, set the state
+	tflog.Debug(ctx, "Storing job info into the state")
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+// apply runs the steps of the job against the cluster and populates the model with what it found. state is the prior
+// state, empty on create.
+func (r *JobResource) apply(ctx context.Context, operation string, state JobResourceModel, model *JobResourceModel) (diags diag.Diagnostics) {
+	if r.provider == nil {
+		diags.AddError(
+			"Provider not configured",
+			fmt.Sprintf("Provider not configured"),
+		)
+		return diags
 	}
 
-	serviceExistsAndIsAwsOne := false
-	serviceExistsAndIsAwsOne, clusterIps, err = ServiceExistsAndIsAwsOne(ctx, clientSet, "kube-system", "kube-dns")
+	clientSet, err := r.provider.ResourceClientSet(ctx, model.ID, operation)
 	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDNS service is AWS one",
-			fmt.Sprintf("Error checking CoreDNS service is AWS one: %s", err),
+		diags.AddError(
+			fmt.Sprintf("Error getting Kubernetes client during %s", operation),
+			fmt.Sprintf("Error getting Kubernetes client during %s: %s", operation, err),
 		)
-		return
+		return diags
+	}
+
+	// A fresh cluster is waited for before the cluster identity is read
+	diags.Append(waitForApiServer(ctx, clientSet, r.provider.ReadyTimeout())...)
+	if diags.HasError() {
+		return diags
+	}
+
+	diags.Append(r.pinClusterIdentity(ctx, clientSet, state, model)...)
+	if diags.HasError() {
+		diags.Append(stepTimedOut(ctx, "pin_cluster_identity")...)
+		return diags
 	}
 
-	if len(clusterIps) < 1 && (model.AwsCoreDnsServiceClusterIps.IsUnknown() || model.AwsCoreDnsServiceClusterIps.IsNull()) {
-		_, clusterIps, err = ServiceExistsAndIsAwsOne(ctx, clientSet, "default", "kubernetes")
+	options, optionsDiags := model.Options(r.provider)
+	diags.Append(optionsDiags...)
+	if diags.HasError() {
+		return diags
+	}
+	options.ClusterArn = model.ClusterArn.ValueString()
+
+	// The cluster IPs are read before the CoreDNS service may be removed
+	clusterIps, clusterIpsDiags := r.corednsServiceClusterIps(ctx, clientSet, model.AwsCoreDnsServiceClusterIps)
+	diags.Append(clusterIpsDiags...)
+	if diags.HasError() {
+		diags.Append(stepTimedOut(ctx, "read_coredns_service")...)
+		return diags
+	}
+
+	run, runDiags := RunJob(ctx, clientSet, r.provider.Version, options)
+	diags.Append(runDiags...)
+	if diags.HasError() {
+		return diags
+	}
+	if run.HelmConflicts != nil {
+		model.HelmConflicts = StringsToList(run.HelmConflicts)
+	}
+
+	// A guard that was removed from the configuration, or renamed, is deleted
+	if previous := state.admissionGuard(); previous != nil && state.AdmissionGuardExists.ValueBool() && (options.AdmissionGuard == nil || options.AdmissionGuard.Name != previous.Name) {
+		changes, err := DeleteAdmissionGuard(ctx, clientSet, previous.Name, options.DryRun)
+		run.Changes = append(run.Changes, changes...)
 		if err != nil {
-			res.Diagnostics.AddError(
-				"Error checking Kubernetes service is AWS one",
-				fmt.Sprintf("Error checking Kubernetes service is AWS one: %s", err),
+			diags.AddError(
+				"Error removing admission guard",
+				fmt.Sprintf("Error removing admission guard %s: %s", previous.Name, err),
 			)
-			return
+			return diags
+		}
+	}
+
+	// Read kubernetes to populate model. Drift is only recorded by Read, the outcome of an apply has to match the plan
+	// The changes have been made, so both reads run and report every failure
+	diags.Append(r.readComponentsExist(ctx, clientSet, model)...)
+	if timedOut := stepTimedOut(ctx, "read_components"); timedOut.HasError() {
+		return append(diags, timedOut...)
+	}
+
+	diags.Append(r.checkComponents(ctx, clientSet, model, state.Components, run.Audit.Actions())...)
+	diags.Append(r.readAdmissionGuard(ctx, clientSet, model)...)
+	diags.Append(stepTimedOut(ctx, "check_components")...)
+	if diags.HasError() {
+		return diags
+	}
+
+	setClusterIps(model, clusterIps)
+	if model.HelmConflicts.IsUnknown() || model.HelmConflicts.IsNull() {
+		model.HelmConflicts = StringsToList(nil)
+	}
+
+	if options.DryRun {
+		model.DryRunChanges = StringsToList(run.Changes)
+	} else {
+		model.DryRunChanges = StringsToList(nil)
+	}
+	model.RequiredPermissions = StringsToList(PermissionsToStrings(RequiredPermissions(options)))
+
+	model.ID = basetypes.NewStringValue(r.provider.Host())
+	return diags
+}
+
+// corednsServiceClusterIps returns the cluster IPs of the AWS CoreDNS service. When it doesn't exist and none were
+// recorded before, the address EKS gives it is derived from the kubernetes service.
+func (r *JobResource) corednsServiceClusterIps(ctx context.Context, clientSet *kubernetes.Clientset, current types.List) (clusterIps []string, diags diag.Diagnostics) {
+	var errs ReadErrors
+	_, _, clusterIps, err := ServiceExistsAndIsAwsOne(ctx, clientSet, "kube-system", "kube-dns")
+	if errs.Add("get", "Service kube-system/kube-dns", err) {
+		return nil, errs.Diagnostics()
+	}
+
+	if len(clusterIps) < 1 && (current.IsUnknown() || current.IsNull()) {
+		_, _, clusterIps, err = ServiceExistsAndIsAwsOne(ctx, clientSet, "default", "kubernetes")
+		if errs.Add("get", "Service default/kubernetes", err) {
+			return nil, errs.Diagnostics()
 		}
 		if len(clusterIps) > 0 {
 			if strings.Contains(strings.ToLower(clusterIps[0]), ":") {
@@ -459,1208 +1048,190 @@ func (r *JobResource) Create(ctx context.Context, req resource.CreateRequest, re
 		}
 	}
 
-	if removeAwsCni || removeKubeProxy || removeCoreDns || importCorednsToHelm {
-		if removeAwsCni {
-			_, err = DeleteDaemonset(ctx, clientSet, "kube-system", "aws-node")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error removing AWS CNI daemonset",
-					fmt.Sprintf("Error removing AWS CNI daemonset: %s", err),
-				)
-				return
-			}
+	return clusterIps, diags
+}
+
+// setClusterIps stores the cluster IPs of the AWS CoreDNS service, keeping the ones recorded before when there are none.
+func setClusterIps(model *JobResourceModel, clusterIps []string) {
+	if len(clusterIps) > 0 {
+		elements := []attr.Value{}
+		for _, clusterIp := range clusterIps {
+			elements = append(elements, types.StringValue(clusterIp))
 		}
+		listValue, _ := types.ListValue(types.StringType, elements)
+		model.AwsCoreDnsServiceClusterIps = listValue
+	}
+	if model.AwsCoreDnsServiceClusterIps.IsUnknown() || model.AwsCoreDnsServiceClusterIps.IsNull() {
+		elements := []attr.Value{}
+		listValue, _ := types.ListValue(types.StringType, elements)
+		model.AwsCoreDnsServiceClusterIps = listValue
+	}
+}
 
-		if removeKubeProxy {
-			_, err = DeleteDaemonset(ctx, clientSet, "kube-system", "kube-proxy")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error removing Kube Proxy daemonset",
-					fmt.Sprintf("Error removing Kube Proxy daemonset: %s", err),
-				)
-				return
-			}
+// readAdmissionGuard stores whether the guard of the admission_guard block exists.
+func (r *JobResource) readAdmissionGuard(ctx context.Context, clientSet *kubernetes.Clientset, model *JobResourceModel) (diags diag.Diagnostics) {
+	guard := model.admissionGuard()
+	if guard == nil {
+		model.AdmissionGuardExists = types.BoolValue(false)
+		return diags
+	}
 
-			_, err = DeleteConfigMap(ctx, clientSet, "kube-system", "kube-proxy")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error removing Kube Proxy config map",
-					fmt.Sprintf("Error removing Kube Proxy config map: %s", err),
-				)
-				return
-			}
-		}
+	var errs ReadErrors
+	exists, err := AdmissionGuardExists(ctx, clientSet, guard.Name)
+	if errs.Add("get", fmt.Sprintf("ValidatingAdmissionPolicy %s", guard.Name), err) {
+		return errs.Diagnostics()
+	}
+	model.AdmissionGuardExists = types.BoolValue(exists)
+	return diags
+}
 
-		if removeCoreDns || importCorednsToHelm {
-			// We only want to delete the Amazon CoreDNS and not any further deployed versions
-			deploymentExistsAndIsAwsOne := false
-			deploymentExistsAndIsAwsOne, err = DeploymentExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error checking CoreDNS deployment is AWS one",
-					fmt.Sprintf("Error checking CoreDNS deployment is AWS one: %s", err),
-				)
-				return
-			}
+// readComponentsExist stores which of the objects the job removes still exist. Every object is checked even when
+// others can't be read, so that all failures are reported at once.
+func (r *JobResource) readComponentsExist(ctx context.Context, clientSet *kubernetes.Clientset, model *JobResourceModel) (diags diag.Diagnostics) {
+	var errs ReadErrors
 
-			serviceAccountExistsAndIsAwsOne := false
-			serviceAccountExistsAndIsAwsOne, err = ServiceAccountExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error checking CoreDNS service account is AWS one",
-					fmt.Sprintf("Error checking CoreDNS service account is AWS one: %s", err),
-				)
-				return
-			}
-
-			configMapExistsAndIsAwsOne := false
-			configMapExistsAndIsAwsOne, err = ConfigMapExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error checking CoreDNS config map is AWS one",
-					fmt.Sprintf("Error checking CoreDNS config map is AWS one: %s", err),
-				)
-				return
-			}
-
-			podDisruptionBudgetExistsAndIsAwsOne := false
-			podDisruptionBudgetExistsAndIsAwsOne, err = PodDisruptionBudgetExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error checking CoreDNS pod disruption budget is AWS one",
-					fmt.Sprintf("Error checking CoreDNS pod disruption budget is AWS one: %s", err),
-				)
-				return
-			}
-
-			if removeCoreDns {
-				if deploymentExistsAndIsAwsOne {
-					_, err = DeleteDeployment(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS deployment",
-							fmt.Sprintf("Error removing CoreDNS deployment: %s", err),
-						)
-						return
-					}
-				}
-
-				if serviceExistsAndIsAwsOne {
-					_, err = DeleteService(ctx, clientSet, "kube-system", "kube-dns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS service",
-							fmt.Sprintf("Error removing CoreDNS service: %s", err),
-						)
-						return
-					}
-				}
-
-				if serviceAccountExistsAndIsAwsOne {
-					_, err = DeleteServiceAccount(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS service account",
-							fmt.Sprintf("Error removing CoreDNS service account: %s", err),
-						)
-						return
-					}
-				}
-
-				if configMapExistsAndIsAwsOne {
-					_, err = DeleteConfigMap(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS configmap",
-							fmt.Sprintf("Error removing CoreDNS configmap: %s", err),
-						)
-						return
-					}
-				}
-
-				if podDisruptionBudgetExistsAndIsAwsOne {
-					_, err = DeletePodDisruptionBudget(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS pod disruption budget",
-							fmt.Sprintf("Error removing CoreDNS pod disruption budget: %s", err),
-						)
-						return
-					}
-				}
-			} else if importCorednsToHelm {
-				if deploymentExistsAndIsAwsOne {
-					err = ImportDeploymentIntoHelm(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns deployment to Helm",
-							fmt.Sprintf("Error importing CoreDns deployment to Helm: %s", err),
-						)
-						return
-					}
-				}
-
-				if serviceExistsAndIsAwsOne {
-					err = ImportServiceIntoHelm(ctx, clientSet, "kube-system", "kube-dns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns service to Helm",
-							fmt.Sprintf("Error importing CoreDns service to Helm: %s", err),
-						)
-						return
-					}
-				}
-
-				if serviceAccountExistsAndIsAwsOne {
-					err = ImportServiceAccountIntoHelm(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns service account to Helm",
-							fmt.Sprintf("Error importing CoreDns service account to Helm: %s", err),
-						)
-						return
-					}
-				}
-
-				if configMapExistsAndIsAwsOne {
-					err = ImportConfigMapAccountIntoHelm(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns config map to Helm",
-							fmt.Sprintf("Error importing CoreDns config map to Helm: %s", err),
-						)
-						return
-					}
-				}
-
-				if podDisruptionBudgetExistsAndIsAwsOne {
-					err = ImportPodDisruptionBudgetIntoHelm(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns pod disruption budget to Helm",
-							fmt.Sprintf("Error importing CoreDns pod disruption budget to Helm: %s", err),
-						)
-						return
-					}
-				}
-			}
-		}
-	}
-
-	// Read kubernetes to populate model
 	awsCniDaemonsetExists, err := DaemonsetExist(ctx, clientSet, "kube-system", "aws-node")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for AWS CNI",
-			fmt.Sprintf("Error checking daemonset for AWS CNI: %s", err),
-		)
-		return
-	}
-	model.AwsCniDaemonsetExists = basetypes.NewBoolValue(removeAwsCni && awsCniDaemonsetExists)
-
-	model.RemoveAwsCni = basetypes.NewBoolValue(!(awsCniDaemonsetExists))
+	errs.Add("get", "DaemonSet kube-system/aws-node", err)
+	model.AwsCniDaemonsetExists = basetypes.NewBoolValue(awsCniDaemonsetExists)
 
 	kubeProxyDaemonsetExists, err := DaemonsetExist(ctx, clientSet, "kube-system", "kube-proxy")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for Kube Proxy",
-			fmt.Sprintf("Error checking for Kube Proxy: %s", err),
-		)
-		return
-	}
+	errs.Add("get", "DaemonSet kube-system/kube-proxy", err)
 	model.KubeProxyDaemonsetExists = basetypes.NewBoolValue(kubeProxyDaemonsetExists)
 
 	kubeProxyConfigMapExists, err := ConfigMapExist(ctx, clientSet, "kube-system", "kube-proxy")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for Kube Proxy config map",
-			fmt.Sprintf("Error checking for Kube Proxy config map: %s", err),
-		)
-		return
-	}
+	errs.Add("get", "ConfigMap kube-system/kube-proxy", err)
 	model.KubeProxyConfigMapExists = basetypes.NewBoolValue(kubeProxyConfigMapExists)
 
-	model.RemoveKubeProxy = basetypes.NewBoolValue(removeKubeProxy && !(kubeProxyDaemonsetExists && kubeProxyConfigMapExists))
-
-	awsCoreDnsAwsDeploymentExists, err := DeploymentExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS deployment",
-			fmt.Sprintf("Error checking for Kube Proxy config map: %s", err),
-		)
-		return
-	}
+	awsCoreDnsAwsDeploymentExists, _, err := DeploymentExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
+	errs.Add("get", "Deployment kube-system/coredns", err)
 	model.AwsCoreDnsDeploymentExists = basetypes.NewBoolValue(awsCoreDnsAwsDeploymentExists)
 
-	awsCoreDnsServiceExists, _, err := ServiceExistsAndIsAwsOne(ctx, clientSet, "kube-system", "kube-dns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS service",
-			fmt.Sprintf("Error checking for CoreDNS service: %s", err),
-		)
-		return
-	}
+	awsCoreDnsServiceExists, _, _, err := ServiceExistsAndIsAwsOne(ctx, clientSet, "kube-system", "kube-dns")
+	errs.Add("get", "Service kube-system/kube-dns", err)
 	model.AwsCoreDnsServiceExists = basetypes.NewBoolValue(awsCoreDnsServiceExists)
 
-	awsCoreDnsServiceAccountExists, err := ServiceAccountExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS service account",
-			fmt.Sprintf("Error checking for CoreDNS service account: %s", err),
-		)
-		return
-	}
+	awsCoreDnsServiceAccountExists, _, err := ServiceAccountExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
+	errs.Add("get", "ServiceAccount kube-system/coredns", err)
 	model.AwsCoreDnsServiceAccountExists = basetypes.NewBoolValue(awsCoreDnsServiceAccountExists)
 
-	awsCoreDnsConfigMapExists, err := ConfigMapExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS config map",
-			fmt.Sprintf("Error checking for CoreDNS config map: %s", err),
-		)
-		return
-	}
+	awsCoreDnsConfigMapExists, _, err := ConfigMapExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
+	errs.Add("get", "ConfigMap kube-system/coredns", err)
 	model.AwsCoreDnsConfigMapExists = basetypes.NewBoolValue(awsCoreDnsConfigMapExists)
 
-	awsCoreDnsPodDisruptionBudgetExists, err := PodDisruptionBudgetExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS pod disruption budget",
-			fmt.Sprintf("Error checking for CoreDNS pod disruption budget: %s", err),
-		)
-		return
-	}
+	awsCoreDnsPodDisruptionBudgetExists, _, err := PodDisruptionBudgetExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
+	errs.Add("get", "PodDisruptionBudget kube-system/coredns", err)
 	model.AwsCoreDnsPodDisruptionBudgetExists = basetypes.NewBoolValue(awsCoreDnsPodDisruptionBudgetExists)
 
-	model.RemoveCoreDns = basetypes.NewBoolValue(removeCoreDns && !(awsCoreDnsAwsDeploymentExists && awsCoreDnsServiceExists && awsCoreDnsServiceAccountExists && awsCoreDnsConfigMapExists && awsCoreDnsPodDisruptionBudgetExists))
-
-	deploymentHelmReleaseNameAnnotationSet, deploymentHelmReleaseNamespaceAnnotationSet, deploymentManagedByLabelSet, deploymentAmazonManagedLabelRemoved, err := DeploymentImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns deployment to Helm",
-			fmt.Sprintf("Error checking CoreDns deployment to Helm: %s", err),
-		)
-		return
-	}
-	model.CorednsDeploymentLabelHelmReleaseNameSet = basetypes.NewBoolValue(deploymentHelmReleaseNameAnnotationSet)
-	model.CorednsDeploymentLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(deploymentHelmReleaseNamespaceAnnotationSet)
-	model.CorednsDeploymentLabelManagedBySet = basetypes.NewBoolValue(deploymentManagedByLabelSet)
-	model.CorednsDeploymentLabelAmazonManagedRemoved = basetypes.NewBoolValue(deploymentAmazonManagedLabelRemoved)
-
-	serviceHelmReleaseNameAnnotationSet, serviceHelmReleaseNamespaceAnnotationSet, serviceManagedByLabelSet, serviceAmazonManagedLabelRemoved, err := ServiceImportedIntoHelm(ctx, clientSet, "kube-system", "kube-dns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns service to Helm",
-			fmt.Sprintf("Error checking CoreDns service to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsServiceLabelHelmReleaseNameSet = basetypes.NewBoolValue(serviceHelmReleaseNameAnnotationSet)
-	model.CorednsServiceLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(serviceHelmReleaseNamespaceAnnotationSet)
-	model.CorednsServiceLabelManagedBySet = basetypes.NewBoolValue(serviceManagedByLabelSet)
-	model.CorednsServiceLabelAmazonManagedRemoved = basetypes.NewBoolValue(serviceAmazonManagedLabelRemoved)
-
-	serviceAccountHelmReleaseNameAnnotationSet, serviceAccountHelmReleaseNamespaceAnnotationSet, serviceAccountManagedByLabelSet, serviceAccountAmazonManagedLabelRemoved, err := ServiceAccountImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns service account to Helm",
-			fmt.Sprintf("Error checking CoreDns service account to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsServiceAccountLabelHelmReleaseNameSet = basetypes.NewBoolValue(serviceAccountHelmReleaseNameAnnotationSet)
-	model.CorednsServiceAccountLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(serviceAccountHelmReleaseNamespaceAnnotationSet)
-	model.CorednsServiceAccountLabelManagedBySet = basetypes.NewBoolValue(serviceAccountManagedByLabelSet)
-	model.CorednsServiceAccountLabelAmazonManagedRemoved = basetypes.NewBoolValue(serviceAccountAmazonManagedLabelRemoved)
-
-	configMapHelmReleaseNameAnnotationSet, configMapHelmReleaseNamespaceAnnotationSet, configMapManagedByLabelSet, configMapAmazonManagedLabelRemoved, err := ConfigMapImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns config map to Helm",
-			fmt.Sprintf("Error checking CoreDns config map to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsConfigMapLabelHelmReleaseNameSet = basetypes.NewBoolValue(configMapHelmReleaseNameAnnotationSet)
-	model.CorednsConfigMapLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(configMapHelmReleaseNamespaceAnnotationSet)
-	model.CorednsConfigMapLabelManagedBySet = basetypes.NewBoolValue(configMapManagedByLabelSet)
-	model.CorednsConfigMapLabelAmazonManagedRemoved = basetypes.NewBoolValue(configMapAmazonManagedLabelRemoved)
-
-	podDistruptionBudgetHelmReleaseNameAnnotationSet, podDistruptionBudgetHelmReleaseNamespaceAnnotationSet, podDistruptionBudgetManagedByLabelSet, podDistruptionBudgetAmazonManagedLabelRemoved, err := ConfigMapImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns pod disruption budget to Helm",
-			fmt.Sprintf("Error checking CoreDns pod disruption budget to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsPodDistruptionBudgetLabelHelmReleaseNameSet = basetypes.NewBoolValue(podDistruptionBudgetHelmReleaseNameAnnotationSet)
-	model.CorednsPodDistruptionBudgetLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(podDistruptionBudgetHelmReleaseNamespaceAnnotationSet)
-	model.CorednsPodDistruptionBudgetLabelManagedBySet = basetypes.NewBoolValue(podDistruptionBudgetManagedByLabelSet)
-	model.CorednsPodDistruptionBudgetLabelAmazonManagedRemoved = basetypes.NewBoolValue(podDistruptionBudgetAmazonManagedLabelRemoved)
-
-	model.ImportCorednsToHelm = basetypes.NewBoolValue(importCorednsToHelm && (deploymentHelmReleaseNameAnnotationSet && deploymentHelmReleaseNamespaceAnnotationSet && deploymentManagedByLabelSet && deploymentAmazonManagedLabelRemoved && serviceHelmReleaseNameAnnotationSet && serviceHelmReleaseNamespaceAnnotationSet && serviceManagedByLabelSet && serviceAmazonManagedLabelRemoved && serviceAccountHelmReleaseNameAnnotationSet && serviceAccountHelmReleaseNamespaceAnnotationSet && serviceAccountManagedByLabelSet && serviceAccountAmazonManagedLabelRemoved && configMapHelmReleaseNameAnnotationSet && configMapHelmReleaseNamespaceAnnotationSet && configMapManagedByLabelSet && configMapAmazonManagedLabelRemoved && podDistruptionBudgetHelmReleaseNameAnnotationSet && podDistruptionBudgetHelmReleaseNamespaceAnnotationSet && podDistruptionBudgetManagedByLabelSet && podDistruptionBudgetAmazonManagedLabelRemoved))
-
-	if len(clusterIps) > 0 {
-		elements := []attr.Value{}
-		for _, clusterIp := range clusterIps {
-			elements = append(elements, types.StringValue(clusterIp))
-		}
-		listValue, _ := types.ListValue(types.StringType, elements)
-		model.AwsCoreDnsServiceClusterIps = listValue
-	}
-	if model.AwsCoreDnsServiceClusterIps.IsUnknown() || model.AwsCoreDnsServiceClusterIps.IsNull() {
-		elements := []attr.Value{}
-		listValue, _ := types.ListValue(types.StringType, elements)
-		model.AwsCoreDnsServiceClusterIps = listValue
-	}
-	model.ID = basetypes.NewStringValue(r.provider.model.Host.ValueString())
-
-	// Finally, set the state
-	tflog.Debug(ctx, "Storing job info into the state")
-	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+	return errs.Diagnostics()
 }
 
-func (r *JobResource) Read(ctx context.Context, req resource.ReadRequest, res *resource.ReadResponse) {
-	// NO-OP: all there is to read is in the State, and response is already populated with that.
-	tflog.Debug(ctx, "Reading job from state")
-
-	// Load entire configuration into the model
+func (r *JobResource) Delete(ctx context.Context, req resource.DeleteRequest, res *resource.DeleteResponse) {
 	var model JobResourceModel
 	res.Diagnostics.Append(req.State.Get(ctx, &model)...)
-	tflog.Debug(ctx, "Loaded job configuration", map[string]interface{}{
-		"jobConfig": fmt.Sprintf("%+v", model),
-	})
-
-	cleanEksProviderResourceData := r.provider
-	if cleanEksProviderResourceData == nil {
-		res.Diagnostics.AddError(
-			"Provider not configured",
-			fmt.Sprintf("Provider not configured"),
-		)
-		return
-	}
-
-	if r.provider.model.Host.IsUnknown() && !(model.ID.IsUnknown() || model.ID.IsNull()) {
-		r.provider.model.Host = model.ID
-	}
-
-	if r.provider.model.ClientCertificate.IsUnknown() && r.provider.model.Insecure.IsUnknown() {
-		r.provider.model.Insecure = types.BoolValue(true)
-	}
-
-	execCommand := ""
-	execArgs := []string{}
-	execEnv := map[string]string{}
-	if len(r.provider.model.Exec) > 0 {
-		execCommand = r.provider.model.Exec[0].Command.ValueString()
-		execArgs = r.provider.model.Exec[0].Args
-		execEnv = r.provider.model.Exec[0].Env
-	}
-	password := ""
-	if len(r.provider.model.Password.ValueString()) > 0 {
-		password = passwordMask
-	}
-
-	clientKey := ""
-	if len(r.provider.model.ClientKey.ValueString()) > 0 {
-		clientKey = passwordMask
-	}
-	tflog.Debug(ctx, "Loaded provider configuration during Job.Read", map[string]interface{}{
-		"host":                  r.provider.model.Host.ValueString(),
-		"burtLimit":             r.provider.model.BurstLimit.ValueInt64(),
-		"token":                 r.provider.model.Token.ValueString(),
-		"insecure":              r.provider.model.Insecure.ValueBool(),
-		"clusterCACertificate":  r.provider.model.ClusterCACertificate.ValueString(),
-		"tlsServerName":         r.provider.model.TLSServerName.ValueString(),
-		"username":              r.provider.model.Username.ValueString(),
-		"password":              password,
-		"clientCertificate":     r.provider.model.ClientCertificate.ValueString(),
-		"clientKey":             clientKey,
-		"execCommand":           execCommand,
-		"execArgs":              execArgs,
-		"execEnv":               execEnv,
-		"configPaths":           r.provider.model.ConfigPaths,
-		"configContext":         r.provider.model.ConfigContext.ValueString(),
-		"configContextCluster":  r.provider.model.ConfigContextCluster.ValueString(),
-		"configContextAuthInfo": r.provider.model.ConfigContextAuthInfo.ValueString(),
-	})
-
-	var err error
-
-	clientSet := cleanEksProviderResourceData.clientSet
-	if clientSet == nil {
-		clientSet, err = cleanEksProviderResourceData.GetClientSet(ctx)
-		if err != nil {
-			if errors.Is(err, clientcmd.ErrEmptyConfig) && r.provider.model.Host.IsUnknown() {
-				// We don't want to throw error here as we EKS cluster might not exist yet
-				res.Diagnostics.Append(diag.NewWarningDiagnostic("Host configuration is not know yet. Provider operations likely to fail. Failed to initialize Kubernetes client configuration, this could be because credentials are not available during provider initialization", err.Error()))
-				return
-			} else {
-				res.Diagnostics.AddError(
-					"Error getting Kubernetes client during JobResource.Read",
-					fmt.Sprintf("Error getting Kubernetes client during JobResource.Read: %s", err),
-				)
-				return
-			}
-		}
-		cleanEksProviderResourceData.clientSet = clientSet
-	}
-
-	removeAwsCni := true
-	if !(model.RemoveAwsCni.IsNull() || model.RemoveAwsCni.IsUnknown()) {
-		removeAwsCni = model.RemoveAwsCni.ValueBool()
-	}
-
-	removeKubeProxy := true
-	if !(model.RemoveKubeProxy.IsNull() || model.RemoveKubeProxy.IsUnknown()) {
-		removeKubeProxy = model.RemoveKubeProxy.ValueBool()
-	}
-
-	removeCoreDns := true
-	var clusterIps []string
-	if !(model.RemoveCoreDns.IsNull() || model.RemoveCoreDns.IsUnknown()) {
-		removeCoreDns = model.RemoveCoreDns.ValueBool()
-	}
-
-	importCorednsToHelm := false
-	if !(model.ImportCorednsToHelm.IsNull() || model.ImportCorednsToHelm.IsUnknown()) {
-		importCorednsToHelm = model.ImportCorednsToHelm.ValueBool()
-	}
-
-	// Read kubernetes to populate model
-
-	awsCniDaemonsetExists, err := DaemonsetExist(ctx, clientSet, "kube-system", "aws-node")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for AWS CNI daemonset",
-			fmt.Sprintf("Error checking daemonset for AWS CNI daemonset: %s", err),
-		)
-		return
-	}
-	model.AwsCniDaemonsetExists = basetypes.NewBoolValue(awsCniDaemonsetExists)
-
-	model.RemoveAwsCni = basetypes.NewBoolValue(removeAwsCni && !(awsCniDaemonsetExists))
-
-	kubeProxyDaemonsetExists, err := DaemonsetExist(ctx, clientSet, "kube-system", "kube-proxy")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for Kube Proxy daemonset",
-			fmt.Sprintf("Error checking for Kube Proxy daemonset: %s", err),
-		)
-		return
-	}
-	model.KubeProxyDaemonsetExists = basetypes.NewBoolValue(kubeProxyDaemonsetExists)
-
-	kubeProxyConfigMapExists, err := ConfigMapExist(ctx, clientSet, "kube-system", "kube-proxy")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for Kube Proxy config map",
-			fmt.Sprintf("Error checking for Kube Proxy config map: %s", err),
-		)
-		return
-	}
-	model.KubeProxyConfigMapExists = basetypes.NewBoolValue(kubeProxyConfigMapExists)
-
-	model.RemoveKubeProxy = basetypes.NewBoolValue(removeKubeProxy && !(kubeProxyDaemonsetExists && kubeProxyConfigMapExists))
-
-	awsCoreDnsAwsDeploymentExists, err := DeploymentExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS deployment",
-			fmt.Sprintf("Error checking for Kube Proxy config map: %s", err),
-		)
-		return
-	}
-	model.AwsCoreDnsDeploymentExists = basetypes.NewBoolValue(awsCoreDnsAwsDeploymentExists)
-
-	awsCoreDnsServiceExists := false
-	awsCoreDnsServiceExists, clusterIps, err = ServiceExistsAndIsAwsOne(ctx, clientSet, "kube-system", "kube-dns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS service",
-			fmt.Sprintf("Error checking for CoreDNS service: %s", err),
-		)
+	if res.Diagnostics.HasError() {
 		return
 	}
-	model.AwsCoreDnsServiceExists = basetypes.NewBoolValue(awsCoreDnsServiceExists)
 
-	awsCoreDnsServiceAccountExists, err := ServiceAccountExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS service account",
-			fmt.Sprintf("Error checking for CoreDNS service account: %s", err),
-		)
+	deleteTimeout, diags := model.Timeouts.Delete(ctx, jobDeleteTimeout)
+	res.Diagnostics.Append(diags...)
+	if res.Diagnostics.HasError() {
 		return
 	}
-	model.AwsCoreDnsServiceAccountExists = basetypes.NewBoolValue(awsCoreDnsServiceAccountExists)
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
 
-	awsCoreDnsConfigMapExists, err := ConfigMapExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS config map",
-			fmt.Sprintf("Error checking for CoreDNS config map: %s", err),
-		)
-		return
-	}
-	if len(clusterIps) < 1 && (model.AwsCoreDnsServiceClusterIps.IsUnknown() || model.AwsCoreDnsServiceClusterIps.IsNull()) {
-		_, clusterIps, err = ServiceExistsAndIsAwsOne(ctx, clientSet, "default", "kubernetes")
-		if err != nil {
+	// The components stay removed or adopted, only the admission guard is taken away
+	if guard := model.admissionGuard(); guard != nil && model.AdmissionGuardExists.ValueBool() {
+		if r.provider == nil {
 			res.Diagnostics.AddError(
-				"Error checking Kubernetes service is AWS one",
-				fmt.Sprintf("Error checking Kubernetes service is AWS one: %s", err),
+				"Provider not configured",
+				fmt.Sprintf("Provider not configured"),
 			)
 			return
 		}
-		if len(clusterIps) > 0 {
-			if strings.Contains(strings.ToLower(clusterIps[0]), ":") {
-				ipv6Parts := strings.Split(clusterIps[0], ":")
-				ipv6Parts = ipv6Parts[:len(ipv6Parts)-1]
-				ipv6 := strings.Join(ipv6Parts, ":") + ":a"
-				clusterIps = []string{ipv6}
-			}
-		}
-
-		if len(clusterIps) > 0 {
-			if strings.Contains(strings.ToLower(clusterIps[0]), ".") {
-				ipv6Parts := strings.Split(clusterIps[0], ".")
-				ipv6Parts = ipv6Parts[:len(ipv6Parts)-1]
-				ipv6 := strings.Join(ipv6Parts, ".") + ".10"
-				clusterIps = []string{ipv6}
-			}
-		}
-	}
-
-	model.AwsCoreDnsConfigMapExists = basetypes.NewBoolValue(awsCoreDnsConfigMapExists)
-
-	awsCoreDnsPodDisruptionBudgetExists, err := PodDisruptionBudgetExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS pod disruption budget",
-			fmt.Sprintf("Error checking for CoreDNS pod disruption budget: %s", err),
-		)
-		return
-	}
-	model.AwsCoreDnsPodDisruptionBudgetExists = basetypes.NewBoolValue(awsCoreDnsPodDisruptionBudgetExists)
-
-	model.RemoveCoreDns = basetypes.NewBoolValue(removeCoreDns && !(awsCoreDnsAwsDeploymentExists && awsCoreDnsServiceExists && awsCoreDnsServiceAccountExists && awsCoreDnsConfigMapExists && awsCoreDnsPodDisruptionBudgetExists))
-
-	deploymentHelmReleaseNameAnnotationSet, deploymentHelmReleaseNamespaceAnnotationSet, deploymentManagedByLabelSet, deploymentAmazonManagedLabelRemoved, err := DeploymentImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns deployment to Helm",
-			fmt.Sprintf("Error checking CoreDns deployment to Helm: %s", err),
-		)
-		return
-	}
-	model.CorednsDeploymentLabelHelmReleaseNameSet = basetypes.NewBoolValue(deploymentHelmReleaseNameAnnotationSet)
-	model.CorednsDeploymentLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(deploymentHelmReleaseNamespaceAnnotationSet)
-	model.CorednsDeploymentLabelManagedBySet = basetypes.NewBoolValue(deploymentManagedByLabelSet)
-	model.CorednsDeploymentLabelAmazonManagedRemoved = basetypes.NewBoolValue(deploymentAmazonManagedLabelRemoved)
-
-	serviceHelmReleaseNameAnnotationSet, serviceHelmReleaseNamespaceAnnotationSet, serviceManagedByLabelSet, serviceAmazonManagedLabelRemoved, err := ServiceImportedIntoHelm(ctx, clientSet, "kube-system", "kube-dns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns service to Helm",
-			fmt.Sprintf("Error checking CoreDns service to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsServiceLabelHelmReleaseNameSet = basetypes.NewBoolValue(serviceHelmReleaseNameAnnotationSet)
-	model.CorednsServiceLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(serviceHelmReleaseNamespaceAnnotationSet)
-	model.CorednsServiceLabelManagedBySet = basetypes.NewBoolValue(serviceManagedByLabelSet)
-	model.CorednsServiceLabelAmazonManagedRemoved = basetypes.NewBoolValue(serviceAmazonManagedLabelRemoved)
-
-	serviceAccountHelmReleaseNameAnnotationSet, serviceAccountHelmReleaseNamespaceAnnotationSet, serviceAccountManagedByLabelSet, serviceAccountAmazonManagedLabelRemoved, err := ServiceAccountImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns service account to Helm",
-			fmt.Sprintf("Error checking CoreDns service account to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsServiceAccountLabelHelmReleaseNameSet = basetypes.NewBoolValue(serviceAccountHelmReleaseNameAnnotationSet)
-	model.CorednsServiceAccountLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(serviceAccountHelmReleaseNamespaceAnnotationSet)
-	model.CorednsServiceAccountLabelManagedBySet = basetypes.NewBoolValue(serviceAccountManagedByLabelSet)
-	model.CorednsServiceAccountLabelAmazonManagedRemoved = basetypes.NewBoolValue(serviceAccountAmazonManagedLabelRemoved)
-
-	configMapHelmReleaseNameAnnotationSet, configMapHelmReleaseNamespaceAnnotationSet, configMapManagedByLabelSet, configMapAmazonManagedLabelRemoved, err := ConfigMapImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns config map to Helm",
-			fmt.Sprintf("Error checking CoreDns config map to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsConfigMapLabelHelmReleaseNameSet = basetypes.NewBoolValue(configMapHelmReleaseNameAnnotationSet)
-	model.CorednsConfigMapLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(configMapHelmReleaseNamespaceAnnotationSet)
-	model.CorednsConfigMapLabelManagedBySet = basetypes.NewBoolValue(configMapManagedByLabelSet)
-	model.CorednsConfigMapLabelAmazonManagedRemoved = basetypes.NewBoolValue(configMapAmazonManagedLabelRemoved)
-
-	podDistruptionBudgetHelmReleaseNameAnnotationSet, podDistruptionBudgetHelmReleaseNamespaceAnnotationSet, podDistruptionBudgetManagedByLabelSet, podDistruptionBudgetAmazonManagedLabelRemoved, err := ConfigMapImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns pod disruption budget to Helm",
-			fmt.Sprintf("Error checking CoreDns pod disruption budget to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsPodDistruptionBudgetLabelHelmReleaseNameSet = basetypes.NewBoolValue(podDistruptionBudgetHelmReleaseNameAnnotationSet)
-	model.CorednsPodDistruptionBudgetLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(podDistruptionBudgetHelmReleaseNamespaceAnnotationSet)
-	model.CorednsPodDistruptionBudgetLabelManagedBySet = basetypes.NewBoolValue(podDistruptionBudgetManagedByLabelSet)
-	model.CorednsPodDistruptionBudgetLabelAmazonManagedRemoved = basetypes.NewBoolValue(podDistruptionBudgetAmazonManagedLabelRemoved)
-
-	model.ImportCorednsToHelm = basetypes.NewBoolValue(importCorednsToHelm && (deploymentHelmReleaseNameAnnotationSet && deploymentHelmReleaseNamespaceAnnotationSet && deploymentManagedByLabelSet && deploymentAmazonManagedLabelRemoved && serviceHelmReleaseNameAnnotationSet && serviceHelmReleaseNamespaceAnnotationSet && serviceManagedByLabelSet && serviceAmazonManagedLabelRemoved && serviceAccountHelmReleaseNameAnnotationSet && serviceAccountHelmReleaseNamespaceAnnotationSet && serviceAccountManagedByLabelSet && serviceAccountAmazonManagedLabelRemoved && configMapHelmReleaseNameAnnotationSet && configMapHelmReleaseNamespaceAnnotationSet && configMapManagedByLabelSet && configMapAmazonManagedLabelRemoved && podDistruptionBudgetHelmReleaseNameAnnotationSet && podDistruptionBudgetHelmReleaseNamespaceAnnotationSet && podDistruptionBudgetManagedByLabelSet && podDistruptionBudgetAmazonManagedLabelRemoved))
-
-	if len(clusterIps) > 0 {
-		elements := []attr.Value{}
-		for _, clusterIp := range clusterIps {
-			elements = append(elements, types.StringValue(clusterIp))
-		}
-		listValue, _ := types.ListValue(types.StringType, elements)
-		model.AwsCoreDnsServiceClusterIps = listValue
-	}
-	if model.AwsCoreDnsServiceClusterIps.IsUnknown() || model.AwsCoreDnsServiceClusterIps.IsNull() {
-		elements := []attr.Value{}
-		listValue, _ := types.ListValue(types.StringType, elements)
-		model.AwsCoreDnsServiceClusterIps = listValue
-	}
-	model.ID = basetypes.NewStringValue(r.provider.model.Host.ValueString())
-
-	// Finally, set the state
-	tflog.Debug(ctx, "Storing job info into the state")
-	res.Diagnostics.Append(res.State.Set(ctx, model)...)
-}
-
-func (r *JobResource) Update(ctx context.Context, req resource.UpdateRequest, res *resource.UpdateResponse) {
-	tflog.Debug(ctx, "Updating job")
-
-	// Load entire configuration into the model
-	var model JobResourceModel
-	res.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
-	if res.Diagnostics.HasError() {
-		return
-	}
-	tflog.Debug(ctx, "Loaded job configuration", map[string]interface{}{
-		"jobConfig": fmt.Sprintf("%+v", model),
-	})
-
-	cleanEksProviderResourceData := r.provider
-	if cleanEksProviderResourceData == nil {
-		res.Diagnostics.AddError(
-			"Provider not configured",
-			fmt.Sprintf("Provider not configured"),
-		)
-		return
-	}
-
-	if r.provider.model.Host.IsUnknown() && !(model.ID.IsUnknown() || model.ID.IsNull()) {
-		r.provider.model.Host = model.ID
-	}
-
-	if r.provider.model.ClientCertificate.IsUnknown() && r.provider.model.Insecure.IsUnknown() {
-		r.provider.model.Insecure = types.BoolValue(true)
-	}
-
-	execCommand := ""
-	execArgs := []string{}
-	execEnv := map[string]string{}
-	if len(r.provider.model.Exec) > 0 {
-		execCommand = r.provider.model.Exec[0].Command.ValueString()
-		execArgs = r.provider.model.Exec[0].Args
-		execEnv = r.provider.model.Exec[0].Env
-	}
-	password := ""
-	if len(r.provider.model.Password.ValueString()) > 0 {
-		password = passwordMask
-	}
-
-	clientKey := ""
-	if len(r.provider.model.ClientKey.ValueString()) > 0 {
-		clientKey = passwordMask
-	}
-	tflog.Debug(ctx, "Loaded provider configuration during Job.Update", map[string]interface{}{
-		"host":                  r.provider.model.Host.ValueString(),
-		"burtLimit":             r.provider.model.BurstLimit.ValueInt64(),
-		"token":                 r.provider.model.Token.ValueString(),
-		"insecure":              r.provider.model.Insecure.ValueBool(),
-		"clusterCACertificate":  r.provider.model.ClusterCACertificate.ValueString(),
-		"tlsServerName":         r.provider.model.TLSServerName.ValueString(),
-		"username":              r.provider.model.Username.ValueString(),
-		"password":              password,
-		"clientCertificate":     r.provider.model.ClientCertificate.ValueString(),
-		"clientKey":             clientKey,
-		"execCommand":           execCommand,
-		"execArgs":              execArgs,
-		"execEnv":               execEnv,
-		"configPaths":           r.provider.model.ConfigPaths,
-		"configContext":         r.provider.model.ConfigContext.ValueString(),
-		"configContextCluster":  r.provider.model.ConfigContextCluster.ValueString(),
-		"configContextAuthInfo": r.provider.model.ConfigContextAuthInfo.ValueString(),
-	})
 
-	var err error
-
-	clientSet := cleanEksProviderResourceData.clientSet
-	if clientSet == nil {
-		clientSet, err = cleanEksProviderResourceData.GetClientSet(ctx)
+		clientSet, err := r.provider.ResourceClientSet(ctx, model.ID, "JobResource.Delete")
 		if err != nil {
 			res.Diagnostics.AddError(
-				"Error getting Kubernetes client during JobResource.Update",
-				fmt.Sprintf("Error getting Kubernetes client during JobResource.Update: %s", err),
+				"Error getting Kubernetes client during JobResource.Delete",
+				fmt.Sprintf("Error getting Kubernetes client during JobResource.Delete: %s", err),
 			)
 			return
 		}
-		cleanEksProviderResourceData.clientSet = clientSet
-	}
-
-	removeAwsCni := true
-	if !(model.RemoveAwsCni.IsNull() || model.RemoveAwsCni.IsUnknown()) {
-		removeAwsCni = model.RemoveAwsCni.ValueBool()
-	}
-
-	removeKubeProxy := true
-	if !(model.RemoveKubeProxy.IsNull() || model.RemoveKubeProxy.IsUnknown()) {
-		removeKubeProxy = model.RemoveKubeProxy.ValueBool()
-	}
-
-	removeCoreDns := true
-	var clusterIps []string
-	if !(model.RemoveCoreDns.IsNull() || model.RemoveCoreDns.IsUnknown()) {
-		removeCoreDns = model.RemoveCoreDns.ValueBool()
-	}
 
-	importCorednsToHelm := false
-	if !(model.ImportCorednsToHelm.IsNull() || model.ImportCorednsToHelm.IsUnknown()) {
-		importCorednsToHelm = model.ImportCorednsToHelm.ValueBool()
-	}
-
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error making request",
-			fmt.Sprintf("Error making request: %s", err),
-		)
-		return
-	}
-
-	serviceExistsAndIsAwsOne := false
-	serviceExistsAndIsAwsOne, clusterIps, err = ServiceExistsAndIsAwsOne(ctx, clientSet, "kube-system", "kube-dns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDNS service is AWS one",
-			fmt.Sprintf("Error checking CoreDNS service is AWS one: %s", err),
-		)
-		return
-	}
-
-	if len(clusterIps) < 1 && (model.AwsCoreDnsServiceClusterIps.IsUnknown() || model.AwsCoreDnsServiceClusterIps.IsNull()) {
-		_, clusterIps, err = ServiceExistsAndIsAwsOne(ctx, clientSet, "default", "kubernetes")
+		tflog.Debug(ctx, "Removing admission guard", map[string]interface{}{
+			"name": guard.Name,
+		})
+		_, err = DeleteAdmissionGuard(ctx, clientSet, guard.Name, dryRunValue(r.provider, model.DryRun))
 		if err != nil {
 			res.Diagnostics.AddError(
-				"Error checking Kubernetes service is AWS one",
-				fmt.Sprintf("Error checking Kubernetes service is AWS one: %s", err),
+				"Error removing admission guard",
+				fmt.Sprintf("Error removing admission guard %s: %s", guard.Name, err),
 			)
+			res.Diagnostics.Append(stepTimedOut(ctx, "remove_admission_guard")...)
 			return
 		}
-		if len(clusterIps) > 0 {
-			if strings.Contains(strings.ToLower(clusterIps[0]), ":") {
-				ipv6Parts := strings.Split(clusterIps[0], ":")
-				ipv6Parts = ipv6Parts[:len(ipv6Parts)-1]
-				ipv6 := strings.Join(ipv6Parts, ":") + ":a"
-				clusterIps = []string{ipv6}
-			}
-		}
-
-		if len(clusterIps) > 0 {
-			if strings.Contains(strings.ToLower(clusterIps[0]), ".") {
-				ipv6Parts := strings.Split(clusterIps[0], ".")
-				ipv6Parts = ipv6Parts[:len(ipv6Parts)-1]
-				ipv6 := strings.Join(ipv6Parts, ".") + ".10"
-				clusterIps = []string{ipv6}
-			}
-		}
 	}
 
-	if removeAwsCni || removeKubeProxy || removeCoreDns || importCorednsToHelm {
-		if removeAwsCni {
-			_, err = DeleteDaemonset(ctx, clientSet, "kube-system", "aws-node")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error removing AWS CNI daemonset",
-					fmt.Sprintf("Error removing AWS CNI daemonset: %s", err),
-				)
-				return
-			}
-		}
-
-		if removeKubeProxy {
-			_, err = DeleteDaemonset(ctx, clientSet, "kube-system", "kube-proxy")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error removing Kube Proxy daemonset",
-					fmt.Sprintf("Error removing Kube Proxy daemonset: %s", err),
-				)
-				return
-			}
-
-			_, err = DeleteConfigMap(ctx, clientSet, "kube-system", "kube-proxy")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error removing Kube Proxy config map",
-					fmt.Sprintf("Error removing Kube Proxy config map: %s", err),
-				)
-				return
-			}
-		}
-
-		if removeCoreDns || importCorednsToHelm {
-			// We only want to delete the Amazon CoreDNS and not any further deployed versions
-			deploymentExistsAndIsAwsOne := false
-			deploymentExistsAndIsAwsOne, err = DeploymentExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error checking CoreDNS deployment is AWS one",
-					fmt.Sprintf("Error checking CoreDNS deployment is AWS one: %s", err),
-				)
-				return
-			}
-
-			serviceAccountExistsAndIsAwsOne := false
-			serviceAccountExistsAndIsAwsOne, err = ServiceAccountExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error checking CoreDNS service account is AWS one",
-					fmt.Sprintf("Error checking CoreDNS service account is AWS one: %s", err),
-				)
-				return
-			}
-
-			configMapExistsAndIsAwsOne := false
-			configMapExistsAndIsAwsOne, err = ConfigMapExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error checking CoreDNS config map is AWS one",
-					fmt.Sprintf("Error checking CoreDNS config map is AWS one: %s", err),
-				)
-				return
-			}
-
-			podDisruptionBudgetExistsAndIsAwsOne := false
-			podDisruptionBudgetExistsAndIsAwsOne, err = PodDisruptionBudgetExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-			if err != nil {
-				res.Diagnostics.AddError(
-					"Error checking CoreDNS pod disruption budget is AWS one",
-					fmt.Sprintf("Error checking CoreDNS pod disruption budget is AWS one: %s", err),
-				)
-				return
-			}
-
-			if removeCoreDns {
-				if deploymentExistsAndIsAwsOne {
-					_, err = DeleteDeployment(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS deployment",
-							fmt.Sprintf("Error removing CoreDNS deployment: %s", err),
-						)
-						return
-					}
-				}
-
-				if serviceExistsAndIsAwsOne {
-					_, err = DeleteService(ctx, clientSet, "kube-system", "kube-dns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS service",
-							fmt.Sprintf("Error removing CoreDNS service: %s", err),
-						)
-						return
-					}
-				}
-
-				if serviceAccountExistsAndIsAwsOne {
-					_, err = DeleteServiceAccount(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS service account",
-							fmt.Sprintf("Error removing CoreDNS service account: %s", err),
-						)
-						return
-					}
-				}
-
-				if configMapExistsAndIsAwsOne {
-					_, err = DeleteConfigMap(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS configmap",
-							fmt.Sprintf("Error removing CoreDNS configmap: %s", err),
-						)
-						return
-					}
-				}
-
-				if podDisruptionBudgetExistsAndIsAwsOne {
-					_, err = DeletePodDisruptionBudget(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error removing CoreDNS pod disruption budget",
-							fmt.Sprintf("Error removing CoreDNS pod disruption budget: %s", err),
-						)
-						return
-					}
-				}
-			} else if importCorednsToHelm {
-				if deploymentExistsAndIsAwsOne {
-					err = ImportDeploymentIntoHelm(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns deployment to Helm",
-							fmt.Sprintf("Error importing CoreDns deployment to Helm: %s", err),
-						)
-						return
-					}
-				}
-
-				if serviceExistsAndIsAwsOne {
-					err = ImportServiceIntoHelm(ctx, clientSet, "kube-system", "kube-dns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns service to Helm",
-							fmt.Sprintf("Error importing CoreDns service to Helm: %s", err),
-						)
-						return
-					}
-				}
-
-				if serviceAccountExistsAndIsAwsOne {
-					err = ImportServiceAccountIntoHelm(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns service account to Helm",
-							fmt.Sprintf("Error importing CoreDns service account to Helm: %s", err),
-						)
-						return
-					}
-				}
-
-				if configMapExistsAndIsAwsOne {
-					err = ImportConfigMapAccountIntoHelm(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns config map to Helm",
-							fmt.Sprintf("Error importing CoreDns config map to Helm: %s", err),
-						)
-						return
-					}
-				}
-
-				if podDisruptionBudgetExistsAndIsAwsOne {
-					err = ImportPodDisruptionBudgetIntoHelm(ctx, clientSet, "kube-system", "coredns")
-					if err != nil {
-						res.Diagnostics.AddError(
-							"Error importing CoreDns pod disruption budget to Helm",
-							fmt.Sprintf("Error importing CoreDns pod disruption budget to Helm: %s", err),
-						)
-						return
-					}
-				}
-			}
-		}
-	}
-
-	// Read kubernetes to populate model
-
-	awsCniDaemonsetExists, err := DaemonsetExist(ctx, clientSet, "kube-system", "aws-node")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for AWS CNI",
-			fmt.Sprintf("Error checking daemonset for AWS CNI: %s", err),
-		)
-		return
-	}
-	model.AwsCniDaemonsetExists = basetypes.NewBoolValue(awsCniDaemonsetExists)
-
-	model.RemoveAwsCni = basetypes.NewBoolValue(removeAwsCni && !(awsCniDaemonsetExists))
-
-	kubeProxyDaemonsetExists, err := DaemonsetExist(ctx, clientSet, "kube-system", "kube-proxy")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for Kube Proxy",
-			fmt.Sprintf("Error checking for Kube Proxy: %s", err),
-		)
-		return
-	}
-	model.KubeProxyDaemonsetExists = basetypes.NewBoolValue(kubeProxyDaemonsetExists)
-
-	kubeProxyConfigMapExists, err := ConfigMapExist(ctx, clientSet, "kube-system", "kube-proxy")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for Kube Proxy config map",
-			fmt.Sprintf("Error checking for Kube Proxy config map: %s", err),
-		)
-		return
-	}
-	model.KubeProxyConfigMapExists = basetypes.NewBoolValue(kubeProxyConfigMapExists)
-
-	model.RemoveKubeProxy = basetypes.NewBoolValue(removeKubeProxy && !(kubeProxyDaemonsetExists && kubeProxyConfigMapExists))
-
-	awsCoreDnsAwsDeploymentExists, err := DeploymentExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS deployment",
-			fmt.Sprintf("Error checking for Kube Proxy config map: %s", err),
-		)
-		return
-	}
-	model.AwsCoreDnsDeploymentExists = basetypes.NewBoolValue(awsCoreDnsAwsDeploymentExists)
-
-	awsCoreDnsServiceExists, _, err := ServiceExistsAndIsAwsOne(ctx, clientSet, "kube-system", "kube-dns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS service",
-			fmt.Sprintf("Error checking for CoreDNS service: %s", err),
-		)
-		return
-	}
-	model.AwsCoreDnsServiceExists = basetypes.NewBoolValue(awsCoreDnsServiceExists)
-
-	awsCoreDnsServiceAccountExists, err := ServiceAccountExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS service account",
-			fmt.Sprintf("Error checking for CoreDNS service account: %s", err),
-		)
-		return
-	}
-	model.AwsCoreDnsServiceAccountExists = basetypes.NewBoolValue(awsCoreDnsServiceAccountExists)
-
-	awsCoreDnsConfigMapExists, err := ConfigMapExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS config map",
-			fmt.Sprintf("Error checking for CoreDNS config map: %s", err),
-		)
-		return
-	}
-	model.AwsCoreDnsConfigMapExists = basetypes.NewBoolValue(awsCoreDnsConfigMapExists)
-
-	awsCoreDnsPodDisruptionBudgetExists, err := PodDisruptionBudgetExistsAndIsAwsOne(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking for CoreDNS pod disruption budget",
-			fmt.Sprintf("Error checking for CoreDNS pod disruption budget: %s", err),
-		)
-		return
-	}
-	model.AwsCoreDnsPodDisruptionBudgetExists = basetypes.NewBoolValue(awsCoreDnsPodDisruptionBudgetExists)
-
-	model.RemoveCoreDns = basetypes.NewBoolValue(removeCoreDns && !(awsCoreDnsAwsDeploymentExists && awsCoreDnsServiceExists && awsCoreDnsServiceAccountExists && awsCoreDnsConfigMapExists && awsCoreDnsPodDisruptionBudgetExists))
-
-	deploymentHelmReleaseNameAnnotationSet, deploymentHelmReleaseNamespaceAnnotationSet, deploymentManagedByLabelSet, deploymentAmazonManagedLabelRemoved, err := DeploymentImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns deployment to Helm",
-			fmt.Sprintf("Error checking CoreDns deployment to Helm: %s", err),
-		)
-		return
-	}
-	model.CorednsDeploymentLabelHelmReleaseNameSet = basetypes.NewBoolValue(deploymentHelmReleaseNameAnnotationSet)
-	model.CorednsDeploymentLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(deploymentHelmReleaseNamespaceAnnotationSet)
-	model.CorednsDeploymentLabelManagedBySet = basetypes.NewBoolValue(deploymentManagedByLabelSet)
-	model.CorednsDeploymentLabelAmazonManagedRemoved = basetypes.NewBoolValue(deploymentAmazonManagedLabelRemoved)
-
-	serviceHelmReleaseNameAnnotationSet, serviceHelmReleaseNamespaceAnnotationSet, serviceManagedByLabelSet, serviceAmazonManagedLabelRemoved, err := ServiceImportedIntoHelm(ctx, clientSet, "kube-system", "kube-dns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns service to Helm",
-			fmt.Sprintf("Error checking CoreDns service to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsServiceLabelHelmReleaseNameSet = basetypes.NewBoolValue(serviceHelmReleaseNameAnnotationSet)
-	model.CorednsServiceLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(serviceHelmReleaseNamespaceAnnotationSet)
-	model.CorednsServiceLabelManagedBySet = basetypes.NewBoolValue(serviceManagedByLabelSet)
-	model.CorednsServiceLabelAmazonManagedRemoved = basetypes.NewBoolValue(serviceAmazonManagedLabelRemoved)
-
-	serviceAccountHelmReleaseNameAnnotationSet, serviceAccountHelmReleaseNamespaceAnnotationSet, serviceAccountManagedByLabelSet, serviceAccountAmazonManagedLabelRemoved, err := ServiceAccountImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns service account to Helm",
-			fmt.Sprintf("Error checking CoreDns service account to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsServiceAccountLabelHelmReleaseNameSet = basetypes.NewBoolValue(serviceAccountHelmReleaseNameAnnotationSet)
-	model.CorednsServiceAccountLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(serviceAccountHelmReleaseNamespaceAnnotationSet)
-	model.CorednsServiceAccountLabelManagedBySet = basetypes.NewBoolValue(serviceAccountManagedByLabelSet)
-	model.CorednsServiceAccountLabelAmazonManagedRemoved = basetypes.NewBoolValue(serviceAccountAmazonManagedLabelRemoved)
-
-	configMapHelmReleaseNameAnnotationSet, configMapHelmReleaseNamespaceAnnotationSet, configMapManagedByLabelSet, configMapAmazonManagedLabelRemoved, err := ConfigMapImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns config map to Helm",
-			fmt.Sprintf("Error checking CoreDns config map to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsConfigMapLabelHelmReleaseNameSet = basetypes.NewBoolValue(configMapHelmReleaseNameAnnotationSet)
-	model.CorednsConfigMapLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(configMapHelmReleaseNamespaceAnnotationSet)
-	model.CorednsConfigMapLabelManagedBySet = basetypes.NewBoolValue(configMapManagedByLabelSet)
-	model.CorednsConfigMapLabelAmazonManagedRemoved = basetypes.NewBoolValue(configMapAmazonManagedLabelRemoved)
-
-	podDistruptionBudgetHelmReleaseNameAnnotationSet, podDistruptionBudgetHelmReleaseNamespaceAnnotationSet, podDistruptionBudgetManagedByLabelSet, podDistruptionBudgetAmazonManagedLabelRemoved, err := ConfigMapImportedIntoHelm(ctx, clientSet, "kube-system", "coredns")
-	if err != nil {
-		res.Diagnostics.AddError(
-			"Error checking CoreDns pod disruption budget to Helm",
-			fmt.Sprintf("Error checking CoreDns pod disruption budget to Helm: %s", err),
-		)
-		return
-	}
-
-	model.CorednsPodDistruptionBudgetLabelHelmReleaseNameSet = basetypes.NewBoolValue(podDistruptionBudgetHelmReleaseNameAnnotationSet)
-	model.CorednsPodDistruptionBudgetLabelHelmReleaseNamespaceSet = basetypes.NewBoolValue(podDistruptionBudgetHelmReleaseNamespaceAnnotationSet)
-	model.CorednsPodDistruptionBudgetLabelManagedBySet = basetypes.NewBoolValue(podDistruptionBudgetManagedByLabelSet)
-	model.CorednsPodDistruptionBudgetLabelAmazonManagedRemoved = basetypes.NewBoolValue(podDistruptionBudgetAmazonManagedLabelRemoved)
+	// Returning no error is enough for the framework to remove the resource from state.
+	tflog.Debug(ctx, "Removing job from state")
+}
 
-	model.ImportCorednsToHelm = basetypes.NewBoolValue(importCorednsToHelm && (deploymentHelmReleaseNameAnnotationSet && deploymentHelmReleaseNamespaceAnnotationSet && deploymentManagedByLabelSet && deploymentAmazonManagedLabelRemoved && serviceHelmReleaseNameAnnotationSet && serviceHelmReleaseNamespaceAnnotationSet && serviceManagedByLabelSet && serviceAmazonManagedLabelRemoved && serviceAccountHelmReleaseNameAnnotationSet && serviceAccountHelmReleaseNamespaceAnnotationSet && serviceAccountManagedByLabelSet && serviceAccountAmazonManagedLabelRemoved && configMapHelmReleaseNameAnnotationSet && configMapHelmReleaseNamespaceAnnotationSet && configMapManagedByLabelSet && configMapAmazonManagedLabelRemoved && podDistruptionBudgetHelmReleaseNameAnnotationSet && podDistruptionBudgetHelmReleaseNamespaceAnnotationSet && podDistruptionBudgetManagedByLabelSet && podDistruptionBudgetAmazonManagedLabelRemoved))
+func (r *JobResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
 
-	if len(clusterIps) > 0 {
-		elements := []attr.Value{}
-		for _, clusterIp := range clusterIps {
-			elements = append(elements, types.StringValue(clusterIp))
-		}
-		listValue, _ := types.ListValue(types.StringType, elements)
-		model.AwsCoreDnsServiceClusterIps = listValue
-	}
-	if model.AwsCoreDnsServiceClusterIps.IsUnknown() || model.AwsCoreDnsServiceClusterIps.IsNull() {
-		elements := []attr.Value{}
-		listValue, _ := types.ListValue(types.StringType, elements)
-		model.AwsCoreDnsServiceClusterIps = listValue
+// pinClusterIdentity records the identity of the live cluster in the model, refusing a cluster that doesn't match the
+// identity pinned in state.
+func (r *JobResource) pinClusterIdentity(ctx context.Context, clientSet *kubernetes.Clientset, pinned JobResourceModel, model *JobResourceModel) (diags diag.Diagnostics) {
+	identity, diags := checkClusterIdentity(ctx, clientSet, ClusterIdentity{Uid: pinned.ClusterUid.ValueString(), Arn: pinned.ClusterArn.ValueString()}, model.AllowClusterReplacement.ValueBool(), r.provider.EksClusterArn())
+	if diags.HasError() {
+		return diags
 	}
-	model.ID = basetypes.NewStringValue(r.provider.model.Host.ValueString())
 
-	// Finally, set the state
-	tflog.Debug(ctx, "Storing job info into the state")
-	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+	model.ClusterUid = types.StringValue(identity.Uid)
+	model.ClusterArn = types.StringValue(identity.Arn)
+	return diags
 }
 
-func (r *JobResource) Delete(ctx context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
-	// NO-OP: Returning no error is enough for the framework to remove the resource from state.
-	tflog.Debug(ctx, "Removing job from state")
-}
-
-func (r *JobResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+// checkComponents checks every object the job removes or adopts with one generic checker, storing the result in
+// components and the per object CoreDNS attributes. Drift is found from components when the next plan is made.
+func (r *JobResource) checkComponents(ctx context.Context, clientSet *kubernetes.Clientset, model *JobResourceModel, previous types.List, actions map[ClusterObject]string) (diags diag.Diagnostics) {
+	statuses, errs := CheckComponents(ctx, clientSet, AuditedObjects)
+	if len(errs) > 0 {
+		return errs.Diagnostics()
+	}
+
+	model.Components, diags = componentsValue(ctx, AuditedObjects, statuses, previous, actions)
+
+	deployment := statuses[CorednsHelmObjects[0]]
+	model.CorednsDeploymentLabelHelmReleaseNameSet = types.BoolValue(deployment.AdoptedKey(helmReleaseNameAnnotationName))
+	model.CorednsDeploymentLabelHelmReleaseNamespaceSet = types.BoolValue(deployment.AdoptedKey(helmReleaseNamespaceAnnotationName))
+	model.CorednsDeploymentLabelManagedBySet = types.BoolValue(deployment.AdoptedKey(managedByLabelName))
+	model.CorednsDeploymentLabelAmazonManagedRemoved = types.BoolValue(deployment.AdoptedKey(amazonManagedLabelName))
+
+	service := statuses[CorednsHelmObjects[1]]
+	model.CorednsServiceLabelHelmReleaseNameSet = types.BoolValue(service.AdoptedKey(helmReleaseNameAnnotationName))
+	model.CorednsServiceLabelHelmReleaseNamespaceSet = types.BoolValue(service.AdoptedKey(helmReleaseNamespaceAnnotationName))
+	model.CorednsServiceLabelManagedBySet = types.BoolValue(service.AdoptedKey(managedByLabelName))
+	model.CorednsServiceLabelAmazonManagedRemoved = types.BoolValue(service.AdoptedKey(amazonManagedLabelName))
+
+	serviceAccount := statuses[CorednsHelmObjects[2]]
+	model.CorednsServiceAccountLabelHelmReleaseNameSet = types.BoolValue(serviceAccount.AdoptedKey(helmReleaseNameAnnotationName))
+	model.CorednsServiceAccountLabelHelmReleaseNamespaceSet = types.BoolValue(serviceAccount.AdoptedKey(helmReleaseNamespaceAnnotationName))
+	model.CorednsServiceAccountLabelManagedBySet = types.BoolValue(serviceAccount.AdoptedKey(managedByLabelName))
+	model.CorednsServiceAccountLabelAmazonManagedRemoved = types.BoolValue(serviceAccount.AdoptedKey(amazonManagedLabelName))
+
+	configMap := statuses[CorednsHelmObjects[3]]
+	model.CorednsConfigMapLabelHelmReleaseNameSet = types.BoolValue(configMap.AdoptedKey(helmReleaseNameAnnotationName))
+	model.CorednsConfigMapLabelHelmReleaseNamespaceSet = types.BoolValue(configMap.AdoptedKey(helmReleaseNamespaceAnnotationName))
+	model.CorednsConfigMapLabelManagedBySet = types.BoolValue(configMap.AdoptedKey(managedByLabelName))
+	model.CorednsConfigMapLabelAmazonManagedRemoved = types.BoolValue(configMap.AdoptedKey(amazonManagedLabelName))
+
+	podDisruptionBudget := statuses[CorednsHelmObjects[4]]
+	model.CorednsPodDistruptionBudgetLabelHelmReleaseNameSet = types.BoolValue(podDisruptionBudget.AdoptedKey(helmReleaseNameAnnotationName))
+	model.CorednsPodDistruptionBudgetLabelHelmReleaseNamespaceSet = types.BoolValue(podDisruptionBudget.AdoptedKey(helmReleaseNamespaceAnnotationName))
+	model.CorednsPodDistruptionBudgetLabelManagedBySet = types.BoolValue(podDisruptionBudget.AdoptedKey(managedByLabelName))
+	model.CorednsPodDistruptionBudgetLabelAmazonManagedRemoved = types.BoolValue(podDisruptionBudget.AdoptedKey(amazonManagedLabelName))
+	return diags
 }
@@ -0,0 +1,215 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+func TestJobResourceUpgradeStateV0(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		want  map[string]interface{}
+	}{
+		{
+			name:  "nothing set",
+			state: `{"id":"job"}`,
+			want: map[string]interface{}{
+				"id":         "job",
+				"aws_cni":    map[string]interface{}{"action": "keep"},
+				"kube_proxy": map[string]interface{}{"action": "keep"},
+				"coredns":    map[string]interface{}{"action": "keep"},
+			},
+		},
+		{
+			name:  "all false",
+			state: `{"id":"job","remove_aws_cni":false,"remove_kube_proxy":false,"remove_core_dns":false,"import_coredns_to_helm":false}`,
+			want: map[string]interface{}{
+				"id":         "job",
+				"aws_cni":    map[string]interface{}{"action": "keep"},
+				"kube_proxy": map[string]interface{}{"action": "keep"},
+				"coredns":    map[string]interface{}{"action": "keep"},
+			},
+		},
+		{
+			name:  "remove aws cni and kube proxy",
+			state: `{"id":"job","remove_aws_cni":true,"remove_kube_proxy":true,"remove_core_dns":false,"import_coredns_to_helm":false}`,
+			want: map[string]interface{}{
+				"id":         "job",
+				"aws_cni":    map[string]interface{}{"action": "remove"},
+				"kube_proxy": map[string]interface{}{"action": "remove"},
+				"coredns":    map[string]interface{}{"action": "keep"},
+			},
+		},
+		{
+			name:  "remove coredns",
+			state: `{"id":"job","remove_aws_cni":false,"remove_kube_proxy":false,"remove_core_dns":true,"import_coredns_to_helm":false}`,
+			want: map[string]interface{}{
+				"id":         "job",
+				"aws_cni":    map[string]interface{}{"action": "keep"},
+				"kube_proxy": map[string]interface{}{"action": "keep"},
+				"coredns":    map[string]interface{}{"action": "remove"},
+			},
+		},
+		{
+			name:  "import coredns to helm",
+			state: `{"id":"job","remove_aws_cni":true,"remove_kube_proxy":false,"remove_core_dns":false,"import_coredns_to_helm":true}`,
+			want: map[string]interface{}{
+				"id":         "job",
+				"aws_cni":    map[string]interface{}{"action": "remove"},
+				"kube_proxy": map[string]interface{}{"action": "keep"},
+				"coredns":    map[string]interface{}{"action": "adopt"},
+			},
+		},
+		{
+			// Version 0 removed CoreDNS when both were set, so removing wins over adopting
+			name:  "remove coredns and import coredns to helm",
+			state: `{"id":"job","remove_aws_cni":true,"remove_kube_proxy":true,"remove_core_dns":true,"import_coredns_to_helm":true}`,
+			want: map[string]interface{}{
+				"id":         "job",
+				"aws_cni":    map[string]interface{}{"action": "remove"},
+				"kube_proxy": map[string]interface{}{"action": "remove"},
+				"coredns":    map[string]interface{}{"action": "remove"},
+			},
+		},
+		{
+			name:  "null flags",
+			state: `{"id":"job","remove_aws_cni":null,"remove_kube_proxy":null,"remove_core_dns":null,"import_coredns_to_helm":null}`,
+			want: map[string]interface{}{
+				"id":         "job",
+				"aws_cni":    map[string]interface{}{"action": "keep"},
+				"kube_proxy": map[string]interface{}{"action": "keep"},
+				"coredns":    map[string]interface{}{"action": "keep"},
+			},
+		},
+	}
+
+	upgrader := (&JobResource{}).UpgradeState(context.Background())[0]
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := resource.UpgradeStateRequest{RawState: &tfprotov6.RawState{JSON: []byte(test.state)}}
+			resp := &resource.UpgradeStateResponse{}
+			upgrader.StateUpgrader(context.Background(), req, resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+			}
+			if resp.DynamicValue == nil {
+				t.Fatal("no upgraded state")
+			}
+
+			var got map[string]interface{}
+			err := json.Unmarshal(resp.DynamicValue.JSON, &got)
+			if err != nil {
+				t.Fatalf("upgraded state isn't JSON: %s", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("upgraded state\n got: %v\nwant: %v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("invalid state", func(t *testing.T) {
+		req := resource.UpgradeStateRequest{RawState: &tfprotov6.RawState{JSON: []byte(`{`)}}
+		resp := &resource.UpgradeStateResponse{}
+		upgrader.StateUpgrader(context.Background(), req, resp)
+		if !resp.Diagnostics.HasError() {
+			t.Error("expected an error for invalid state")
+		}
+	})
+}
+
+func TestJobDrift(t *testing.T) {
+	guard := []JobAdmissionGuardModel{{}}
+	keep := &JobComponentActionModel{Action: types.StringValue(componentActionKeep)}
+	remove := &JobComponentActionModel{Action: types.StringValue(componentActionRemove)}
+	adopt := &JobComponentActionModel{Action: types.StringValue(componentActionAdopt)}
+	awsNode := map[ClusterObject]ComponentStatus{AwsCniObjects[0]: {Object: AwsCniObjects[0], Exists: true}}
+	corednsDeployment := map[ClusterObject]ComponentStatus{CorednsHelmObjects[0]: {Object: CorednsHelmObjects[0], Exists: true, IsEksManaged: true, Adoption: map[string]bool{helmReleaseNameAnnotationName: false}}}
+
+	tests := []struct {
+		name     string
+		state    JobResourceModel
+		plan     JobResourceModel
+		statuses map[ClusterObject]ComponentStatus
+		dryRun   bool
+		want     []string
+	}{
+		{
+			name: "nothing changed",
+			plan: JobResourceModel{AwsCni: remove, KubeProxy: remove, Coredns: adopt},
+		},
+		{
+			name:     "aws cni back",
+			plan:     JobResourceModel{AwsCni: remove, KubeProxy: remove},
+			statuses: awsNode,
+			want:     []string{"aws_cni"},
+		},
+		{
+			name:     "aws cni kept",
+			plan:     JobResourceModel{AwsCni: keep},
+			statuses: awsNode,
+		},
+		{
+			name:     "aws cni back after a dry run",
+			plan:     JobResourceModel{AwsCni: remove},
+			statuses: awsNode,
+			dryRun:   true,
+		},
+		{
+			name:     "coredns no longer adopted",
+			plan:     JobResourceModel{Coredns: adopt},
+			statuses: corednsDeployment,
+			want:     []string{"coredns"},
+		},
+		{
+			name:     "coredns back",
+			plan:     JobResourceModel{Coredns: remove},
+			statuses: corednsDeployment,
+			want:     []string{"coredns"},
+		},
+		{
+			name:  "guard exists",
+			state: JobResourceModel{AdmissionGuard: guard, AdmissionGuardExists: types.BoolValue(true)},
+			plan:  JobResourceModel{AwsCni: remove, AdmissionGuard: guard},
+		},
+		{
+			name:  "guard deleted",
+			state: JobResourceModel{AdmissionGuard: guard, AdmissionGuardExists: types.BoolValue(false)},
+			plan:  JobResourceModel{AwsCni: remove, AdmissionGuard: guard},
+			want:  []string{"admission_guard"},
+		},
+		{
+			name:     "aws cni and guard",
+			state:    JobResourceModel{AdmissionGuard: guard, AdmissionGuardExists: types.BoolValue(false)},
+			plan:     JobResourceModel{AwsCni: remove, AdmissionGuard: guard},
+			statuses: awsNode,
+			want:     []string{"aws_cni", "admission_guard"},
+		},
+		{
+			name:   "guard never installed by a dry run",
+			state:  JobResourceModel{AdmissionGuard: guard, AdmissionGuardExists: types.BoolValue(false)},
+			plan:   JobResourceModel{AwsCni: remove, AdmissionGuard: guard},
+			dryRun: true,
+		},
+		{
+			name:  "guard removed from the configuration",
+			state: JobResourceModel{AdmissionGuard: guard, AdmissionGuardExists: types.BoolValue(false)},
+			plan:  JobResourceModel{AwsCni: remove},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := jobDrift(test.state, test.plan, test.statuses, test.dryRun)
+			if len(got) != len(test.want) || (len(got) > 0 && !reflect.DeepEqual(got, test.want)) {
+				t.Errorf("jobDrift() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &KubeProxyRemovalResource{}
+var _ resource.ResourceWithImportState = &KubeProxyRemovalResource{}
+var _ resource.ResourceWithValidateConfig = &KubeProxyRemovalResource{}
+var _ resource.ResourceWithModifyPlan = &KubeProxyRemovalResource{}
+
+func NewKubeProxyRemovalResource() resource.Resource {
+	return &KubeProxyRemovalResource{}
+}
+
+// KubeProxyRemovalResource removes kube-proxy on its own, so that it can be ordered after the kube-proxy replacement is
+// installed.
+type KubeProxyRemovalResource struct {
+	provider *CleanEksProvider
+}
+
+type KubeProxyRemovalResourceModel struct {
+	ID types.String `tfsdk:"id"`
+
+	DryRun types.Bool `tfsdk:"dry_run"`
+
+	VerifyEks           types.Bool   `tfsdk:"verify_eks"`
+	ExpectedClusterName types.String `tfsdk:"expected_cluster_name"`
+	ExpectedClusterArn  types.String `tfsdk:"expected_cluster_arn"`
+
+	AllowClusterReplacement types.Bool   `tfsdk:"allow_cluster_replacement"`
+	ClusterUid              types.String `tfsdk:"cluster_uid"`
+	ClusterArn              types.String `tfsdk:"cluster_arn"`
+
+	LockLeaseName types.String `tfsdk:"lock_lease_name"`
+	LockTimeout   types.String `tfsdk:"lock_timeout"`
+
+	Force types.Bool `tfsdk:"force"`
+
+	KubeProxyReplacementChecks []JobKubeProxyReplacementCheckModel `tfsdk:"kube_proxy_replacement_check"`
+
+	WaitFor        []JobWaitForModel `tfsdk:"wait_for"`
+	WaitForTimeout types.String      `tfsdk:"wait_for_timeout"`
+
+	DryRunChanges types.List `tfsdk:"dry_run_changes"`
+
+	RequiredPermissions types.List `tfsdk:"required_permissions"`
+
+	Components types.List `tfsdk:"components"`
+}
+
+// Options returns the settings of the removal for the step engine.
+func (m KubeProxyRemovalResourceModel) Options(p *CleanEksProvider) (options JobOptions, diags diag.Diagnostics) {
+	options = JobOptions{
+		KubeProxy:                  componentActionRemove,
+		DryRun:                     dryRunValue(p, m.DryRun),
+		ReadyTimeout:               p.ReadyTimeout(),
+		ReadObjects:                KubeProxyObjects,
+		Force:                      m.Force.ValueBool(),
+		VerifyEks:                  m.VerifyEks.ValueBool(),
+		ExpectedClusterName:        m.ExpectedClusterName.ValueString(),
+		ExpectedClusterArn:         m.ExpectedClusterArn.ValueString(),
+		LockLeaseName:              m.LockLeaseName.ValueString(),
+		KubeProxyReplacementChecks: kubeProxyReplacementChecks(m.KubeProxyReplacementChecks),
+		WaitFor:                    waitConditions(m.WaitFor),
+	}
+
+	var durationDiags diag.Diagnostics
+	options.LockTimeout, durationDiags = durationValue("lock_timeout", m.LockTimeout)
+	diags.Append(durationDiags...)
+	options.WaitForTimeout, durationDiags = durationValue("wait_for_timeout", m.WaitForTimeout)
+	diags.Append(durationDiags...)
+	return options, diags
+}
+
+// Store records what was found in the cluster.
+func (m *KubeProxyRemovalResourceModel) Store(result *componentResult) {
+	m.ID = types.StringValue(result.Host)
+	m.ClusterUid = types.StringValue(result.Identity.Uid)
+	m.ClusterArn = types.StringValue(result.Identity.Arn)
+	m.Components = result.Components
+	if result.Run != nil || m.DryRunChanges.IsUnknown() || m.DryRunChanges.IsNull() {
+		m.DryRunChanges = result.DryRunChanges()
+	}
+	if result.Run != nil || m.RequiredPermissions.IsUnknown() || m.RequiredPermissions.IsNull() {
+		m.RequiredPermissions = result.RequiredPermissions()
+	}
+}
+
+func (r *KubeProxyRemovalResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_kube_proxy_removal"
+}
+
+func (r *KubeProxyRemovalResource) Schema(_ context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Removes the default Kube-Proxy from an EKS cluster. Use depends_on to remove it only once the " +
+			"kube-proxy replacement is installed.",
+		Attributes: mergeAttributes(runAttributes("Status of the Kube-Proxy daemonset and config map."), map[string]schema.Attribute{
+			"force": forceAttribute(),
+
+			"wait_for_timeout": waitForTimeoutAttribute(),
+		}),
+		Blocks: map[string]schema.Block{
+			"kube_proxy_replacement_check": kubeProxyReplacementCheckBlock(),
+
+			"wait_for": waitForBlock(),
+		},
+	}
+}
+
+func (r *KubeProxyRemovalResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var model KubeProxyRemovalResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateWaitFor(model.WaitFor)...)
+	resp.Diagnostics.Append(validateKubeProxyReplacementChecks(model.KubeProxyReplacementChecks)...)
+}
+
+func (r *KubeProxyRemovalResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	cleanEksProvider, diags := configureProvider(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+	if cleanEksProvider != nil {
+		r.provider = cleanEksProvider
+	}
+}
+
+func (r *KubeProxyRemovalResource) Create(ctx context.Context, req resource.CreateRequest, res *resource.CreateResponse) {
+	tflog.Debug(ctx, "Removing Kube Proxy")
+
+	var model KubeProxyRemovalResourceModel
+	res.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+	tflog.Debug(ctx, "Loaded Kube Proxy removal configuration", map[string]interface{}{
+		"config": fmt.Sprintf("%+v", model),
+	})
+
+	res.Diagnostics.Append(r.apply(ctx, "KubeProxyRemovalResource.Create", KubeProxyRemovalResourceModel{}, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+func (r *KubeProxyRemovalResource) Read(ctx context.Context, req resource.ReadRequest, res *resource.ReadResponse) {
+	tflog.Debug(ctx, "Reading Kube Proxy removal")
+
+	var model KubeProxyRemovalResourceModel
+	res.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	pinned := ClusterIdentity{Uid: model.ClusterUid.ValueString(), Arn: model.ClusterArn.ValueString()}
+	result, diags := applyComponent(ctx, r.provider, "KubeProxyRemovalResource.Read", model.ID, pinned, model.AllowClusterReplacement.ValueBool(), nil, KubeProxyObjects, model.Components)
+	res.Diagnostics.Append(diags...)
+	if res.Diagnostics.HasError() || result == nil {
+		return
+	}
+
+	model.Store(result)
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+// ModifyPlan plans an update when the state read from the cluster shows that Kube Proxy is back, e.g. reinstalled by
+// an EKS add-on update, so that it is removed again.
+func (r *KubeProxyRemovalResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing drifts before the removal is created or once it is destroyed
+	if r.provider == nil || req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var state KubeProxyRemovalResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	// A dry run never removed it
+	if resp.Diagnostics.HasError() || dryRunValue(r.provider, state.DryRun) {
+		return
+	}
+
+	statuses, diags := componentStatuses(ctx, state.Components)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	planDrift(ctx, resp, UnsatisfiedActions(JobOptions{KubeProxy: componentActionRemove}, statuses, nil))
+}
+
+func (r *KubeProxyRemovalResource) Update(ctx context.Context, req resource.UpdateRequest, res *resource.UpdateResponse) {
+	tflog.Debug(ctx, "Updating Kube Proxy removal")
+
+	var model KubeProxyRemovalResourceModel
+	res.Diagnostics.Append(req.Plan.Get(ctx, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	var state KubeProxyRemovalResourceModel
+	res.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	res.Diagnostics.Append(r.apply(ctx, "KubeProxyRemovalResource.Update", state, &model)...)
+	if res.Diagnostics.HasError() {
+		return
+	}
+
+	res.Diagnostics.Append(res.State.Set(ctx, model)...)
+}
+
+func (r *KubeProxyRemovalResource) Delete(ctx context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+	// NO-OP: Returning no error is enough for the framework to remove the resource from state.
+	tflog.Debug(ctx, "Removing Kube Proxy removal from state")
+}
+
+func (r *KubeProxyRemovalResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// apply removes Kube Proxy and populates the model. state is the prior state, empty on create.
+func (r *KubeProxyRemovalResource) apply(ctx context.Context, operation string, state KubeProxyRemovalResourceModel, model *KubeProxyRemovalResourceModel) (diags diag.Diagnostics) {
+	if r.provider == nil {
+		diags.AddError(
+			"Provider not configured",
+			fmt.Sprintf("Provider not configured"),
+		)
+		return diags
+	}
+
+	options, diags := model.Options(r.provider)
+	if diags.HasError() {
+		return diags
+	}
+
+	pinned := ClusterIdentity{Uid: state.ClusterUid.ValueString(), Arn: state.ClusterArn.ValueString()}
+	result, applyDiags := applyComponent(ctx, r.provider, operation, model.ID, pinned, model.AllowClusterReplacement.ValueBool(), &options, KubeProxyObjects, state.Components)
+	diags.Append(applyDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	model.Store(result)
+	return diags
+}
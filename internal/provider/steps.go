@@ -0,0 +1,794 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AwsCniObjects are the objects that are deleted to remove AWS CNI.
+var AwsCniObjects = []ClusterObject{
+	{Kind: "DaemonSet", Namespace: "kube-system", Name: "aws-node"},
+}
+
+// KubeProxyObjects are the objects that are deleted to remove kube-proxy.
+var KubeProxyObjects = []ClusterObject{
+	{Kind: "DaemonSet", Namespace: "kube-system", Name: "kube-proxy"},
+	{Kind: "ConfigMap", Namespace: "kube-system", Name: "kube-proxy"},
+}
+
+// cleanupTimeout bounds releasing the lock and writing the audit trail once a run has finished or timed out.
+const cleanupTimeout = 30 * time.Second
+
+// JobOptions are the settings of a run, independent of the resource they were configured on.
+type JobOptions struct {
+	// AwsCni, KubeProxy and Coredns are the component actions, empty means keep
+	AwsCni    string
+	KubeProxy string
+	Coredns   string
+
+	DryRun bool
+	Force  bool
+
+	// ReadyTimeout is how long the caller waits for /readyz of the API server before anything else, zero doesn't wait.
+	// RunJob doesn't wait itself, as the cluster identity is read before it runs.
+	ReadyTimeout time.Duration
+
+	VerifyEks           bool
+	ExpectedClusterName string
+	ExpectedClusterArn  string
+	// ClusterArn is the ARN of the cluster from the EKS API or pinned in state, which verify_eks falls back to for what
+	// can't be discovered in the cluster once aws-node is removed
+	ClusterArn string
+
+	ConflictPolicy string
+
+	LockLeaseName string
+	LockTimeout   time.Duration
+
+	AcknowledgedAwsCniDependencies []string
+	KubeProxyReplacementChecks     []KubeProxyReplacementCheck
+
+	WaitFor        []WaitCondition
+	WaitForTimeout time.Duration
+
+	// ReadObjects are the objects the resource reads after the run to report on them
+	ReadObjects []ClusterObject
+
+	// EksAddons releases the components from their EKS managed add-ons, nil leaves the add-ons alone
+	EksAddons *EksAddons
+	// StrictAddonOwnership fails the run instead of warning when objects owned by an EKS managed add-on would be
+	// removed or adopted
+	StrictAddonOwnership bool
+
+	// AdmissionGuard is installed once the components are removed, nil installs none
+	AdmissionGuard *AdmissionGuard
+}
+
+// Removes returns true when any component is removed.
+func (o JobOptions) Removes() bool {
+	return o.AwsCni == componentActionRemove || o.KubeProxy == componentActionRemove || o.Coredns == componentActionRemove
+}
+
+// Changes returns true when any component is changed.
+func (o JobOptions) Changes() bool {
+	return o.Removes() || o.Coredns == componentActionAdopt || o.Coredns == componentActionRestore || o.AdmissionGuard != nil
+}
+
+// ChangedObjects returns the objects the run removes or adopts.
+func (o JobOptions) ChangedObjects() (objects []ClusterObject) {
+	if o.AwsCni == componentActionRemove {
+		objects = append(objects, AwsCniObjects...)
+	}
+	if o.KubeProxy == componentActionRemove {
+		objects = append(objects, KubeProxyObjects...)
+	}
+	if o.Coredns == componentActionRemove || o.Coredns == componentActionAdopt {
+		objects = append(objects, CorednsHelmObjects...)
+	}
+	return objects
+}
+
+// JobStep is one unit of work of a run. Steps run in order and a run stops at the first step that fails.
+type JobStep struct {
+	Name string
+	Run  func(ctx context.Context, run *JobRun) diag.Diagnostics
+	// Permissions are the calls the step makes to the API server
+	Permissions []Permission
+}
+
+// Changes returns true when the step makes calls that change the cluster.
+func (s JobStep) Changes() bool {
+	for _, permission := range s.Permissions {
+		switch permission.Verb {
+		case "create", "update", "patch", "delete", "deletecollection":
+			return true
+		}
+	}
+	return false
+}
+
+// JobRun is what the steps of one run share, and what they found and changed.
+type JobRun struct {
+	Clientset *kubernetes.Clientset
+	Version   string
+	Options   JobOptions
+	Audit     *AuditLog
+
+	// Changes are the diffs and deletes that were made, or would have been made during a dry run
+	Changes []string
+	// HelmConflicts is nil unless the CoreDNS objects were checked for Helm ownership conflicts
+	HelmConflicts []string
+
+	lock     *LeaseLock
+	auditing bool
+	skip     map[string]bool
+}
+
+// JobSteps returns the steps that apply the options, in the order they run, with the permissions each of them needs.
+func JobSteps(options JobOptions) (steps []JobStep) {
+	if !options.Changes() {
+		return steps
+	}
+
+	// Nothing is changed during a dry run, so no lock is taken
+	var lock, verifyEks []Permission
+	if !options.DryRun {
+		lock = lockPermissions(options)
+	}
+	if options.VerifyEks {
+		verifyEks = eksVerificationPermissions()
+	}
+
+	steps = append(steps,
+		JobStep{Name: "check_permissions", Run: stepCheckPermissions},
+		JobStep{Name: "acquire_lock", Run: stepAcquireLock, Permissions: lock},
+		JobStep{Name: "verify_eks", Run: stepVerifyEks, Permissions: verifyEks},
+	)
+	if options.Removes() {
+		steps = append(steps, JobStep{Name: "wait_for", Run: stepWaitFor, Permissions: waitForPermissions(options)})
+	}
+	steps = append(steps, JobStep{Name: "audit_snapshot", Run: stepAuditSnapshot, Permissions: auditPermissions(options)})
+	if options.EksAddons == nil && len(options.ChangedObjects()) > 0 {
+		// Released add-ons no longer reconcile, so their former objects are only checked when the add-ons are left alone
+		steps = append(steps, JobStep{Name: "check_addon_ownership", Run: stepCheckAddonOwnership, Permissions: objectPermissions(options.ChangedObjects(), "get")})
+	}
+
+	if options.AwsCni == componentActionRemove {
+		steps = append(steps,
+			JobStep{Name: "guard_aws_cni", Run: stepGuardAwsCni, Permissions: awsCniGuardPermissions()},
+		)
+		if options.EksAddons != nil {
+			steps = append(steps, JobStep{Name: "release_aws_cni_addon", Run: releaseEksAddonStep(eksAddonAwsCni, false)})
+		}
+		steps = append(steps,
+			JobStep{Name: "remove_aws_cni", Run: removeObjectsStep("AWS CNI", AwsCniObjects, false), Permissions: objectPermissions(AwsCniObjects, "delete")},
+		)
+	}
+
+	if options.KubeProxy == componentActionRemove {
+		steps = append(steps,
+			JobStep{Name: "guard_kube_proxy", Run: stepGuardKubeProxy, Permissions: kubeProxyGuardPermissions(options)},
+		)
+		if options.EksAddons != nil {
+			steps = append(steps, JobStep{Name: "release_kube_proxy_addon", Run: releaseEksAddonStep(eksAddonKubeProxy, false)})
+		}
+		steps = append(steps,
+			JobStep{Name: "remove_kube_proxy", Run: removeObjectsStep("Kube Proxy", KubeProxyObjects, false), Permissions: objectPermissions(KubeProxyObjects, "delete")},
+		)
+	}
+
+	switch options.Coredns {
+	case componentActionRemove:
+		if options.EksAddons != nil {
+			steps = append(steps, JobStep{Name: "release_coredns_addon", Run: releaseEksAddonStep(eksAddonCoredns, false)})
+		}
+		// We only want to delete the Amazon CoreDNS and not any further deployed versions
+		steps = append(steps, JobStep{Name: "remove_coredns", Run: removeObjectsStep("CoreDNS", CorednsHelmObjects, true), Permissions: objectPermissions(CorednsHelmObjects, "get", "delete")})
+	case componentActionAdopt:
+		steps = append(steps, JobStep{Name: "resolve_helm_conflicts", Run: stepResolveHelmConflicts, Permissions: helmConflictPermissions()})
+		if options.EksAddons != nil {
+			// The objects are kept, so that CoreDNS becomes self-managed before it is adopted
+			steps = append(steps, JobStep{Name: "release_coredns_addon", Run: releaseEksAddonStep(eksAddonCoredns, true)})
+		}
+		steps = append(steps, JobStep{Name: "adopt_coredns", Run: stepAdoptCoredns, Permissions: objectPermissions(CorednsHelmObjects, "get", "update")})
+	case componentActionRestore:
+		steps = append(steps, JobStep{Name: "restore_coredns", Run: stepRestoreCoredns, Permissions: objectPermissions(CorednsHelmObjects, "get", "update")})
+		if options.EksAddons != nil {
+			steps = append(steps, JobStep{Name: "hand_back_coredns_addon", Run: stepHandBackCorednsAddon})
+		}
+	}
+
+	if options.AdmissionGuard != nil {
+		// delete is used when the guard is removed from the configuration or the job is destroyed
+		steps = append(steps, JobStep{Name: "install_admission_guard", Run: stepInstallAdmissionGuard, Permissions: admissionGuardPermissions(options.AdmissionGuard.Name, "get", "create", "update", "delete")})
+	}
+
+	return steps
+}
+
+// RunJob runs the steps for the options, stopping at the first one that fails. Once the lock is held, the run stops
+// as soon as the lease is taken over by another run: the step in flight is cancelled and no later step that changes the
+// cluster starts. Whatever the outcome the audit trail is flushed and the lock released.
+func RunJob(ctx context.Context, clientset *kubernetes.Clientset, version string, options JobOptions) (run *JobRun, diags diag.Diagnostics) {
+	run = &JobRun{
+		Clientset: clientset,
+		Version:   version,
+		Options:   options,
+		Audit:     NewAuditLog(clientset, version, options.DryRun),
+	}
+
+	stepCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	lockLost := false
+	defer func() {
+		// The audit trail and the lock are still cleaned up when the run timed out
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+		defer cancel()
+
+		if run.auditing {
+			diags.Append(auditWarning(run.Audit.Flush(cleanupCtx))...)
+		}
+		if lockLost {
+			// The loss has been reported, there is nothing left to give up
+			return
+		}
+		diags.Append(releaseLeaseLock(cleanupCtx, run.lock)...)
+	}()
+
+	watching := false
+	for _, step := range JobSteps(options) {
+		if run.lock != nil && !watching {
+			watching = true
+			go func(lock *LeaseLock) {
+				select {
+				case <-lock.Lost():
+					cancel(lock.Err())
+				case <-stepCtx.Done():
+				}
+			}(run.lock)
+		}
+		if run.lock != nil && step.Changes() {
+			if err := run.lock.Check(stepCtx); err != nil {
+				if run.lock.Err() != nil {
+					lockLost = true
+					diags.Append(lostLeaseLock(step.Name, err)...)
+				} else {
+					diags.AddError(
+						"Error checking lease lock",
+						fmt.Sprintf("Error checking lease lock before step %s: %s", step.Name, err),
+					)
+				}
+				return run, diags
+			}
+		}
+
+		tflog.Debug(ctx, "Running step", map[string]interface{}{
+			"step": step.Name,
+		})
+		diags.Append(step.Run(stepCtx, run)...)
+		if run.lock != nil && run.lock.Err() != nil {
+			lockLost = true
+			diags.Append(lostLeaseLock(step.Name, run.lock.Err())...)
+			return run, diags
+		}
+		if diags.HasError() {
+			diags.Append(stepTimedOut(ctx, step.Name)...)
+			return run, diags
+		}
+	}
+
+	return run, diags
+}
+
+// lostLeaseLock reports that the run stopped at a step because its lock was taken over by another run.
+func lostLeaseLock(step string, err error) (diags diag.Diagnostics) {
+	diags.AddError(
+		"Lost lease lock",
+		fmt.Sprintf("Stopped at step %s, as the lease lock is no longer held by this run and changes would overlap with another run: %s", step, err),
+	)
+	return diags
+}
+
+// stepTimedOut names the step that was running when the deadline of ctx passed, as the error the step returned is
+// usually an unhelpful "context deadline exceeded" from the Kubernetes client.
+func stepTimedOut(ctx context.Context, step string) (diags diag.Diagnostics) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		diags.AddError(
+			"Timed out",
+			fmt.Sprintf("Timed out while running step %s, increase the timeouts of the resource if the cluster is slow to respond", step),
+		)
+	}
+	return diags
+}
+
+// waitForApiServer blocks until the API server is ready, as a job often runs straight after the cluster is created. It
+// runs before anything else calls the API server, so zero timeout doesn't wait.
+func waitForApiServer(ctx context.Context, clientset *kubernetes.Clientset, timeout time.Duration) (diags diag.Diagnostics) {
+	if timeout <= 0 {
+		return diags
+	}
+	tflog.Debug(ctx, "Waiting for the API server to be ready", map[string]interface{}{
+		"timeout": timeout.String(),
+	})
+
+	err := WaitForApiServerReady(ctx, clientset, timeout)
+	if err != nil {
+		diags.AddError(
+			"Error waiting for the API server to be ready",
+			fmt.Sprintf("Error waiting for the API server to be ready: %s", err),
+		)
+	}
+	diags.Append(stepTimedOut(ctx, "wait_for_api_server")...)
+	return diags
+}
+
+// stepCheckPermissions checks every permission the run needs with a SelfSubjectAccessReview before anything is changed,
+// so that a missing permission doesn't stop the run half way through.
+func stepCheckPermissions(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	denied, err := DeniedPermissions(ctx, run.Clientset, RequiredPermissions(run.Options))
+	if err != nil {
+		diags.AddError(
+			"Error checking permissions",
+			fmt.Sprintf("Error checking permissions: %s", err),
+		)
+		return diags
+	}
+
+	if len(denied) > 0 {
+		diags.AddError(
+			"Missing permissions",
+			fmt.Sprintf("No changes were made because the Kubernetes user is missing these permissions:\n  - %s\n\nGrant them with a ClusterRole and Role bound to the user the provider runs as.", strings.Join(PermissionsToStrings(denied), "\n  - ")),
+		)
+	}
+	return diags
+}
+
+// stepAcquireLock takes the lock_lease_name Lease so that no other run changes the cluster at the same time. Nothing is
+// changed during a dry run, so no lock is taken.
+func stepAcquireLock(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	if run.Options.DryRun {
+		return diags
+	}
+
+	identity := LeaseLockIdentity(run.Version)
+	tflog.Debug(ctx, "Acquiring lease lock", map[string]interface{}{
+		"lease":    fmt.Sprintf("%s/%s", leaseLockNamespace, run.Options.LockLeaseName),
+		"identity": identity,
+		"timeout":  run.Options.LockTimeout.String(),
+	})
+
+	lock, err := AcquireLeaseLock(ctx, run.Clientset, leaseLockNamespace, run.Options.LockLeaseName, identity, run.Options.LockTimeout)
+	if err != nil {
+		diags.AddError(
+			"Error acquiring lease lock",
+			fmt.Sprintf("Error acquiring lease lock: %s", err),
+		)
+		return diags
+	}
+	run.lock = lock
+	return diags
+}
+
+// releaseLeaseLock gives up the lock taken by stepAcquireLock.
+func releaseLeaseLock(ctx context.Context, lock *LeaseLock) (diags diag.Diagnostics) {
+	if lock == nil {
+		return diags
+	}
+
+	err := lock.Release(ctx)
+	if err != nil {
+		diags.AddError(
+			"Error releasing lease lock",
+			fmt.Sprintf("Error releasing lease lock, changes may have overlapped with another run: %s", err),
+		)
+	}
+	return diags
+}
+
+// stepVerifyEks refuses to make changes unless the cluster can be confirmed to be an EKS cluster and, when
+// expected_cluster_name or expected_cluster_arn are set, the expected one.
+func stepVerifyEks(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	if !run.Options.VerifyEks {
+		return diags
+	}
+
+	info, problems, err := VerifyEksCluster(ctx, run.Clientset, run.Options.ClusterArn, run.Options.ExpectedClusterName, run.Options.ExpectedClusterArn)
+	if err != nil {
+		diags.AddError(
+			"Error verifying EKS cluster",
+			fmt.Sprintf("Error verifying EKS cluster: %s", err),
+		)
+		return diags
+	}
+
+	tflog.Debug(ctx, "Discovered EKS cluster", map[string]interface{}{
+		"gitVersion":      info.GitVersion,
+		"eksClusterRoles": info.EksClusterRoles,
+		"clusterName":     info.ClusterName,
+		"region":          info.Region,
+		"account":         info.Account,
+		"awsNodeRemoved":  info.AwsNodeRemoved,
+	})
+
+	if len(problems) > 0 {
+		diags.AddError(
+			"Refusing to change cluster that could not be verified",
+			fmt.Sprintf("No changes were made because the cluster could not be verified as the expected EKS cluster:\n  - %s\n\nCheck the provider is pointing at the right cluster, or set verify_eks = false.", strings.Join(problems, "\n  - ")),
+		)
+	}
+	return diags
+}
+
+// stepWaitFor blocks until every wait_for condition holds, so that components are only removed once their
+// replacements are running.
+func stepWaitFor(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	if len(run.Options.WaitFor) == 0 {
+		return diags
+	}
+
+	timeout := run.Options.WaitForTimeout
+	tflog.Debug(ctx, "Waiting for conditions before removing components", map[string]interface{}{
+		"conditions": fmt.Sprintf("%v", run.Options.WaitFor),
+		"timeout":    timeout.String(),
+	})
+
+	unmet, err := WaitForConditions(ctx, run.Clientset, run.Options.WaitFor, timeout)
+	if err != nil {
+		if len(unmet) > 0 {
+			diags.AddError(
+				"Timed out waiting for wait_for conditions",
+				fmt.Sprintf("No components were removed because the following conditions did not become true within %s:\n  - %s", timeout, strings.Join(unmet, "\n  - ")),
+			)
+		} else {
+			diags.AddError(
+				"Error waiting for wait_for conditions",
+				fmt.Sprintf("Error waiting for wait_for conditions: %s", err),
+			)
+		}
+	}
+	return diags
+}
+
+// stepAuditSnapshot reads the objects before they are changed, so that the audit trail can record what they were.
+func stepAuditSnapshot(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	err := run.Audit.Snapshot(ctx, AuditedObjects)
+	if err != nil {
+		diags.AddError(
+			"Error reading objects for the audit trail",
+			fmt.Sprintf("Error reading objects for the audit trail: %s", err),
+		)
+		return diags
+	}
+	run.auditing = true
+	return diags
+}
+
+// stepCheckAddonOwnership warns, or with StrictAddonOwnership fails, when objects that are about to be removed or adopted
+// are owned by an EKS managed add-on, as the add-on undoes the change the next time it reconciles.
+func stepCheckAddonOwnership(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	owned := []string{}
+	for _, object := range run.Options.ChangedObjects() {
+		_, isAddonManaged, err := ObjectExistsAndIsAwsOne(ctx, run.Clientset, object)
+		if err != nil {
+			diags.AddError(
+				"Error checking EKS add-on ownership",
+				fmt.Sprintf("Error checking EKS add-on ownership of %s: %s", object, err),
+			)
+			return diags
+		}
+		if isAddonManaged {
+			owned = append(owned, object.String())
+		}
+	}
+
+	if len(owned) == 0 {
+		return diags
+	}
+
+	if run.Options.StrictAddonOwnership {
+		diags.AddError(
+			"Refusing to change objects owned by an EKS managed add-on",
+			fmt.Sprintf("These objects are owned by an EKS managed add-on, which undoes any change to them the next time it reconciles:\n  - %s\n\nSet manage_eks_addons = true to release the add-ons first, delete the add-ons, or set strict_addon_ownership = false to change the objects anyway.", strings.Join(owned, "\n  - ")),
+		)
+		return diags
+	}
+
+	diags.AddWarning(
+		"Changing objects owned by an EKS managed add-on",
+		fmt.Sprintf("These objects are owned by an EKS managed add-on, which undoes the change the next time it reconciles:\n  - %s\n\nSet manage_eks_addons = true to release the add-ons first.", strings.Join(owned, "\n  - ")),
+	)
+	return diags
+}
+
+// stepGuardAwsCni refuses to remove aws-node while VPC CNI features that depend on it are in use, unless each of them
+// has been acknowledged.
+func stepGuardAwsCni(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	dependencies, err := AwsCniDependencies(ctx, run.Clientset, "kube-system", "aws-node")
+	if err != nil {
+		diags.AddError(
+			"Error checking for AWS CNI dependencies",
+			fmt.Sprintf("Error checking for AWS CNI dependencies: %s", err),
+		)
+		return diags
+	}
+
+	unacknowledged := UnacknowledgedAwsCniDependencies(dependencies, run.Options.AcknowledgedAwsCniDependencies)
+	if len(unacknowledged) > 0 {
+		diags.AddError(
+			"Refusing to remove AWS CNI",
+			fmt.Sprintf("The cluster uses VPC CNI features that break when aws-node is removed:\n  - %s\n\nMigrate off these features, or add them to acknowledged_aws_cni_dependencies to remove AWS CNI anyway.", strings.Join(unacknowledged, "\n  - ")),
+		)
+	}
+	return diags
+}
+
+// stepGuardKubeProxy refuses to remove kube-proxy unless a kube-proxy replacement is active, as removing it without one
+// breaks every ClusterIP service in the cluster.
+func stepGuardKubeProxy(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	kubeProxyDaemonsetExists, err := DaemonsetExist(ctx, run.Clientset, "kube-system", "kube-proxy")
+	if err != nil {
+		diags.AddError(
+			"Error checking for Kube Proxy daemonset",
+			fmt.Sprintf("Error checking for Kube Proxy daemonset: %s", err),
+		)
+		return diags
+	}
+	if !kubeProxyDaemonsetExists {
+		return diags
+	}
+
+	checks := append([]KubeProxyReplacementCheck{CiliumKubeProxyReplacementCheck}, run.Options.KubeProxyReplacementChecks...)
+	replacement, reasons, err := ActiveKubeProxyReplacement(ctx, run.Clientset, checks)
+	if err != nil {
+		diags.AddError(
+			"Error checking for kube-proxy replacement",
+			fmt.Sprintf("Error checking for kube-proxy replacement %s", err),
+		)
+		return diags
+	}
+	if replacement != "" {
+		tflog.Debug(ctx, "Detected kube-proxy replacement", map[string]interface{}{
+			"replacement": replacement,
+		})
+		return diags
+	}
+
+	if run.Options.Force {
+		diags.AddWarning(
+			"Removing Kube Proxy without a detected replacement",
+			fmt.Sprintf("force is set, so Kube Proxy is removed even though no kube-proxy replacement is active:\n  - %s", strings.Join(reasons, "\n  - ")),
+		)
+		return diags
+	}
+
+	diags.AddError(
+		"Refusing to remove Kube Proxy",
+		fmt.Sprintf("Removing Kube Proxy without a kube-proxy replacement breaks every ClusterIP service in the cluster, and no replacement is active:\n  - %s\n\nInstall a replacement (e.g. Cilium with kube-proxy-replacement enabled), describe it with a kube_proxy_replacement_check block, or set force = true.", strings.Join(reasons, "\n  - ")),
+	)
+	return diags
+}
+
+// removeObjectsStep returns a step that deletes the objects of a component. With awsOnly, objects that no longer carry
+// the EKS component label are left alone.
+func removeObjectsStep(component string, objects []ClusterObject, awsOnly bool) func(ctx context.Context, run *JobRun) diag.Diagnostics {
+	return func(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+		for _, object := range objects {
+			if awsOnly {
+				isAwsOne, _, err := ObjectExistsAndIsAwsOne(ctx, run.Clientset, object)
+				if err != nil {
+					diags.AddError(
+						fmt.Sprintf("Error checking %s is AWS one", component),
+						fmt.Sprintf("Error checking %s is AWS one: %s", object, err),
+					)
+					return diags
+				}
+				if !isAwsOne {
+					continue
+				}
+			}
+
+			deleted, err := DeleteObject(ctx, run.Clientset, object, run.Options.DryRun)
+			if err != nil {
+				diags.AddError(
+					fmt.Sprintf("Error removing %s", component),
+					fmt.Sprintf("Error removing %s: %s", object, err),
+				)
+				return diags
+			}
+			if deleted {
+				run.Changes = append(run.Changes, DeleteChange(object.Kind, object.Namespace, object.Name))
+				diags.Append(auditWarning(run.Audit.Record(ctx, auditActionRemoved, object))...)
+			}
+		}
+		return diags
+	}
+}
+
+// stepResolveHelmConflicts applies conflict_policy to the CoreDNS objects that are owned by a different Helm release, or
+// that an already installed coredns release doesn't own.
+func stepResolveHelmConflicts(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	run.skip = map[string]bool{}
+
+	conflicts, err := HelmConflicts(ctx, run.Clientset, CorednsHelmObjects)
+	if err != nil {
+		diags.AddError(
+			"Error checking for Helm ownership conflicts",
+			fmt.Sprintf("Error checking for Helm ownership conflicts: %s", err),
+		)
+		return diags
+	}
+
+	run.HelmConflicts = make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		run.HelmConflicts = append(run.HelmConflicts, conflict.String())
+	}
+
+	if len(conflicts) == 0 {
+		return diags
+	}
+
+	switch run.Options.ConflictPolicy {
+	case conflictPolicyTakeover:
+		diags.AddWarning(
+			"Taking over Helm owned objects",
+			fmt.Sprintf("conflict_policy is takeover, so these objects are adopted into the coredns Helm release anyway:\n  - %s", strings.Join(run.HelmConflicts, "\n  - ")),
+		)
+	case conflictPolicySkip:
+		for _, conflict := range conflicts {
+			run.skip[conflict.Object.Kind] = true
+		}
+		diags.AddWarning(
+			"Skipping Helm owned objects",
+			fmt.Sprintf("conflict_policy is skip, so these objects are not adopted into the coredns Helm release:\n  - %s", strings.Join(run.HelmConflicts, "\n  - ")),
+		)
+	default:
+		diags.AddError(
+			"Refusing to take over Helm owned objects",
+			fmt.Sprintf("No objects were adopted into the coredns Helm release because of ownership conflicts:\n  - %s\n\nSet conflict_policy to takeover to adopt them anyway, or to skip to leave them alone.", strings.Join(run.HelmConflicts, "\n  - ")),
+		)
+	}
+	return diags
+}
+
+// stepAdoptCoredns adds the Helm metadata to the AWS CoreDNS objects, so that a Helm chart can take them over without
+// losing DNS.
+func stepAdoptCoredns(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	for _, object := range CorednsHelmObjects {
+		isAwsOne, _, err := ObjectExistsAndIsAwsOne(ctx, run.Clientset, object)
+		if err != nil {
+			diags.AddError(
+				"Error checking CoreDNS is AWS one",
+				fmt.Sprintf("Error checking %s is AWS one: %s", object, err),
+			)
+			return diags
+		}
+		if !isAwsOne || run.skip[object.Kind] {
+			continue
+		}
+
+		diff, err := ImportObjectIntoHelm(ctx, run.Clientset, object, run.Options.DryRun)
+		if err != nil {
+			diags.AddError(
+				"Error importing CoreDns to Helm",
+				fmt.Sprintf("Error importing %s to Helm: %s", object, err),
+			)
+			return diags
+		}
+		if diff != "" {
+			run.Changes = append(run.Changes, diff)
+			diags.Append(auditWarning(run.Audit.Record(ctx, auditActionAdopted, object))...)
+		}
+	}
+	return diags
+}
+
+// stepRestoreCoredns undoes an adoption, so that the CoreDNS objects look like the ones EKS installed.
+func stepRestoreCoredns(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	for _, object := range CorednsHelmObjects {
+		diff, err := RestoreFromHelm(ctx, run.Clientset, object, EksComponentLabelValue(object), run.Options.DryRun)
+		if err != nil {
+			diags.AddError(
+				"Error restoring CoreDns from Helm",
+				fmt.Sprintf("Error restoring CoreDns %s from Helm: %s", object, err),
+			)
+			return diags
+		}
+		if diff != "" {
+			run.Changes = append(run.Changes, diff)
+			diags.Append(auditWarning(run.Audit.Record(ctx, auditActionRestored, object))...)
+		}
+	}
+	return diags
+}
+
+// releaseEksAddonStep returns a step that deletes the EKS managed add-on of a component, keeping its objects with
+// preserve. During a dry run the add-on is only looked up, as the EKS API has no dry run.
+func releaseEksAddonStep(name string, preserve bool) func(ctx context.Context, run *JobRun) diag.Diagnostics {
+	return func(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+		action := "delete"
+		if preserve {
+			action = "delete, preserving the objects of,"
+		}
+
+		var released bool
+		var err error
+		if run.Options.DryRun {
+			released, err = run.Options.EksAddons.Installed(ctx, name)
+		} else {
+			released, err = run.Options.EksAddons.Release(ctx, name, preserve)
+		}
+		if err != nil {
+			diags.AddError(
+				"Error releasing EKS add-on",
+				fmt.Sprintf("Error releasing EKS add-on %s: %s", name, err),
+			)
+			return diags
+		}
+		if released {
+			run.Changes = append(run.Changes, EksAddonChange(action, name))
+		}
+		return diags
+	}
+}
+
+// stepHandBackCorednsAddon installs the CoreDNS EKS managed add-on once CoreDNS is restored, so that EKS manages it again.
+func stepHandBackCorednsAddon(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	var created bool
+	var err error
+	if run.Options.DryRun {
+		var installed bool
+		installed, err = run.Options.EksAddons.Installed(ctx, eksAddonCoredns)
+		created = !installed
+	} else {
+		created, err = run.Options.EksAddons.HandBack(ctx, eksAddonCoredns)
+	}
+	if err != nil {
+		diags.AddError(
+			"Error handing back EKS add-on",
+			fmt.Sprintf("Error handing back EKS add-on %s: %s", eksAddonCoredns, err),
+		)
+		return diags
+	}
+	if created {
+		run.Changes = append(run.Changes, EksAddonChange("create", eksAddonCoredns))
+	}
+	return diags
+}
+
+// auditWarning reports a failure to write the audit trail as a warning, as the change it describes has already been made.
+func auditWarning(err error) (diags diag.Diagnostics) {
+	if err != nil {
+		diags.AddWarning(
+			"Error writing audit trail",
+			fmt.Sprintf("Error writing audit trail: %s", err),
+		)
+	}
+	return diags
+}
+
+// stepInstallAdmissionGuard creates or updates the policy and binding that deny creating the removed components again.
+func stepInstallAdmissionGuard(ctx context.Context, run *JobRun) (diags diag.Diagnostics) {
+	guard := *run.Options.AdmissionGuard
+	tflog.Debug(ctx, "Installing admission guard", map[string]interface{}{
+		"name":    guard.Name,
+		"objects": admissionGuardObjectsString(guard.Objects),
+	})
+
+	changes, err := ApplyAdmissionGuard(ctx, run.Clientset, guard, run.Options.DryRun)
+	run.Changes = append(run.Changes, changes...)
+	if err != nil {
+		diags.AddError(
+			"Error installing admission guard",
+			fmt.Sprintf("Error installing admission guard %s: %s", guard.Name, err),
+		)
+	}
+	return diags
+}
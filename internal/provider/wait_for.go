@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const waitForKindDaemonSet string = "DaemonSet"
+const waitForKindDeployment string = "Deployment"
+const waitForKindNode string = "Node"
+
+const waitForPollInterval = 5 * time.Second
+
+// WaitCondition is a readiness condition that has to hold before components are removed.
+type WaitCondition struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// MinReady is the number of ready pods (or nodes) required, nil means everything that is desired has to be ready
+	MinReady *int64
+}
+
+func (c WaitCondition) String() string {
+	var target string
+	switch {
+	case c.Kind == waitForKindNode && c.Name == "":
+		target = "nodes"
+	case c.Kind == waitForKindNode:
+		target = fmt.Sprintf("%s %s", c.Kind, c.Name)
+	default:
+		target = fmt.Sprintf("%s %s/%s", c.Kind, c.Namespace, c.Name)
+	}
+
+	if c.MinReady != nil {
+		return fmt.Sprintf("%s (at least %d ready)", target, *c.MinReady)
+	}
+	return fmt.Sprintf("%s (all ready)", target)
+}
+
+// WaitConditionMet checks a single condition, returning whether it holds and a description of what was observed.
+func WaitConditionMet(ctx context.Context, clientset *kubernetes.Clientset, condition WaitCondition) (met bool, observed string, err error) {
+	switch condition.Kind {
+	case waitForKindDaemonSet:
+		daemonset, err := clientset.AppsV1().DaemonSets(condition.Namespace).Get(ctx, condition.Name, metav1.GetOptions{})
+		switch {
+		case err != nil && !errors.IsNotFound(err):
+			return false, "", err
+		case errors.IsNotFound(err):
+			return false, "daemonset not found", nil
+		}
+
+		required := int64(daemonset.Status.DesiredNumberScheduled)
+		if condition.MinReady != nil {
+			required = *condition.MinReady
+		} else if required == 0 {
+			return false, "no pods scheduled yet", nil
+		}
+
+		ready := int64(daemonset.Status.NumberReady)
+		return ready >= required, fmt.Sprintf("%d/%d pods ready", ready, daemonset.Status.DesiredNumberScheduled), nil
+
+	case waitForKindDeployment:
+		deployment, err := clientset.AppsV1().Deployments(condition.Namespace).Get(ctx, condition.Name, metav1.GetOptions{})
+		switch {
+		case err != nil && !errors.IsNotFound(err):
+			return false, "", err
+		case errors.IsNotFound(err):
+			return false, "deployment not found", nil
+		}
+
+		desired := int64(1)
+		if deployment.Spec.Replicas != nil {
+			desired = int64(*deployment.Spec.Replicas)
+		}
+
+		required := desired
+		if condition.MinReady != nil {
+			required = *condition.MinReady
+		}
+
+		ready := int64(deployment.Status.ReadyReplicas)
+		return ready >= required, fmt.Sprintf("%d/%d replicas ready", ready, desired), nil
+
+	case waitForKindNode:
+		var nodes []corev1.Node
+		if condition.Name != "" {
+			node, err := clientset.CoreV1().Nodes().Get(ctx, condition.Name, metav1.GetOptions{})
+			switch {
+			case err != nil && !errors.IsNotFound(err):
+				return false, "", err
+			case errors.IsNotFound(err):
+				return false, "node not found", nil
+			}
+			nodes = append(nodes, *node)
+		} else {
+			nodeList, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return false, "", err
+			}
+			nodes = nodeList.Items
+		}
+
+		ready := int64(0)
+		for _, node := range nodes {
+			if NodeIsReady(node) {
+				ready++
+			}
+		}
+
+		required := int64(len(nodes))
+		if condition.MinReady != nil {
+			required = *condition.MinReady
+		} else if required == 0 {
+			return false, "no nodes registered yet", nil
+		}
+
+		return ready >= required, fmt.Sprintf("%d/%d nodes ready", ready, len(nodes)), nil
+
+	default:
+		return false, "", fmt.Errorf("unsupported wait_for kind %q", condition.Kind)
+	}
+}
+
+// NodeIsReady returns true when the node reports the Ready condition as True.
+func NodeIsReady(node corev1.Node) bool {
+	for _, nodeCondition := range node.Status.Conditions {
+		if nodeCondition.Type == corev1.NodeReady {
+			return nodeCondition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// WaitForConditions polls until every condition holds or the timeout expires. On timeout the conditions that never
+// became true are returned, each with the state that was last observed.
+func WaitForConditions(ctx context.Context, clientset *kubernetes.Clientset, conditions []WaitCondition, timeout time.Duration) (unmet []string, err error) {
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	observed := make([]string, len(conditions))
+	met := make([]bool, len(conditions))
+
+	err = wait.PollUntilContextTimeout(ctx, waitForPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		allMet := true
+		for i, condition := range conditions {
+			conditionMet, conditionObserved, err := WaitConditionMet(ctx, clientset, condition)
+			if err != nil {
+				return false, fmt.Errorf("checking %s: %w", condition, err)
+			}
+
+			met[i] = conditionMet
+			observed[i] = conditionObserved
+			allMet = allMet && conditionMet
+		}
+		return allMet, nil
+	})
+
+	if err != nil && wait.Interrupted(err) {
+		for i, condition := range conditions {
+			if !met[i] {
+				unmet = append(unmet, fmt.Sprintf("%s: %s", condition, observed[i]))
+			}
+		}
+		return unmet, fmt.Errorf("timed out after %s waiting for %s", timeout, strings.Join(unmet, "; "))
+	}
+
+	return nil, err
+}
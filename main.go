@@ -20,6 +20,15 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch command := os.Args[1]; command {
+		case "controller":
+			os.Exit(runController(os.Args[2:]))
+		case provider.CliCommandClean, provider.CliCommandStatus, provider.CliCommandAdopt, provider.CliCommandRestore:
+			os.Exit(runCli(command, os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	var debug bool
 
 	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")